@@ -0,0 +1,506 @@
+// Command erd extracts, exports, compares and checks database schemas from
+// the command line, without serving the web UI, so that scripts and
+// scheduled pipelines can run it.
+//
+//	erd extract [flags]            write the schema as JSON
+//	erd export -format=F [flags]   write the schema as DBML, Markdown or a diagram
+//	erd diff [flags] OLD [NEW]     list the changes from schema file OLD to NEW or the database
+//	erd lint [flags]               check the schema for design problems
+//
+// The exit code is 0 on success, 1 if diff found changes or lint found
+// problems, 2 for invalid arguments and 3 if the schema could not be read
+// or the output could not be written.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	_ "erddiagram/internal/db/extractors"
+
+	"erddiagram/internal/db"
+	"erddiagram/internal/dbml"
+	"erddiagram/internal/ddl"
+	"erddiagram/internal/diagram"
+	"erddiagram/internal/introspect"
+	"erddiagram/internal/lint"
+	"erddiagram/internal/markdown"
+	"erddiagram/internal/schemadiff"
+	"erddiagram/pkg/config"
+)
+
+// Exit codes.
+const (
+	exitOK      = 0
+	exitFound   = 1 // diff found changes or lint found problems
+	exitUsage   = 2
+	exitFailure = 3
+)
+
+// defaultTimeout is the default of -timeout in seconds.
+const defaultTimeout = 10
+
+// command is a subcommand of erd.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = []command{
+	{"extract", "write the schema as JSON", runExtract},
+	{"export", "write the schema as DBML, Markdown, JSON or a diagram", runExport},
+	{"diff", "list the changes between two schemas", runDiff},
+	{"lint", "check the schema for design problems", runLint},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the subcommand named by args[0] and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		usage(stdout)
+		return exitOK
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:], stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "erd: unknown command %q\n", args[0])
+	usage(stderr)
+	return exitUsage
+}
+
+// usage lists the subcommands and exit codes.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: erd <command> [flags]\n\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w, "\nRun erd <command> -h for the flags of a command.")
+	fmt.Fprintln(w, "Exit codes: 0 ok, 1 changes or problems found, 2 invalid arguments, 3 failure.")
+}
+
+// source holds the flags that select the schema a command reads: a
+// connection of the config file, a driver and DSN, or a schema file.
+type source struct {
+	config  string
+	conn    string
+	driver  string
+	dsn     string
+	timeout int
+	in      string
+}
+
+// flags defines the source flags on fs.
+func (src *source) flags(fs *flag.FlagSet) {
+	fs.StringVar(&src.config, "config", filepath.Join(".", "configs", "example.yaml"), "path to config YAML")
+	fs.StringVar(&src.conn, "conn", "", "connection of the config file, default the database section")
+	fs.StringVar(&src.driver, "driver", "", "db driver override (postgres,mysql,sqlite,sqlserver,godror,ddl), requires -dsn")
+	fs.StringVar(&src.dsn, "dsn", "", "dsn override, requires -driver")
+	fs.IntVar(&src.timeout, "timeout", defaultTimeout, "db connect timeout seconds")
+	fs.StringVar(&src.in, "in", "", "read the schema from a .json, .dbml or .sql file instead of a database")
+}
+
+// check reports invalid combinations of the source flags.
+func (src *source) check() error {
+	if (src.driver == "") != (src.dsn == "") {
+		return errors.New("-driver and -dsn must be given together")
+	}
+	if src.in != "" && (src.driver != "" || src.conn != "") {
+		return errors.New("-in cannot be combined with -driver or -conn")
+	}
+	return nil
+}
+
+// load reads the schema of src. explicitConfig tells whether -config was
+// given: a missing default config file is no error with -driver and -dsn.
+func (src *source) load(explicitConfig bool) (introspect.Schema, error) {
+	if src.in != "" {
+		return readSchemaFile(src.in)
+	}
+	cfg, err := config.LoadFile(src.config)
+	if err != nil && (explicitConfig || src.driver == "") {
+		return introspect.Schema{}, fmt.Errorf("config file: %w", err)
+	}
+
+	c := cfg.Database
+	if src.conn != "" && src.conn != "default" {
+		var ok bool
+		if c, ok = cfg.Connections[src.conn]; !ok {
+			return introspect.Schema{}, fmt.Errorf("unknown connection %q in %s", src.conn, src.config)
+		}
+	}
+	if src.driver != "" {
+		c = config.DBConfig{Type: src.driver, DSN: src.dsn, Filter: c.Filter}
+	}
+	driver, dsn, err := config.BuildDriverAndDSN(c)
+	if err != nil {
+		return introspect.Schema{}, err
+	}
+
+	skip, err := db.ParseSections(cfg.Extract.Skip)
+	if err != nil {
+		return introspect.Schema{}, fmt.Errorf("extract.skip: %w", err)
+	}
+	return db.ConnectAndExtractWithOptions(driver, dsn, src.timeout, db.Options{
+		ExactRowCounts:  cfg.Extract.ExactRowCounts,
+		RowCountTimeout: time.Duration(cfg.Extract.RowCountTimeout) * time.Second,
+		Concurrency:     cfg.Extract.Concurrency,
+		Filter: db.Filter{
+			IncludeSchemas: c.Filter.IncludeSchemas,
+			ExcludeSchemas: c.Filter.ExcludeSchemas,
+			IncludeTables:  c.Filter.IncludeTables,
+			ExcludeTables:  c.Filter.ExcludeTables,
+		},
+		Skip: skip,
+	})
+}
+
+// readSchemaFile reads a schema written by erd extract, a snapshot of the
+// server, a DBML model or a DDL script, by the extension of path.
+func readSchemaFile(path string) (introspect.Schema, error) {
+	var s introspect.Schema
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		// a snapshot of the server holds the schema in its schema member
+		var snap struct {
+			Schema *introspect.Schema `json:"schema"`
+		}
+		if err := json.Unmarshal(b, &snap); err == nil && snap.Schema != nil {
+			return *snap.Schema, nil
+		}
+		if err := json.Unmarshal(b, &s); err != nil {
+			return s, fmt.Errorf("%s: %w", path, err)
+		}
+		return s, nil
+	case ".dbml":
+		s, err = dbml.Parse(string(b))
+	case ".sql", ".ddl":
+		s, err = ddl.Parse(string(b), "")
+	default:
+		return s, fmt.Errorf("%s: unknown schema file type, want .json, .dbml or .sql", path)
+	}
+	if err != nil {
+		return s, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// parseFlags parses args with fs and returns the exit code to stop with,
+// or -1 to go on.
+func parseFlags(fs *flag.FlagSet, args []string, stderr io.Writer) int {
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	return -1
+}
+
+// isSet reports whether the flag name was given on the command line of fs.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// usageError reports an invalid argument of the command of fs.
+func usageError(fs *flag.FlagSet, stderr io.Writer, format string, args ...any) int {
+	fmt.Fprintf(stderr, "erd %s: %s\n", fs.Name(), fmt.Sprintf(format, args...))
+	return exitUsage
+}
+
+// failure reports an error that stopped the command of fs.
+func failure(fs *flag.FlagSet, stderr io.Writer, err error) int {
+	fmt.Fprintf(stderr, "erd %s: %v\n", fs.Name(), err)
+	return exitFailure
+}
+
+// warn writes the extraction warnings of s to stderr, as a schema that
+// lacks some parts may still be worth writing.
+func warn(stderr io.Writer, s introspect.Schema) {
+	for _, w := range s.Warnings {
+		if w.Object != "" {
+			fmt.Fprintf(stderr, "warning: %s of %s: %s\n", w.Phase, w.Object, w.Message)
+			continue
+		}
+		fmt.Fprintf(stderr, "warning: %s: %s\n", w.Phase, w.Message)
+	}
+}
+
+// writeOutput calls write with a buffer and writes its content to the
+// file out, or to stdout if out is empty, so that a failed command leaves
+// no partial file behind.
+func writeOutput(out string, stdout io.Writer, write func(io.Writer) error) error {
+	var b bytes.Buffer
+	if err := write(&b); err != nil {
+		return err
+	}
+	if out == "" {
+		_, err := stdout.Write(b.Bytes())
+		return err
+	}
+	return os.WriteFile(out, b.Bytes(), 0o644)
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// loadSource loads the schema of src for the command of fs, whose flags
+// are parsed, returning the exit code to stop with, or -1 to go on.
+func loadSource(fs *flag.FlagSet, src *source, stderr io.Writer) (introspect.Schema, int) {
+	if fs.NArg() > 0 {
+		return introspect.Schema{}, usageError(fs, stderr, "unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if err := src.check(); err != nil {
+		return introspect.Schema{}, usageError(fs, stderr, "%v", err)
+	}
+	s, err := src.load(isSet(fs, "config"))
+	if err != nil {
+		return s, failure(fs, stderr, err)
+	}
+	warn(stderr, s)
+	return s, -1
+}
+
+func runExtract(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("extract", flag.ContinueOnError)
+	var src source
+	src.flags(fs)
+	out := fs.String("out", "", "file to write to, default stdout")
+	if code := parseFlags(fs, args, stderr); code >= 0 {
+		return code
+	}
+	s, code := loadSource(fs, &src, stderr)
+	if code >= 0 {
+		return code
+	}
+	if err := writeOutput(*out, stdout, func(w io.Writer) error { return writeJSON(w, s) }); err != nil {
+		return failure(fs, stderr, err)
+	}
+	return exitOK
+}
+
+// exportFormats returns the formats of erd export, sorted by name.
+func exportFormats() []string {
+	names := append([]string{"dbml", "json", "markdown"}, diagram.FormatNames()...)
+	slices.Sort(names)
+	return names
+}
+
+// export writes s to w in format, one of exportFormats. The diagram
+// formats show the objects o selects.
+func export(w io.Writer, format string, s introspect.Schema, o diagram.Options) error {
+	switch format {
+	case "json":
+		return writeJSON(w, s)
+	case "dbml":
+		return dbml.Write(w, s)
+	case "markdown":
+		return markdown.Write(w, s)
+	}
+	return diagram.Formats[format](w, s, o)
+}
+
+func runExport(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	var src source
+	src.flags(fs)
+	out := fs.String("out", "", "file to write to, default stdout")
+	format := fs.String("format", "mermaid", "output format: "+strings.Join(exportFormats(), ", "))
+	var o diagram.Options
+	fs.StringVar(&o.Schema, "schema", "", "diagram only this schema and the tables its foreign keys reference")
+	fs.StringVar(&o.Search, "search", "", "diagram only tables and views whose name contains this")
+	fs.StringVar(&o.ColorBy, "color-by", "size", "color diagram tables by size or rows")
+
+	if code := parseFlags(fs, args, stderr); code >= 0 {
+		return code
+	}
+	if !slices.Contains(exportFormats(), *format) {
+		return usageError(fs, stderr, "unsupported format %q, want one of %s", *format, strings.Join(exportFormats(), ", "))
+	}
+	s, code := loadSource(fs, &src, stderr)
+	if code >= 0 {
+		return code
+	}
+	if err := writeOutput(*out, stdout, func(w io.Writer) error { return export(w, *format, s, o) }); err != nil {
+		return failure(fs, stderr, err)
+	}
+	return exitOK
+}
+
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	var src source
+	src.flags(fs)
+	out := fs.String("out", "", "file to write to, default stdout")
+	format := fs.String("format", "text", "output format: text, json or markdown")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: erd diff [flags] OLD [NEW]\n\nOLD and NEW are .json, .dbml or .sql schema files; without NEW the database of the flags is compared.")
+		fs.PrintDefaults()
+	}
+	if code := parseFlags(fs, args, stderr); code >= 0 {
+		return code
+	}
+	if !slices.Contains([]string{"text", "json", "markdown"}, *format) {
+		return usageError(fs, stderr, "unsupported format %q, want text, json or markdown", *format)
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return usageError(fs, stderr, "want the schema files OLD and optionally NEW")
+	}
+	if err := src.check(); err != nil {
+		return usageError(fs, stderr, "%v", err)
+	}
+
+	old, err := readSchemaFile(fs.Arg(0))
+	if err != nil {
+		return failure(fs, stderr, err)
+	}
+	var cur introspect.Schema
+	if fs.NArg() == 2 {
+		cur, err = readSchemaFile(fs.Arg(1))
+	} else {
+		cur, err = src.load(isSet(fs, "config"))
+	}
+	if err != nil {
+		return failure(fs, stderr, err)
+	}
+	warn(stderr, cur)
+
+	// an empty list rather than null in JSON
+	changes := append([]schemadiff.Change{}, schemadiff.Diff(old, cur)...)
+	err = writeOutput(*out, stdout, func(w io.Writer) error {
+		switch *format {
+		case "json":
+			return writeJSON(w, struct {
+				Changes []schemadiff.Change `json:"changes"`
+			}{Changes: changes})
+		case "markdown":
+			return writeDiffMarkdown(w, changes)
+		}
+		for _, c := range changes {
+			if _, err := fmt.Fprintln(w, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return failure(fs, stderr, err)
+	}
+	if len(changes) > 0 {
+		return exitFound
+	}
+	return exitOK
+}
+
+// writeDiffMarkdown writes changes as a Markdown list, for pull request
+// comments and reports.
+func writeDiffMarkdown(w io.Writer, changes []schemadiff.Change) error {
+	var b strings.Builder
+	b.WriteString("# Schema changes\n\n")
+	if len(changes) == 0 {
+		b.WriteString("No changes.\n")
+	}
+	for _, c := range changes {
+		fmt.Fprintf(&b, "- **%s** `%s`", c.Kind, c.Object)
+		if c.Detail != "" {
+			fmt.Fprintf(&b, ": %s", c.Detail)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func runLint(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	var src source
+	src.flags(fs)
+	out := fs.String("out", "", "file to write to, default stdout")
+	format := fs.String("format", "text", "output format: text or json")
+	disable := fs.String("disable", "", "comma-separated rules not to check")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: erd lint [flags]\n\nrules:")
+		for _, r := range lint.Rules {
+			fmt.Fprintf(fs.Output(), "  %-20s %s\n", r.Name, r.Description)
+		}
+		fmt.Fprintln(fs.Output(), "\nflags:")
+		fs.PrintDefaults()
+	}
+
+	if code := parseFlags(fs, args, stderr); code >= 0 {
+		return code
+	}
+	if *format != "text" && *format != "json" {
+		return usageError(fs, stderr, "unsupported format %q, want text or json", *format)
+	}
+	var disabled []string
+	for _, name := range strings.Split(*disable, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if !slices.ContainsFunc(lint.Rules, func(r lint.Rule) bool { return r.Name == name }) {
+			return usageError(fs, stderr, "unknown rule %q", name)
+		}
+		disabled = append(disabled, name)
+	}
+	s, code := loadSource(fs, &src, stderr)
+	if code >= 0 {
+		return code
+	}
+
+	findings := append([]lint.Finding{}, lint.Check(s, disabled...)...)
+	err := writeOutput(*out, stdout, func(w io.Writer) error {
+		if *format == "json" {
+			return writeJSON(w, struct {
+				Findings []lint.Finding `json:"findings"`
+			}{Findings: findings})
+		}
+		for _, f := range findings {
+			if _, err := fmt.Fprintln(w, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return failure(fs, stderr, err)
+	}
+	if len(findings) > 0 {
+		return exitFound
+	}
+	return exitOK
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const shop = "../../internal/dbml/testdata/shop.dbml"
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	extracted := filepath.Join(dir, "shop.json")
+	if code := run([]string{"extract", "-in", shop, "-out", extracted}, &strings.Builder{}, &strings.Builder{}); code != exitOK {
+		t.Fatalf("\ngot exit code %d extracting the test schema, wanted %d", code, exitOK)
+	}
+	snapshotFile := filepath.Join(dir, "snapshot.json")
+	if err := os.WriteFile(snapshotFile, []byte(`{"id":"x","connection":"prod","schema":{"tables":[{"name":"customers","columns":[{"name":"id","type":"integer","pk":true}],"indexes":[{"columns":["id"],"unique":true,"primary":true}]}]}}`), 0o644); err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	changed := filepath.Join(dir, "changed.dbml")
+	if err := os.WriteFile(changed, []byte("Table customers {\n  id integer [pk]\n}\n"), 0o644); err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+
+	var tests = []struct {
+		name string
+		args []string
+		want int
+		out  string
+	}{
+		{"no command", nil, exitUsage, ""},
+		{"unknown command", []string{"serve"}, exitUsage, ""},
+		{"help", []string{"-h"}, exitOK, "usage: erd"},
+		{"command help", []string{"lint", "-h"}, exitOK, ""},
+		{"unknown flag", []string{"extract", "-nope"}, exitUsage, ""},
+		{"export markdown", []string{"export", "-in", shop, "-format", "markdown"}, exitOK, "### `sales.orders`"},
+		{"export mermaid", []string{"export", "-in", extracted}, exitOK, "erDiagram"},
+		{"unsupported format", []string{"export", "-in", shop, "-format", "svg"}, exitUsage, ""},
+		{"missing file", []string{"extract", "-in", filepath.Join(dir, "missing.sql")}, exitFailure, ""},
+		{"driver without dsn", []string{"extract", "-driver", "sqlite"}, exitUsage, ""},
+		{"missing config", []string{"extract", "-config", filepath.Join(dir, "missing.yaml")}, exitFailure, ""},
+		{"unknown connection", []string{"extract", "-config", "../../configs/example.yaml", "-conn", "nope"}, exitFailure, ""},
+		{"diff unchanged", []string{"diff", extracted, shop}, exitOK, ""},
+		{"diff changed", []string{"diff", extracted, changed}, exitFound, "removed table sales.orders\n"},
+		{"diff snapshot", []string{"diff", snapshotFile, changed}, exitOK, ""},
+		{"diff json", []string{"diff", "-format", "json", changed, changed}, exitOK, `"changes": []`},
+		{"diff without files", []string{"diff"}, exitUsage, ""},
+		{"lint problems", []string{"lint", "-in", shop}, exitFound, "fk-without-index: sales.order_lines(product)"},
+		{"lint disabled", []string{"lint", "-in", shop, "-disable", "fk-without-index,extraction-warning"}, exitOK, ""},
+		{"lint unknown rule", []string{"lint", "-in", shop, "-disable", "nope"}, exitUsage, ""},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			if code := run(tt.args, &stdout, &stderr); code != tt.want {
+				t.Errorf("\ngot exit code %d, wanted %d\nstderr: %s", code, tt.want, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.out) {
+				t.Errorf("\ngot output:\n%s\nwanted it to contain %q", stdout.String(), tt.out)
+			}
+		})
+	}
+}
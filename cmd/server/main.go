@@ -2,56 +2,235 @@
 
 import (
 	"cmp"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	_ "erddiagram/internal/db/extractors"
 	"erddiagram/internal/logger"
 
 	"erddiagram/internal/db"
+	"erddiagram/internal/dbml"
+	"erddiagram/internal/ddl"
+	"erddiagram/internal/diagram"
 	"erddiagram/internal/introspect"
+	"erddiagram/internal/session"
+	"erddiagram/internal/snapshot"
 	"erddiagram/pkg/config"
 )
 
+// maxImportSize bounds the schema files uploaded to /api/import.
+const maxImportSize = 32 << 20
+
+// importTimeout replaces the server read and write timeouts for
+// /api/import, which are too short to upload maxImportSize on a slow link.
+const importTimeout = 2 * time.Minute
+
 var (
-	activeMu      sync.RWMutex
-	activeDriver  string
-	activeDSN     string
-	activeTimeout = 10
-	defaultPort   = 8080
+	defaultPort = 8080
+
+	// conns keeps a connection pool per database between requests
+	conns *db.Manager
+	// schemas keeps the last schema extracted per database
+	schemas *db.Cache
+	// sessions keeps the named connections and the one each browser selected
+	sessions *session.Store
+	// snapshots keeps the history of the schemas of the shared connections, nil if disabled
+	snapshots *snapshot.Store
+	// snapshotted holds the hash of the schema last passed to snapshots per connection
+	snapshotted sync.Map
 )
 
-// setActive sets the active database connection
-func setActive(driver, dsn string, timeout int) {
-	activeMu.Lock()
-	defer activeMu.Unlock()
-	activeDriver = driver
-	activeDSN = dsn
-	activeTimeout = timeout
+// release lets the pool of a database that no connection uses anymore
+// close and drops its cached schema.
+func release(driver, dsn string) {
+	if err := conns.Release(driver, dsn); err != nil {
+		logger.Error("close connection: %v", err)
+	}
+	schemas.Forget(driver, dsn)
+}
+
+// connectRequest is the JSON body that creates a named connection.
+type connectRequest struct {
+	Name string `json:"name"`
+	config.DBConfig
+}
+
+// connectionInfo describes a connection to the client, without its DSN,
+// which may hold a password.
+type connectionInfo struct {
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	Host         string `json:"host,omitempty"`
+	Port         int    `json:"port,omitempty"`
+	DatabaseName string `json:"database_name,omitempty"`
+	Shared       bool   `json:"shared"`   // from the config file, cannot be deleted
+	Selected     bool   `json:"selected"` // used when a request names no connection
 }
 
-// getActive returns the active databse connection
-func getActive() (string, string, int) {
-	activeMu.RLock()
-	defer activeMu.RUnlock()
-	return activeDriver, activeDSN, activeTimeout
+// profileFromRequest checks the body of a connect request and returns it
+// as a profile named name, or DefaultName if it names none. A masked
+// password is replaced by the one of the connection of the same name in
+// session id, or of the selected one the form was loaded from.
+func profileFromRequest(id string, r *http.Request) (session.Profile, error) {
+	var req connectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return session.Profile{}, fmt.Errorf("invalid json: %w", err)
+	}
+	p := session.Profile{Name: cmp.Or(strings.TrimSpace(req.Name), session.DefaultName), Config: req.DBConfig}
+	old, err := sessions.Get(id, p.Name)
+	if err != nil {
+		old, _ = sessions.Get(id, "")
+	}
+	p.Config = p.Config.WithSecretsFrom(old.Config)
+	driver, _, err := config.BuildDriverAndDSN(p.Config)
+	if err != nil {
+		return p, err
+	}
+	if driver == "ddl" {
+		// clients must not read files of the server
+		return p, errors.New("ddl files are only read from the config file, upload them to /api/import")
+	}
+	return p, filterFromConfig(p.Config.Filter).Validate()
+}
+
+// sharedProfiles returns the connections of the config file, the database
+// section as DefaultName. Connections without a usable DSN are skipped.
+func sharedProfiles(appCfg config.AppConfig) []session.Profile {
+	var out []session.Profile
+	add := func(name string, c config.DBConfig) {
+		if _, _, err := config.BuildDriverAndDSN(c); err != nil {
+			logger.Error("connection %s: error building DSN: %v", name, err)
+			return
+		}
+		out = append(out, session.Profile{Name: name, Config: c})
+	}
+	if appCfg.Database.Type != "" {
+		add(session.DefaultName, appCfg.Database)
+	}
+	for name, c := range appCfg.Connections {
+		if name == session.DefaultName && appCfg.Database.Type != "" {
+			logger.Error("connection %s: the name is taken by the database section", name)
+			continue
+		}
+		add(name, c)
+	}
+	return out
+}
+
+// etagMatch reports whether the If-None-Match header value header lists etag.
+func etagMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// saveSnapshot adds the schema of connection p to the history if it is a
+// shared connection and the schema changed since its last snapshot.
+func saveSnapshot(p session.Profile, cached db.CachedSchema) {
+	if snapshots == nil || !p.Shared {
+		return
+	}
+	// schemas served from the cache were saved before
+	if last, ok := snapshotted.Load(p.Name); ok && last == cached.Hash {
+		return
+	}
+	snap, saved, err := snapshots.Save(p.Name, cached.Schema, cached.Extracted)
+	if err != nil {
+		logger.Error("save snapshot of %s: %v", p.Name, err)
+		return
+	}
+	snapshotted.Store(p.Name, cached.Hash)
+	if saved {
+		logger.Info("saved snapshot %s of %s", snap.ID, p.Name)
+	}
+}
+
+// hasHistory reports whether connection p has snapshots, and responds
+// with the reason if not. Only the shared connections have a history.
+func hasHistory(w http.ResponseWriter, p session.Profile) bool {
+	if snapshots == nil {
+		http.Error(w, "snapshots are not enabled; set snapshots.dir in the config file", http.StatusNotFound)
+		return false
+	}
+	if !p.Shared {
+		http.Error(w, fmt.Sprintf("connection %q has no history, only the connections of the config file have", p.Name), http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// connectionErrorStatus returns the HTTP status for an error of the session
+// or snapshot store.
+func connectionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, session.ErrNotFound), errors.Is(err, snapshot.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, session.ErrShared):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// exportExtensions maps the export formats to the file name extension of
+// their output.
+var exportExtensions = map[string]string{"dbml": ".dbml", "mermaid": ".mmd", "plantuml": ".puml", "dot": ".dot"}
+
+// exportSchema writes s to w in format, a key of exportExtensions. The
+// diagram formats show the objects o selects.
+func exportSchema(w io.Writer, format string, s introspect.Schema, o diagram.Options) error {
+	if format == "dbml" {
+		return dbml.Write(w, s)
+	}
+	write, ok := diagram.Formats[format]
+	if !ok {
+		return fmt.Errorf("unsupported format: %q", format)
+	}
+	return write(w, s, o)
+}
+
+// diagramOptions reads the diagram options of an export request.
+func diagramOptions(r *http.Request) diagram.Options {
+	q := r.URL.Query()
+	return diagram.Options{Schema: q.Get("schema"), Search: q.Get("search"), ColorBy: q.Get("color_by")}
+}
+
+// filterFromConfig converts the filter rules of a database config.
+func filterFromConfig(f config.FilterConfig) db.Filter {
+	return db.Filter{
+		IncludeSchemas: f.IncludeSchemas,
+		ExcludeSchemas: f.ExcludeSchemas,
+		IncludeTables:  f.IncludeTables,
+		ExcludeTables:  f.ExcludeTables,
+	}
 }
 
 func main() {
 	// flags
 	cfgPath := flag.String("config", filepath.Join(".", "configs", "example.yaml"), "path to config YAML")
-	driverFlag := flag.String("driver", "", "db driver override (postgres,mysql,sqlite,sqlserver,godror)")
+	driverFlag := flag.String("driver", "", "db driver override (postgres,mysql,sqlite,sqlserver,godror,ddl)")
 	dsnFlag := flag.String("dsn", "", "dsn override")
 	port := flag.Int("port", 0, "http port (overrides config, default"+fmt.Sprintf(" %d)", defaultPort))
 	timeout := flag.Int("timeout", 10, "db connect timeout seconds")
 	webdir := flag.String("web", filepath.Join(".", "web"), "web ui directory")
 	flag.Parse()
-	logger.Info("dsnFlag = %s", *dsnFlag)
+	logger.Info("dsnFlag = %s", config.MaskDSN(*dsnFlag))
 
 	// attempt to load config file (optional)
 	var appCfg config.AppConfig
@@ -64,9 +243,23 @@ func main() {
 		}
 	}
 
+	conns = db.NewManager(db.PoolConfig{
+		MaxOpen:     appCfg.Pool.MaxOpen,
+		MaxIdle:     cmp.Or(appCfg.Pool.MaxIdle, appCfg.Extract.Concurrency),
+		MaxLifetime: time.Duration(appCfg.Pool.MaxLifetime) * time.Second,
+		MaxIdleTime: time.Duration(appCfg.Pool.MaxIdleTime) * time.Second,
+		Concurrency: appCfg.Extract.Concurrency,
+	})
+	schemas = db.NewCache(conns, time.Duration(appCfg.Cache.MaxAge)*time.Second)
+	if appCfg.Snapshots.Dir != "" {
+		var err error
+		if snapshots, err = snapshot.Open(appCfg.Snapshots.Dir); err != nil {
+			logger.Error("error opening snapshots.dir: %v", err)
+		}
+	}
+
 	// allow CLI overrides
 	if *driverFlag != "" && *dsnFlag != "" {
-		setActive(*driverFlag, *dsnFlag, *timeout)
 		appCfg.Database.Type = *driverFlag
 		appCfg.Database.DSN = *dsnFlag
 		appCfg.Database.Host = ""
@@ -74,36 +267,37 @@ func main() {
 		appCfg.Database.Username = ""
 		appCfg.Database.Password = ""
 		appCfg.Database.DatabaseName = ""
-	} else if appCfg.Database.Type != "" {
-		drv, dsn, err := config.BuildDriverAndDSN(appCfg.Database)
-		if err == nil {
-			setActive(drv, dsn, *timeout)
-		} else {
-			logger.Error("error building DSN: %v", err)
-		}
 	}
+	sessions = session.NewStore(sharedProfiles(appCfg), time.Duration(appCfg.Server.SessionTTL)*time.Minute, conns.Acquire, release)
 
 	*port = cmp.Or(*port, appCfg.Server.Port, defaultPort)
 
+	skip, err := db.ParseSections(appCfg.Extract.Skip)
+	if err != nil {
+		logger.Error("error reading extract.skip: %v", err)
+	}
+	extractOpts := db.Options{
+		ExactRowCounts:  appCfg.Extract.ExactRowCounts,
+		RowCountTimeout: time.Duration(appCfg.Extract.RowCountTimeout) * time.Second,
+		Concurrency:     appCfg.Extract.Concurrency,
+		Skip:            skip,
+	}
+
 	// static web
 	fs := http.FileServer(http.Dir(*webdir))
 	http.Handle("/", fs)
 
-	// connect endpoint: user requests DB params
+	// connect endpoint: user requests DB params of the selected or ?conn= connection,
+	// passwords are masked
 	http.HandleFunc("/api/getConnect", func(w http.ResponseWriter, r *http.Request) {
+		p, _ := sessions.Get(sessions.ID(r), r.URL.Query().Get("conn"))
+		p.Config.Type = config.NormalizeDriver(p.Config.Type)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(struct {
 			OK     bool            `json:"ok"`
+			Name   string          `json:"name"`
 			Config config.DBConfig `json:"config"`
-		}{OK: true, Config: config.DBConfig{
-			Type:         config.NormalizeDriver(appCfg.Database.Type),
-			DSN:          appCfg.Database.DSN,
-			Host:         appCfg.Database.Host,
-			Port:         appCfg.Database.Port,
-			Username:     appCfg.Database.Username,
-			Password:     appCfg.Database.Password,
-			DatabaseName: appCfg.Database.DatabaseName,
-		}})
+		}{OK: true, Name: p.Name, Config: p.Config})
 	})
 
 	// connect endpoint: user posts DB params to create/test connection
@@ -112,47 +306,263 @@ func main() {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		var dbReq config.DBConfig
-		if err := json.NewDecoder(r.Body).Decode(&dbReq); err != nil {
-			http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
-			return
-		}
-		appCfg.Database = dbReq
-		driver, dsn, err := config.BuildDriverAndDSN(appCfg.Database)
+		p, err := profileFromRequest(sessions.ID(r), r)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		driver, dsn, _ := config.BuildDriverAndDSN(p.Config)
+		opts := extractOpts
+		opts.Filter = filterFromConfig(p.Config.Filter)
+		// hold the pool until the session holds it, so that the test
+		// connection is kept, or closed if the test fails
+		conns.Acquire(driver, dsn)
+		defer conns.Release(driver, dsn)
 		// test connection and return schema on success
-		schema, err := db.ConnectAndExtract(driver, dsn, *timeout)
+		// a new connection is always extracted, and cached for /api/schema
+		cached, err := schemas.Schema(driver, dsn, *timeout, opts, true)
 		if err != nil {
 			http.Error(w, "connection failed: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-		// persist active connection
-		setActive(driver, dsn, *timeout)
+		// keep the connection in the session and select it
+		if err := sessions.Put(sessions.Start(w, r), p, true); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(struct {
 			OK     bool              `json:"ok"`
+			Name   string            `json:"name"`
 			Schema introspect.Schema `json:"schema"`
-		}{OK: true, Schema: schema})
+		}{OK: true, Name: p.Name, Schema: cached.Schema})
+	})
+
+	// connections endpoints: list, create, select and delete the named connections of the session
+	http.HandleFunc("GET /api/connections", func(w http.ResponseWriter, r *http.Request) {
+		list, selected := sessions.List(sessions.ID(r))
+		out := make([]connectionInfo, len(list))
+		for i, p := range list {
+			out[i] = connectionInfo{
+				Name:         p.Name,
+				Type:         config.NormalizeDriver(p.Config.Type),
+				Host:         p.Config.Host,
+				Port:         p.Config.Port,
+				DatabaseName: p.Config.DatabaseName,
+				Shared:       p.Shared,
+				Selected:     p.Name == selected,
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			OK          bool             `json:"ok"`
+			Connections []connectionInfo `json:"connections"`
+		}{OK: true, Connections: out})
 	})
 
-	// schema endpoint uses active in-memory connection
+	http.HandleFunc("POST /api/connections", func(w http.ResponseWriter, r *http.Request) {
+		p, err := profileFromRequest(sessions.ID(r), r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := sessions.Put(sessions.Start(w, r), p, false); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(struct {
+			OK   bool   `json:"ok"`
+			Name string `json:"name"`
+		}{OK: true, Name: p.Name})
+	})
+
+	http.HandleFunc("POST /api/connections/{name}/select", func(w http.ResponseWriter, r *http.Request) {
+		if err := sessions.Select(sessions.Start(w, r), r.PathValue("name")); err != nil {
+			http.Error(w, err.Error(), connectionErrorStatus(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			OK bool `json:"ok"`
+		}{OK: true})
+	})
+
+	http.HandleFunc("DELETE /api/connections/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if err := sessions.Delete(sessions.ID(r), r.PathValue("name")); err != nil {
+			http.Error(w, err.Error(), connectionErrorStatus(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			OK bool `json:"ok"`
+		}{OK: true})
+	})
+
+	// import endpoint: user uploads a schema file as the request body, the
+	// schema is returned but not kept
+	http.HandleFunc("POST /api/import", func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		deadline := time.Now().Add(importTimeout)
+		if err := errors.Join(rc.SetReadDeadline(deadline), rc.SetWriteDeadline(deadline)); err != nil {
+			logger.Warn("import: extending deadlines: %v", err)
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+		if tooLarge := new(http.MaxBytesError); errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			http.Error(w, "reading upload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		var s introspect.Schema
+		switch format := cmp.Or(r.URL.Query().Get("format"), "ddl"); format {
+		case "ddl":
+			s, err = ddl.Parse(string(body), r.URL.Query().Get("dialect"))
+		case "dbml":
+			s, err = dbml.Parse(string(body))
+		default:
+			err = fmt.Errorf("unsupported format: %q", format)
+		}
+		if err != nil {
+			http.Error(w, "import failed: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			OK     bool              `json:"ok"`
+			Schema introspect.Schema `json:"schema"`
+		}{OK: true, Schema: s})
+	})
+
+	// schema endpoint uses the selected or ?conn= connection of the session
 	http.HandleFunc("/api/schema", func(w http.ResponseWriter, r *http.Request) {
-		driver, dsn, to := getActive()
-		if driver == "" || dsn == "" {
-			http.Error(w, "no active connection; POST /api/connect to create one", http.StatusBadRequest)
+		p, err := sessions.Get(sessions.ID(r), r.URL.Query().Get("conn"))
+		if err != nil {
+			http.Error(w, "no active connection; POST /api/connect to create one: "+err.Error(), http.StatusBadRequest)
 			return
 		}
-		schema, err := db.ConnectAndExtract(driver, dsn, to)
+		driver, dsn, err := config.BuildDriverAndDSN(p.Config)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts := extractOpts
+		opts.Filter = filterFromConfig(p.Config.Filter)
+		cached, err := schemas.Schema(driver, dsn, *timeout, opts, r.URL.Query().Has("refresh"))
 		if err != nil {
 			http.Error(w, "failed to extract schema: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		saveSnapshot(p, cached)
+		// browsers revalidate with If-None-Match and keep their copy while the schema is unchanged
+		etag := `"` + cached.Hash + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Last-Modified", cached.Extracted.UTC().Format(http.TimeFormat))
+		if etagMatch(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(cached.Schema)
+	})
+
+	// export endpoint: the schema of the selected or ?conn= connection as a file
+	http.HandleFunc("GET /api/export", func(w http.ResponseWriter, r *http.Request) {
+		p, err := sessions.Get(sessions.ID(r), r.URL.Query().Get("conn"))
+		if err != nil {
+			http.Error(w, "no active connection; POST /api/connect to create one: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		format := cmp.Or(r.URL.Query().Get("format"), "dbml")
+		ext, ok := exportExtensions[format]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unsupported format: %q", format), http.StatusBadRequest)
+			return
+		}
+		var s introspect.Schema
+		filename := p.Name + ext
+		if id := r.URL.Query().Get("snapshot"); id != "" {
+			// a version from the history instead of the current schema
+			if !hasHistory(w, p) {
+				return
+			}
+			snap, snapSchema, err := snapshots.Load(p.Name, id)
+			if err != nil {
+				http.Error(w, err.Error(), connectionErrorStatus(err))
+				return
+			}
+			s = snapSchema
+			filename = p.Name + "-" + snap.Taken.Format("20060102T150405Z") + ext
+		} else {
+			driver, dsn, err := config.BuildDriverAndDSN(p.Config)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			opts := extractOpts
+			opts.Filter = filterFromConfig(p.Config.Filter)
+			cached, err := schemas.Schema(driver, dsn, *timeout, opts, r.URL.Query().Has("refresh"))
+			if err != nil {
+				http.Error(w, "failed to extract schema: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			saveSnapshot(p, cached)
+			s = cached.Schema
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		if err := exportSchema(w, format, s, diagramOptions(r)); err != nil {
+			logger.Error("export %s: %v", p.Name, err)
+		}
+	})
+
+	// snapshot endpoints: the history of the schema of the selected or ?conn=
+	// shared connection, newest first, and the schema of one snapshot
+	http.HandleFunc("GET /api/snapshots", func(w http.ResponseWriter, r *http.Request) {
+		p, err := sessions.Get(sessions.ID(r), r.URL.Query().Get("conn"))
+		if err != nil {
+			http.Error(w, err.Error(), connectionErrorStatus(err))
+			return
+		}
+		if !hasHistory(w, p) {
+			return
+		}
+		list, err := snapshots.List(p.Name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			OK        bool                `json:"ok"`
+			Name      string              `json:"name"`
+			Snapshots []snapshot.Snapshot `json:"snapshots"`
+		}{OK: true, Name: p.Name, Snapshots: append([]snapshot.Snapshot{}, list...)})
+	})
+
+	http.HandleFunc("GET /api/snapshots/{id}", func(w http.ResponseWriter, r *http.Request) {
+		p, err := sessions.Get(sessions.ID(r), r.URL.Query().Get("conn"))
+		if err != nil {
+			http.Error(w, err.Error(), connectionErrorStatus(err))
+			return
+		}
+		if !hasHistory(w, p) {
+			return
+		}
+		snap, s, err := snapshots.Load(p.Name, r.PathValue("id"))
+		if err != nil {
+			http.Error(w, err.Error(), connectionErrorStatus(err))
+			return
+		}
+		// snapshots never change
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+		w.Header().Set("Last-Modified", snap.Taken.Format(http.TimeFormat))
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(schema)
+		json.NewEncoder(w).Encode(s)
 	})
 
 	// HTTP server
@@ -164,8 +574,28 @@ func main() {
 	}
 	logger.Info("listening on %s, serving %s", addr, *webdir)
 	logger.Info("registered dialects: %v", db.RegisteredDialects())
-	if err := srv.ListenAndServe(); err != nil {
-		logger.Fatal("%v", err)
+	for _, d := range db.RegisteredDialects() {
+		logger.Debug("dialect %s: sections %v, filter pushdown %t, concurrency %t",
+			d.Name, d.Capabilities.Sections, d.Capabilities.Filter, d.Capabilities.Concurrency)
+	}
+
+	// serve until interrupted, then let running requests finish and close the pools
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("%v", err)
+		}
+	}()
+	<-ctx.Done()
+	logger.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), srv.WriteTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("shutdown: %v", err)
+	}
+	if err := conns.Close(); err != nil {
+		logger.Error("close connections: %v", err)
 	}
 
 }
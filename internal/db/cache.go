@@ -0,0 +1,103 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"erddiagram/internal/introspect"
+	"erddiagram/internal/logger"
+	"erddiagram/pkg/config"
+)
+
+// CachedSchema is an extracted schema with the time it was extracted and
+// its content hash.
+type CachedSchema struct {
+	Schema    introspect.Schema
+	Hash      string
+	Extracted time.Time
+}
+
+// cacheEntry is a CachedSchema with what it was extracted with.
+type cacheEntry struct {
+	CachedSchema
+	opts    string // the options, formatted
+	version string // result of the change probe before the extraction
+	probed  bool   // whether the dialect has a change probe
+}
+
+// Cache keeps the last schema extracted for each connection and extracts
+// it again only when the change probe of the dialect reports a change, or
+// the schema is older than the maximum age. Dialects without a probe are
+// extracted on every request. It is safe for concurrent use; concurrent
+// requests for the same connection wait for one extraction.
+type Cache struct {
+	conns  *Manager
+	maxAge time.Duration
+
+	mu      sync.Mutex
+	entries map[poolKey]cacheEntry
+	flights map[poolKey]*sync.Mutex // held while probing and extracting a connection
+}
+
+// NewCache returns a Cache that extracts on the pools of conns. A maxAge of
+// 0 keeps schemas for as long as the probe reports no change.
+func NewCache(conns *Manager, maxAge time.Duration) *Cache {
+	return &Cache{conns: conns, maxAge: maxAge, entries: map[poolKey]cacheEntry{}, flights: map[poolKey]*sync.Mutex{}}
+}
+
+// Schema returns the schema of driver and dsn extracted with opts, from the
+// cache if it is still current. refresh forces a new extraction.
+func (c *Cache) Schema(driver, dsn string, timeoutSec int, opts Options, refresh bool) (CachedSchema, error) {
+	key := poolKey{config.NormalizeDriver(driver), dsn}
+	fmtOpts := fmt.Sprintf("%+v", opts)
+
+	// one request at a time per connection, the others find its result
+	c.mu.Lock()
+	flight, ok := c.flights[key]
+	if !ok {
+		flight = &sync.Mutex{}
+		c.flights[key] = flight
+	}
+	c.mu.Unlock()
+	flight.Lock()
+	defer flight.Unlock()
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+
+	// probe before extracting, so that a change during the extraction is
+	// picked up by the next request
+	version, probed, err := c.conns.SchemaVersion(driver, dsn, timeoutSec)
+	if err != nil {
+		logger.Warn("probe schema version: %v", err)
+		probed = false
+	}
+	if ok && !refresh && probed && e.probed && e.version == version && e.opts == fmtOpts &&
+		(c.maxAge <= 0 || time.Since(e.Extracted) < c.maxAge) {
+		return e.CachedSchema, nil
+	}
+
+	s, err := c.conns.Extract(driver, dsn, timeoutSec, opts)
+	if err != nil {
+		return CachedSchema{}, err
+	}
+	e = cacheEntry{
+		CachedSchema: CachedSchema{Schema: s, Hash: s.Hash(), Extracted: time.Now()},
+		opts:         fmtOpts,
+		version:      version,
+		probed:       probed,
+	}
+	c.mu.Lock()
+	c.entries[key] = e
+	c.mu.Unlock()
+	return e.CachedSchema, nil
+}
+
+// Forget drops the cached schema of driver and dsn.
+func (c *Cache) Forget(driver, dsn string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, poolKey{config.NormalizeDriver(driver), dsn})
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"erddiagram/internal/introspect"
+)
+
+// probedExtractor counts its extractions and reports version as the schema version.
+type probedExtractor struct {
+	extractions *int
+	version     *string
+}
+
+func (e probedExtractor) ExtractWithOptions(ctx context.Context, dbConn *sql.DB, opts Options) (introspect.Schema, error) {
+	*e.extractions++
+	return introspect.Schema{Tables: []introspect.Table{{Name: *e.version}}}, nil
+}
+
+func (probedExtractor) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+func (e probedExtractor) SchemaVersion(ctx context.Context, dbConn *sql.DB) (string, error) {
+	return *e.version, nil
+}
+
+func TestCacheSchema(t *testing.T) {
+	var extractions int
+	version := "1"
+	RegisterOptionsExtractor("sqlite", probedExtractor{&extractions, &version})
+	defer delete(dialects, "sqlite")
+	conns := NewManager(PoolConfig{})
+	defer conns.Close()
+	c := NewCache(conns, time.Hour)
+
+	var tests = []struct {
+		name        string
+		change      func()
+		opts        Options
+		refresh     bool
+		extractions int
+	}{
+		{"first request", func() {}, Options{}, false, 1},
+		{"unchanged", func() {}, Options{}, false, 1},
+		{"schema changed", func() { version = "2" }, Options{}, false, 2},
+		{"refresh", func() {}, Options{}, true, 3},
+		{"other options", func() {}, Options{Skip: SectionViews}, false, 4},
+		{"forgotten", func() { c.Forget("sqlite3", ":memory:") }, Options{Skip: SectionViews}, false, 5},
+		{"too old", func() { c.maxAge = time.Nanosecond }, Options{Skip: SectionViews}, false, 6},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change()
+			cs, err := c.Schema("sqlite", ":memory:", 10, tt.opts, tt.refresh)
+			if err != nil {
+				t.Fatalf("\ngot unexpected error: \"%v\"", err)
+			}
+			if extractions != tt.extractions {
+				t.Errorf("\ngot %d extractions, wanted %d", extractions, tt.extractions)
+			}
+			if cs.Hash != cs.Schema.Hash() {
+				t.Errorf("\ngot hash %s, wanted the hash of the schema %s", cs.Hash, cs.Schema.Hash())
+			}
+		})
+	}
+}
+
+func TestCacheWithoutProbe(t *testing.T) {
+	var pools []*sql.DB
+	RegisterOptionsExtractor("sqlite", poolExtractor{&pools})
+	defer delete(dialects, "sqlite")
+	conns := NewManager(PoolConfig{})
+	defer conns.Close()
+	c := NewCache(conns, 0)
+
+	for range 2 {
+		if _, err := c.Schema("sqlite", ":memory:", 10, Options{}, false); err != nil {
+			t.Fatalf("\ngot unexpected error: \"%v\"", err)
+		}
+	}
+	if len(pools) != 2 {
+		t.Errorf("\ngot %d extractions without a change probe, wanted 2", len(pools))
+	}
+}
+
+// slowExtractor counts its extractions, which take a while.
+type slowExtractor struct {
+	extractions *atomic.Int64
+}
+
+func (e slowExtractor) ExtractWithOptions(ctx context.Context, dbConn *sql.DB, opts Options) (introspect.Schema, error) {
+	e.extractions.Add(1)
+	time.Sleep(50 * time.Millisecond)
+	return introspect.Schema{}, nil
+}
+
+func (slowExtractor) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+func (slowExtractor) SchemaVersion(ctx context.Context, dbConn *sql.DB) (string, error) {
+	return "1", nil
+}
+
+func TestCacheConcurrentMisses(t *testing.T) {
+	var extractions atomic.Int64
+	RegisterOptionsExtractor("sqlite", slowExtractor{&extractions})
+	defer delete(dialects, "sqlite")
+	conns := NewManager(PoolConfig{})
+	defer conns.Close()
+	c := NewCache(conns, time.Hour)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Schema("sqlite", ":memory:", 10, Options{}, false); err != nil {
+				t.Errorf("\ngot unexpected error: \"%v\"", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := extractions.Load(); n != 1 {
+		t.Errorf("\ngot %d extractions for concurrent requests, wanted 1", n)
+	}
+}
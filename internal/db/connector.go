@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -16,48 +17,178 @@ import (
 	"erddiagram/pkg/config"
 )
 
+// Extractor is the original extractor interface without options. Register
+// adapts it, so existing extractors keep working and the connector applies
+// the filter and row counts after they return.
 type Extractor interface {
 
 	// Extract takes a database connection and returns data for the ERD
 	Extract(ctx context.Context, db *sql.DB) (introspect.Schema, error)
 }
 
-var dialects = map[string]Extractor{}
+// OptionsExtractor is an extractor that is told which sections to extract
+// and which tables to filter, and reports what it supports.
+type OptionsExtractor interface {
+
+	// ExtractWithOptions takes a database connection and returns data for
+	// the ERD, leaving out the sections opts skips where it saves queries
+	ExtractWithOptions(ctx context.Context, db *sql.DB, opts Options) (introspect.Schema, error)
+
+	// Capabilities reports the sections and options the extractor supports
+	Capabilities() Capabilities
+}
+
+// ChangeProber is implemented by extractors that can tell cheaply whether
+// the schema may have changed since it was extracted.
+type ChangeProber interface {
+
+	// SchemaVersion returns an opaque value that changes whenever the schema
+	// changes. It may also change without a schema change, but never stays
+	// the same across one.
+	SchemaVersion(ctx context.Context, db *sql.DB) (string, error)
+}
+
+// legacyExtractor adapts an Extractor to OptionsExtractor. It ignores the
+// options and declares no capabilities.
+type legacyExtractor struct {
+	Extractor
+}
+
+func (e legacyExtractor) ExtractWithOptions(ctx context.Context, db *sql.DB, _ Options) (introspect.Schema, error) {
+	return e.Extract(ctx, db)
+}
+
+func (legacyExtractor) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+// Source reads a schema from something other than a database connection,
+// such as a DDL file. The dsn tells it what to read.
+type Source interface {
+
+	// Load returns the schema dsn describes
+	Load(ctx context.Context, dsn string) (introspect.Schema, error)
+
+	// Version returns an opaque value that changes whenever the schema dsn
+	// describes may have changed, such as the modification time of a file
+	Version(dsn string) (string, error)
+}
+
+var dialects = map[string]OptionsExtractor{}
+
+var sources = map[string]Source{}
 
 // Register makes an Extractor available under name.
 func Register(name string, e Extractor) {
+	RegisterOptionsExtractor(name, legacyExtractor{e})
+}
+
+// RegisterOptionsExtractor makes an OptionsExtractor available under name.
+func RegisterOptionsExtractor(name string, e OptionsExtractor) {
 	dialects[strings.ToLower(name)] = e
 }
 
+// RegisterSource makes a Source available under name. Connections with
+// that driver name are read from the source instead of a database.
+func RegisterSource(name string, s Source) {
+	sources[strings.ToLower(name)] = s
+}
+
 // listRegistered returns the registered dialect keys (for diagnostics).
 func listRegistered() []string {
 	keys := make([]string, 0, len(dialects))
 	for k := range dialects {
 		keys = append(keys, k)
 	}
+	for k := range sources {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
 	return keys
 }
 
 // ConnectAndExtract connects to the database and extracts information for the ERD
 func ConnectAndExtract(driver, dsn string, timeoutSec int) (introspect.Schema, error) {
+	return ConnectAndExtractWithOptions(driver, dsn, timeoutSec, Options{})
+}
+
+// ConnectAndExtractWithOptions is ConnectAndExtract with optional extraction steps.
+// It opens a connection pool for this extraction only and closes it again,
+// servers that extract repeatedly should use a Manager instead.
+func ConnectAndExtractWithOptions(driver, dsn string, timeoutSec int, opts Options) (introspect.Schema, error) {
 	driver = config.NormalizeDriver(driver)
-	extractor, ok := dialects[driver]
-	if !ok {
-		return introspect.Schema{}, fmt.Errorf("dialect not registered: %q (available: %v)", driver, listRegistered())
+	if err := checkExtract(driver, opts); err != nil {
+		return introspect.Schema{}, err
+	}
+	if src, ok := sources[driver]; ok {
+		return load(src, dsn, timeoutSec, opts)
 	}
-	dbConn, err := sql.Open(config.NormalizeDriver(driver), dsn)
+	dbConn, err := sql.Open(driver, dsn)
 	if err != nil {
 		return introspect.Schema{}, err
 	}
+	defer dbConn.Close()
+	if opts.Concurrency > 1 {
+		// keep the connections of the workers instead of reopening them
+		dbConn.SetMaxIdleConns(opts.Concurrency)
+	}
+	return extract(dbConn, driver, timeoutSec, opts)
+}
+
+// checkExtract reports whether driver has a registered extractor and opts
+// are valid, so that no connection is opened for a request that must fail.
+func checkExtract(driver string, opts Options) error {
+	_, isSource := sources[driver]
+	if _, ok := dialects[driver]; !ok && !isSource {
+		return fmt.Errorf("dialect not registered: %q (available: %v)", driver, listRegistered())
+	}
+	return opts.Filter.Validate()
+}
+
+// extract pings dbConn and extracts its schema with the extractor of the
+// normalized driver name.
+func extract(dbConn *sql.DB, driver string, timeoutSec int, opts Options) (introspect.Schema, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
 	defer cancel()
 	if err := dbConn.PingContext(ctx); err != nil {
 		return introspect.Schema{}, err
 	}
-	return extractor.Extract(ctx, dbConn)
+	s, err := dialects[driver].ExtractWithOptions(ctx, dbConn, opts)
+	if err != nil {
+		return s, err
+	}
+	// the extractors only push the rules into SQL that the database can evaluate
+	opts.Filter.Prune(&s)
+	if opts.ExactRowCounts {
+		countRows(ctx, dbConn, driver, &s, opts.RowCountTimeout, opts.Concurrency)
+	}
+	return s, nil
 }
 
-// RegisteredDialects is a helper that allows main to print registered dialects
-func RegisteredDialects() []string {
-	return listRegistered()
+// load reads the schema of dsn from src and applies the filter.
+func load(src Source, dsn string, timeoutSec int, opts Options) (introspect.Schema, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+	s, err := src.Load(ctx, dsn)
+	if err != nil {
+		return s, err
+	}
+	opts.Filter.Prune(&s)
+	return s, nil
+}
+
+// RegisteredDialects returns the registered dialects and their capabilities, sorted by name
+func RegisteredDialects() []Dialect {
+	keys := listRegistered()
+	out := make([]Dialect, len(keys))
+	for i, k := range keys {
+		if _, ok := sources[k]; ok {
+			// sources have a version and are filtered after loading
+			out[i] = Dialect{Name: k, Capabilities: Capabilities{ChangeProbe: true}}
+			continue
+		}
+		out[i] = Dialect{Name: k, Capabilities: dialects[k].Capabilities()}
+		_, out[i].Capabilities.ChangeProbe = dialects[k].(ChangeProber)
+	}
+	return out
 }
@@ -29,8 +29,10 @@ func TestRegister(t *testing.T) {
 
 	rd := RegisteredDialects()
 
-	if !(len(rd) == 1 && rd[0] == testdialect) {
+	if !(len(rd) == 1 && rd[0].Name == testdialect) {
 		t.Errorf("\nRegisteredDialects returned unexpected result %v", rd)
+	} else if rd[0].Capabilities != (Capabilities{}) {
+		t.Errorf("\ngot capabilities %+v for a legacy extractor, wanted none", rd[0].Capabilities)
 	}
 }
 
@@ -67,3 +69,60 @@ func TestConnectAndExtract(t *testing.T) {
 		})
 	}
 }
+
+// testSource returns a fixed schema with two tables.
+type testSource struct{}
+
+func (testSource) Load(ctx context.Context, dsn string) (introspect.Schema, error) {
+	if dsn == "" {
+		return introspect.Schema{}, errors.New("no dsn")
+	}
+	return introspect.Schema{Tables: []introspect.Table{{Name: "keep"}, {Name: "drop"}}}, nil
+}
+
+func (testSource) Version(dsn string) (string, error) {
+	return "v-" + dsn, nil
+}
+
+func TestSource(t *testing.T) {
+	RegisterSource("testsource", testSource{})
+	defer delete(sources, "testsource")
+	m := NewManager(PoolConfig{})
+	defer m.Close()
+
+	var tests = []struct {
+		name     string
+		dsn      string
+		tables   int
+		errIsNil bool
+	}{
+		{"filtered", "file", 1, true},
+		{"load error", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Filter: Filter{ExcludeTables: []string{"drop"}}}
+			for _, extract := range []func() (introspect.Schema, error){
+				func() (introspect.Schema, error) { return ConnectAndExtractWithOptions("testsource", tt.dsn, 10, opts) },
+				func() (introspect.Schema, error) { return m.Extract("testsource", tt.dsn, 10, opts) },
+			} {
+				s, err := extract()
+				if (err == nil) != tt.errIsNil {
+					if tt.errIsNil {
+						t.Errorf("\ngot unexpected error: \"%v\"", err)
+					} else {
+						t.Errorf("\nexpected an error, did not receive one")
+					}
+				} else if len(s.Tables) != tt.tables {
+					t.Errorf("\ngot %d tables, wanted %d", len(s.Tables), tt.tables)
+				}
+			}
+		})
+	}
+
+	if v, ok, err := m.SchemaVersion("testsource", "file", 10); v != "v-file" || !ok || err != nil {
+		t.Errorf("\ngot version %q, %t, %v, wanted \"v-file\", true, nil", v, ok, err)
+	}
+}
@@ -0,0 +1,271 @@
+package extractors
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"erddiagram/internal/db"
+	"erddiagram/internal/introspect"
+	"erddiagram/internal/logger"
+)
+
+// tableKey builds the key used to match catalog rows to extracted tables.
+func tableKey(schema, name string) string {
+	return schema + "." + name
+}
+
+// tableMap returns the tables of s keyed by tableKey.
+// The pointers stay valid as long as no tables are appended to s.
+func tableMap(s *introspect.Schema) map[string]*introspect.Table {
+	m := make(map[string]*introspect.Table, len(s.Tables))
+	for i := range s.Tables {
+		t := &s.Tables[i]
+		m[tableKey(t.Schema, t.Name)] = t
+	}
+	return m
+}
+
+// addIndexColumn appends column col to the index idx of table t.
+// Catalog rows arrive one per index column, ordered by index name and
+// position, so a new index is started whenever the name changes.
+func addIndexColumn(t *introspect.Table, idx introspect.Index, col string, include bool) {
+	n := len(t.Indexes)
+	if n == 0 || t.Indexes[n-1].Name != idx.Name {
+		t.Indexes = append(t.Indexes, idx)
+		n++
+	}
+	if include {
+		t.Indexes[n-1].Include = append(t.Indexes[n-1].Include, col)
+	} else {
+		t.Indexes[n-1].Columns = append(t.Indexes[n-1].Columns, col)
+	}
+}
+
+// addConstraintColumn appends column col to the constraint c of table t,
+// starting a new constraint whenever the name changes like addIndexColumn.
+// An empty col only registers the constraint.
+func addConstraintColumn(t *introspect.Table, c introspect.Constraint, col string) {
+	n := len(t.Constraints)
+	if n == 0 || t.Constraints[n-1].Name != c.Name {
+		t.Constraints = append(t.Constraints, c)
+		n++
+	}
+	if col != "" {
+		t.Constraints[n-1].Columns = append(t.Constraints[n-1].Columns, col)
+	}
+}
+
+// stringOrNil returns a pointer to s, or nil if s is empty.
+func stringOrNil(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// viewMap returns the views of s keyed by tableKey.
+// The pointers stay valid as long as no views are appended to s.
+func viewMap(s *introspect.Schema) map[string]*introspect.View {
+	m := make(map[string]*introspect.View, len(s.Views))
+	for i := range s.Views {
+		v := &s.Views[i]
+		m[tableKey(v.Schema, v.Name)] = v
+	}
+	return m
+}
+
+// scanViewColumns attaches the columns in rows to the views in s.
+// Rows must be (schema, view, column, type, nullable, comment) in column order.
+func scanViewColumns(rows *sql.Rows, s *introspect.Schema) error {
+	views := viewMap(s)
+	for rows.Next() {
+		var schema, view string
+		var col introspect.Column
+		if err := rows.Scan(&schema, &view, &col.Name, &col.Type, &col.Nullable, &col.Comment); err != nil {
+			return fmt.Errorf("scan view column: %w", err)
+		}
+		if v, ok := views[tableKey(schema, view)]; ok {
+			v.Columns = append(v.Columns, col)
+		}
+	}
+	return rows.Err()
+}
+
+// scanViewDependencies attaches the relations in rows to the views in s.
+// Rows must be (schema, view, referenced schema, referenced name).
+func scanViewDependencies(rows *sql.Rows, s *introspect.Schema) error {
+	views := viewMap(s)
+	for rows.Next() {
+		var schema, view string
+		var ref introspect.ObjectRef
+		if err := rows.Scan(&schema, &view, &ref.Schema, &ref.Name); err != nil {
+			return fmt.Errorf("scan view dependency: %w", err)
+		}
+		if v, ok := views[tableKey(schema, view)]; ok {
+			v.DependsOn = append(v.DependsOn, ref)
+		}
+	}
+	return rows.Err()
+}
+
+// addForeignKeyColumn appends the column pair from/to to the foreign key fk
+// in s. Catalog rows arrive one per column pair, ordered by constraint and
+// position, so a new foreign key is started whenever the constraint changes.
+func addForeignKeyColumn(s *introspect.Schema, fk introspect.ForeignKey, from, to string) {
+	n := len(s.ForeignKeys)
+	if n == 0 || !sameForeignKey(s.ForeignKeys[n-1], fk) {
+		s.ForeignKeys = append(s.ForeignKeys, fk)
+		n++
+	}
+	s.ForeignKeys[n-1].AddColumn(from, to)
+}
+
+// sameForeignKey reports whether a and b are rows of the same constraint.
+func sameForeignKey(a, b introspect.ForeignKey) bool {
+	return a.FromSchema == b.FromSchema && a.FromTable == b.FromTable && a.Constraint == b.Constraint
+}
+
+// userType returns the type schema.name of kind in s. Catalog rows arrive
+// one per label or attribute, ordered by type, so the last type is reused
+// when it matches and a new type is appended otherwise.
+func userType(s *introspect.Schema, schema, name, kind string) *introspect.UserType {
+	n := len(s.Types)
+	if n == 0 || s.Types[n-1].Schema != schema || s.Types[n-1].Name != name || s.Types[n-1].Kind != kind {
+		s.Types = append(s.Types, introspect.UserType{Schema: schema, Name: name, Kind: kind})
+		n++
+	}
+	return &s.Types[n-1]
+}
+
+// scanRoutineReferences attaches the relations in rows to the routines in s.
+// Rows must be (routine object id, referenced schema, referenced name), and
+// byID maps the object ids to indexes into s.Routines. Routines are matched
+// by id rather than by name, as overloads share their schema and name.
+func scanRoutineReferences(rows *sql.Rows, s *introspect.Schema, byID map[int64]int) error {
+	for rows.Next() {
+		var id int64
+		var ref introspect.ObjectRef
+		if err := rows.Scan(&id, &ref.Schema, &ref.Name); err != nil {
+			return fmt.Errorf("scan routine reference: %w", err)
+		}
+		if i, ok := byID[id]; ok {
+			s.Routines[i].References = append(s.Routines[i].References, ref)
+		}
+	}
+	return rows.Err()
+}
+
+// splitEvents splits a list of trigger events such as "INSERT,UPDATE" or
+// "INSERT OR UPDATE" and normalizes them to upper case.
+func splitEvents(events string) []string {
+	var out []string
+	for _, e := range strings.Split(strings.ToUpper(events), ",") {
+		for _, ev := range strings.Split(e, " OR ") {
+			if ev = strings.TrimSpace(ev); ev != "" {
+				out = append(out, ev)
+			}
+		}
+	}
+	return out
+}
+
+// addPartition appends partition p to table t. A partitioned table holds
+// no data itself, so its size and row estimate become the totals of its
+// partitions.
+func addPartition(t *introspect.Table, strategy string, p introspect.Partition) {
+	if t.Partitioning == nil {
+		t.Partitioning = &introspect.Partitioning{Strategy: strategy}
+	}
+	if len(t.Partitioning.Partitions) == 0 {
+		t.Size8kPages, t.Rows = 0, 0
+	}
+	t.Partitioning.Partitions = append(t.Partitioning.Partitions, p)
+	t.Size8kPages += p.Size8kPages
+	t.Rows += p.Rows
+}
+
+// splitList splits a comma separated SQL list such as "a, lower(b), 'x,y'"
+// at top level commas and trims the items.
+func splitList(list string) []string {
+	var items []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(list[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(list[start:]); last != "" || len(items) > 0 {
+		items = append(items, last)
+	}
+	return items
+}
+
+// markPrimaryKey flags column col of table t as part of the primary key.
+func markPrimaryKey(t *introspect.Table, col string) {
+	for j := range t.Columns {
+		if t.Columns[j].Name == col {
+			t.Columns[j].PK = true
+		}
+	}
+}
+
+// warn logs that the optional extraction step phase failed and records it
+// as a warning in s. object names the table the step was about, if any.
+func warn(s *introspect.Schema, object, phase string, err error) {
+	if object != "" {
+		logger.Error("query %s for %s: %v", phase, object, err)
+	} else {
+		logger.Error("query %s: %v", phase, err)
+	}
+	s.AddWarning(object, phase, err)
+}
+
+// rowErrors collects the scan errors of the rows of one query, so that a
+// query with many bad rows reports one warning instead of one per row.
+type rowErrors struct {
+	n     int
+	first error
+}
+
+// add records the scan error of a row.
+func (r *rowErrors) add(err error) {
+	if r.first == nil {
+		r.first = err
+	}
+	r.n++
+}
+
+// warn adds one warning to s for the rows that failed to scan, and one if
+// iterating rows failed.
+func (r *rowErrors) warn(s *introspect.Schema, object, phase string, rows *sql.Rows) {
+	if r.n > 0 {
+		warn(s, object, phase, fmt.Errorf("scan: skipped %d row(s), first error: %w", r.n, r.first))
+	}
+	if err := rows.Err(); err != nil {
+		warn(s, object, phase, err)
+	}
+}
+
+// sectionSQL returns the select expression expr if opts requests section
+// sec and none otherwise, so that skipped sections cost no catalog lookups.
+func sectionSQL(opts db.Options, sec db.Section, expr, none string) string {
+	if !opts.Wants(sec) {
+		return none
+	}
+	return expr
+}
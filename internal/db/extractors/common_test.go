@@ -0,0 +1,69 @@
+package extractors
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+
+	"erddiagram/internal/introspect"
+)
+
+func TestSplitList(t *testing.T) {
+	var tests = []struct {
+		list  string
+		items []string
+	}{
+		{"created_at", []string{"created_at"}},
+		{"a, lower(b), c", []string{"a", "lower(b)", "c"}},
+		{"'x,y', \"a,b\", `c`, coalesce(d, 0)", []string{"'x,y'", `"a,b"`, "`c`", "coalesce(d, 0)"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.list, func(t *testing.T) {
+			if items := splitList(tt.list); !reflect.DeepEqual(items, tt.items) {
+				t.Errorf("\ngot items %q, wanted %q", items, tt.items)
+			}
+		})
+	}
+}
+
+func TestScanRoutineReferences(t *testing.T) {
+	// two overloads of s.f and a routine whose references are not in the rows
+	s := introspect.Schema{Routines: []introspect.Routine{
+		{Schema: "s", Name: "f", Arguments: "a integer"},
+		{Schema: "s", Name: "f", Arguments: "a text"},
+		{Schema: "s", Name: "g"},
+	}}
+	byID := map[int64]int{101: 0, 102: 1, 103: 2}
+	dbConn := sql.OpenDB(&countingConnector{rows: func(string) [][]driver.Value {
+		return [][]driver.Value{
+			{int64(101), "s", "orders"},
+			{int64(102), "s", "customers"},
+			{int64(102), "s", "orders"},
+			{int64(999), "s", "dropped"},
+		}
+	}})
+	defer dbConn.Close()
+	rows, err := dbConn.Query("routine references")
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	defer rows.Close()
+	if err := scanRoutineReferences(rows, &s, byID); err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+
+	want := [][]introspect.ObjectRef{
+		{{Schema: "s", Name: "orders"}},
+		{{Schema: "s", Name: "customers"}, {Schema: "s", Name: "orders"}},
+		nil,
+	}
+	for i, r := range s.Routines {
+		if !reflect.DeepEqual(r.References, want[i]) {
+			t.Errorf("\ngot references %+v for %s(%s), wanted %+v", r.References, r.Name, r.Arguments, want[i])
+		}
+	}
+}
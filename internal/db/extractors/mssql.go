@@ -4,17 +4,20 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"erddiagram/internal/db"
 	"erddiagram/internal/introspect"
-	"erddiagram/internal/logger"
 )
 
-// mssqlExtractor implements Extractor for Microsoft SQL Server.
-type mssqlExtractor struct{}
+// mssqlExtractor implements OptionsExtractor for Microsoft SQL Server.
+type mssqlExtractor struct {
+	opts db.Options
+}
 
 // This is the extractor for Microsoft SQL Server
-func (mssqlExtractor) Extract(ctx context.Context, dbConn *sql.DB) (introspect.Schema, error) {
+func (e mssqlExtractor) ExtractWithOptions(ctx context.Context, dbConn *sql.DB, opts db.Options) (introspect.Schema, error) {
+	e.opts = opts
 	var s introspect.Schema
 
 	// list tables with schema
@@ -22,20 +25,25 @@ func (mssqlExtractor) Extract(ctx context.Context, dbConn *sql.DB) (introspect.S
         SELECT 
           s.name AS schema_name, 
           t.name AS table_name, 
-          sep.value AS comment, 
-          sum(au.used_pages) as size_8k_pages
+          `+sectionSQL(e.opts, db.SectionComments, `(SELECT CAST(sep.value AS nvarchar(max))
+           FROM sys.extended_properties AS sep
+           WHERE sep.class = 1
+             AND sep.major_id = t.object_id
+             AND sep.minor_id = 0
+             AND sep.name = 'MS_Description')`, `NULL`)+` AS comment,
+          `+sectionSQL(e.opts, db.SectionSizes, `(SELECT coalesce(sum(au.used_pages), 0)
+           FROM sys.partitions AS p
+           JOIN sys.allocation_units AS au
+             ON au.container_id = p.hobt_id
+           WHERE p.object_id = t.object_id)`, `0`)+` AS size_8k_pages,
+          (SELECT coalesce(sum(rp.rows), 0)
+           FROM sys.partitions AS rp
+           WHERE rp.object_id = t.object_id
+             AND rp.index_id IN (0, 1)) AS row_estimate
         FROM sys.schemas AS s
         JOIN sys.tables AS t 
 		  ON s.schema_id = t.schema_id
-        LEFT JOIN sys.extended_properties AS sep 
-		  ON t.object_id = sep.major_id
-         AND sep.minor_id = 0
-         AND sep.name = 'MS_Description'
-        LEFT JOIN sys.partitions AS p
-          ON t.object_id = p.object_id
-        LEFT JOIN sys.allocation_units AS au
-          ON au.container_id = p.hobt_id
-        GROUP BY s.name, t.name, sep.value
+        WHERE 1 = 1`+e.opts.Filter.SQL("sqlserver", "s.name", "t.name")+`
         ORDER BY s.name, t.name`)
 	if err != nil {
 		return s, fmt.Errorf("query tables: %w", err)
@@ -44,60 +52,50 @@ func (mssqlExtractor) Extract(ctx context.Context, dbConn *sql.DB) (introspect.S
 
 	for tr.Next() {
 		var tab introspect.Table
-		if err := tr.Scan(&tab.Schema, &tab.Name, &tab.Comment, &tab.Size8kPages); err != nil {
+		if err := tr.Scan(&tab.Schema, &tab.Name, &tab.Comment, &tab.Size8kPages, &tab.Rows); err != nil {
 			return s, fmt.Errorf("scan table row: %w", err)
 		}
 		s.Tables = append(s.Tables, tab)
 	}
 
-	// columns and PKs for each table
-	for i := range s.Tables {
-		t := &s.Tables[i]
+	if err := e.extractPartitions(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "partitions", err)
+	}
 
-		cr, err := dbConn.QueryContext(ctx, `
-            SELECT COLUMN_NAME, DATA_TYPE, CASE WHEN IS_NULLABLE='YES' THEN 1 ELSE 0 END
-            FROM INFORMATION_SCHEMA.COLUMNS
-            WHERE TABLE_SCHEMA = @schema AND TABLE_NAME = @table
-            ORDER BY ORDINAL_POSITION`, sql.Named("schema", t.Schema), sql.Named("table", t.Name))
-		if err != nil {
-			return s, fmt.Errorf("query columns for %s.%s: %w", t.Schema, t.Name, err)
-		}
+	if err := e.extractColumns(ctx, dbConn, &s); err != nil {
+		return s, fmt.Errorf("query columns: %w", err)
+	}
 
-		for cr.Next() {
-			var col introspect.Column
-			var nullableInt int
-			if err := cr.Scan(&col.Name, &col.Type, &nullableInt); err != nil {
-				cr.Close()
-				return s, fmt.Errorf("scan column for %s.%s: %w", t.Schema, t.Name, err)
-			}
-			col.Nullable = nullableInt == 1
-			t.Columns = append(t.Columns, col)
-		}
-		cr.Close()
-
-		// primary keys
-		pkr, err := dbConn.QueryContext(ctx, `
-            SELECT k.COLUMN_NAME
-            FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS t
-            JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE k ON t.CONSTRAINT_NAME = k.CONSTRAINT_NAME AND t.TABLE_SCHEMA = k.TABLE_SCHEMA
-            WHERE t.CONSTRAINT_TYPE = 'PRIMARY KEY' AND k.TABLE_SCHEMA = @schema AND k.TABLE_NAME = @table`, sql.Named("schema", t.Schema), sql.Named("table", t.Name))
-		if err == nil {
-			for pkr.Next() {
-				var pkcol string
-				if err := pkr.Scan(&pkcol); err == nil {
-					for j := range t.Columns {
-						if t.Columns[j].Name == pkcol {
-							t.Columns[j].PK = true
-						}
-					}
-				} else {
-					logger.Error("scan primary key: %v", err)
-				}
-			}
-			pkr.Close()
-		} else {
-			logger.Error("query primary key: %v", err)
-		}
+	if err := e.extractPrimaryKeys(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "primary keys", err)
+	}
+
+	if err := e.extractIndexes(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "indexes", err)
+	}
+
+	if err := e.extractConstraints(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "constraints", err)
+	}
+
+	if err := e.extractViews(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "views", err)
+	}
+
+	if err := e.extractTypes(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "types", err)
+	}
+
+	if err := e.extractSequences(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "sequences", err)
+	}
+
+	if err := e.extractTriggers(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "triggers", err)
+	}
+
+	if err := e.extractRoutines(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "routines", err)
 	}
 
 	// foreign keys with schema information
@@ -105,34 +103,646 @@ func (mssqlExtractor) Extract(ctx context.Context, dbConn *sql.DB) (introspect.S
         SELECT
             OBJECT_SCHEMA_NAME(fkc.parent_object_id) AS from_schema,
             OBJECT_NAME(fkc.parent_object_id) AS from_table,
-            STRING_AGG(c.NAME, ', ') AS from_column,
+            c.name AS from_column,
             OBJECT_SCHEMA_NAME(fkc.referenced_object_id) AS to_schema,
             OBJECT_NAME(fkc.referenced_object_id) AS to_table,
-            STRING_AGG(rc.NAME, ', ') AS to_column,
-			fk.name AS constraint_name
+            rc.name AS to_column,
+            fk.name AS constraint_name,
+            REPLACE(fk.delete_referential_action_desc, '_', ' ') AS delete_rule,
+            REPLACE(fk.update_referential_action_desc, '_', ' ') AS update_rule,
+            fk.is_disabled,
+            fk.is_not_trusted
         FROM sys.foreign_keys fk
-		JOIN sys.foreign_key_columns fkc ON fk.object_id = fkc.constraint_object_id
+        JOIN sys.foreign_key_columns fkc ON fk.object_id = fkc.constraint_object_id
         JOIN sys.columns c ON fkc.parent_object_id = c.object_id AND fkc.parent_column_id = c.column_id
         JOIN sys.columns rc ON fkc.referenced_object_id = rc.object_id AND fkc.referenced_column_id = rc.column_id
-        GROUP BY fk.name, fkc.parent_object_id, fkc.referenced_object_id`)
+        WHERE 1 = 1`+e.opts.Filter.SQL("sqlserver", "OBJECT_SCHEMA_NAME(fkc.parent_object_id)", "OBJECT_NAME(fkc.parent_object_id)")+`
+        ORDER BY from_schema, from_table, constraint_name, fkc.constraint_column_id`)
 	if err == nil {
 		defer fkr.Close()
+		var failed rowErrors
 		for fkr.Next() {
 			var fk introspect.ForeignKey
-			if err := fkr.Scan(&fk.FromSchema, &fk.FromTable, &fk.FromColumn, &fk.ToSchema, &fk.ToTable, &fk.ToColumn, &fk.Constraint); err == nil {
-				s.ForeignKeys = append(s.ForeignKeys, fk)
+			var from, to string
+			if err := fkr.Scan(&fk.FromSchema, &fk.FromTable, &from, &fk.ToSchema, &fk.ToTable, &to, &fk.Constraint,
+				&fk.OnDelete, &fk.OnUpdate, &fk.Disabled, &fk.NotValidated); err == nil {
+				addForeignKeyColumn(&s, fk, from, to)
 			} else {
-				logger.Error("scan foreign key: %v", err)
+				failed.add(err)
 			}
 		}
+		failed.warn(&s, "", "foreign keys", fkr)
 	} else {
-		logger.Error("query foreign key: %v", err)
+		warn(&s, "", "foreign keys", err)
 	}
 
 	return s, nil
 }
 
+// extractColumns reads the columns of all tables in one query and attaches
+// them to the tables in s.
+func (e mssqlExtractor) extractColumns(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT c.TABLE_SCHEMA, c.TABLE_NAME,
+               c.COLUMN_NAME, COALESCE(c.DOMAIN_NAME, c.DATA_TYPE), CASE WHEN c.IS_NULLABLE='YES' THEN 1 ELSE 0 END,
+               c.COLUMN_DEFAULT,
+               ISNULL(COLUMNPROPERTY(OBJECT_ID(QUOTENAME(c.TABLE_SCHEMA) + '.' + QUOTENAME(c.TABLE_NAME)), c.COLUMN_NAME, 'IsIdentity'), 0),
+               cc.definition,
+               c.CHARACTER_MAXIMUM_LENGTH,
+               CASE WHEN c.DATA_TYPE IN ('decimal', 'numeric') THEN c.NUMERIC_PRECISION END,
+               CASE WHEN c.DATA_TYPE IN ('decimal', 'numeric') THEN c.NUMERIC_SCALE END,
+               c.COLLATION_NAME,
+               `+sectionSQL(e.opts, db.SectionComments, `CAST(sep.value AS nvarchar(max))`, `NULL`)+` AS comment
+        FROM INFORMATION_SCHEMA.COLUMNS c
+        LEFT JOIN sys.computed_columns cc
+          ON cc.object_id = OBJECT_ID(QUOTENAME(c.TABLE_SCHEMA) + '.' + QUOTENAME(c.TABLE_NAME))
+         AND cc.name = c.COLUMN_NAME`+sectionSQL(e.opts, db.SectionComments, `
+        LEFT JOIN sys.extended_properties AS sep
+          ON sep.class = 1
+         AND sep.major_id = OBJECT_ID(QUOTENAME(c.TABLE_SCHEMA) + '.' + QUOTENAME(c.TABLE_NAME))
+         AND sep.minor_id = COLUMNPROPERTY(sep.major_id, c.COLUMN_NAME, 'ColumnId')
+         AND sep.name = 'MS_Description'`, ``)+`
+        WHERE 1 = 1`+e.opts.Filter.SQL("sqlserver", "c.TABLE_SCHEMA", "c.TABLE_NAME")+`
+        ORDER BY c.TABLE_SCHEMA, c.TABLE_NAME, c.ORDINAL_POSITION`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table string
+		var col introspect.Column
+		var nullableInt, identityInt int
+		if err := rows.Scan(&schema, &table, &col.Name, &col.Type, &nullableInt, &col.Default, &identityInt, &col.Generated,
+			&col.MaxLength, &col.Precision, &col.Scale, &col.Collation, &col.Comment); err != nil {
+			return fmt.Errorf("scan column: %w", err)
+		}
+		col.Nullable = nullableInt == 1
+		col.Identity = identityInt == 1
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			t.Columns = append(t.Columns, col)
+		}
+	}
+	return rows.Err()
+}
+
+// extractPrimaryKeys reads the primary key columns of all tables in one
+// query and flags them in s.
+func (e mssqlExtractor) extractPrimaryKeys(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT k.TABLE_SCHEMA, k.TABLE_NAME, k.COLUMN_NAME
+        FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS t
+        JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE k
+          ON t.CONSTRAINT_NAME = k.CONSTRAINT_NAME
+         AND t.TABLE_SCHEMA = k.TABLE_SCHEMA
+        WHERE t.CONSTRAINT_TYPE = 'PRIMARY KEY'`+e.opts.Filter.SQL("sqlserver", "k.TABLE_SCHEMA", "k.TABLE_NAME"))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table, pkcol string
+		if err := rows.Scan(&schema, &table, &pkcol); err != nil {
+			return fmt.Errorf("scan primary key: %w", err)
+		}
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			markPrimaryKey(t, pkcol)
+		}
+	}
+	return rows.Err()
+}
+
+// extractPartitions reads the partition function, the partitioning column
+// and the partitions with their boundaries of all partitioned tables.
+// SQL Server keeps the partitions inside the table, so the table list and
+// the table sizes are already complete.
+func (e mssqlExtractor) extractPartitions(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionPartitions) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT
+          s.name AS schema_name,
+          t.name AS table_name,
+          pf.type_desc AS strategy,
+          pf.boundary_value_on_right,
+          c.name AS key_column,
+          p.partition_number,
+          p.rows,
+          `+sectionSQL(e.opts, db.SectionSizes, `(SELECT coalesce(sum(au.used_pages), 0)
+           FROM sys.partitions AS ap
+           JOIN sys.allocation_units AS au
+             ON au.container_id = ap.hobt_id
+           WHERE ap.object_id = t.object_id
+             AND ap.partition_number = p.partition_number)`, `0`)+` AS size_8k_pages,
+          CONVERT(nvarchar(4000), lo.value, 121) AS lower_bound,
+          CONVERT(nvarchar(4000), hi.value, 121) AS upper_bound
+        FROM sys.tables AS t
+        JOIN sys.schemas AS s
+          ON s.schema_id = t.schema_id
+        JOIN sys.indexes AS i
+          ON i.object_id = t.object_id
+         AND i.index_id IN (0, 1)
+        JOIN sys.partition_schemes AS ps
+          ON ps.data_space_id = i.data_space_id
+        JOIN sys.partition_functions AS pf
+          ON pf.function_id = ps.function_id
+        JOIN sys.index_columns AS ic
+          ON ic.object_id = i.object_id
+         AND ic.index_id = i.index_id
+         AND ic.partition_ordinal = 1
+        JOIN sys.columns AS c
+          ON c.object_id = t.object_id
+         AND c.column_id = ic.column_id
+        JOIN sys.partitions AS p
+          ON p.object_id = t.object_id
+         AND p.index_id = i.index_id
+        LEFT JOIN sys.partition_range_values AS lo
+          ON lo.function_id = pf.function_id
+         AND lo.boundary_id = p.partition_number - 1
+        LEFT JOIN sys.partition_range_values AS hi
+          ON hi.function_id = pf.function_id
+         AND hi.boundary_id = p.partition_number
+        WHERE 1 = 1`+e.opts.Filter.SQL("sqlserver", "s.name", "t.name")+`
+        ORDER BY s.name, t.name, p.partition_number`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table, strategy, key string
+		var right bool
+		var lo, hi sql.NullString
+		var p introspect.Partition
+		if err := rows.Scan(&schema, &table, &strategy, &right, &key, &p.Number, &p.Rows, &p.Size8kPages, &lo, &hi); err != nil {
+			return fmt.Errorf("scan partition: %w", err)
+		}
+		t, ok := tables[tableKey(schema, table)]
+		if !ok {
+			continue
+		}
+		p.Schema = schema
+		p.Bound = mssqlPartitionBound(key, right, lo, hi)
+		addPartition(t, strategy, p)
+		t.Partitioning.Columns = []string{key}
+	}
+	return rows.Err()
+}
+
+// mssqlPartitionBound describes the range of values of column key that a
+// partition between the boundary values lo and hi holds. With RANGE RIGHT
+// a boundary value belongs to the partition above it, with RANGE LEFT to
+// the partition below it.
+func mssqlPartitionBound(key string, right bool, lo, hi sql.NullString) string {
+	lower, upper := ">", "<="
+	if right {
+		lower, upper = ">=", "<"
+	}
+	var conds []string
+	if lo.Valid {
+		conds = append(conds, fmt.Sprintf("%s %s %s", key, lower, lo.String))
+	}
+	if hi.Valid {
+		conds = append(conds, fmt.Sprintf("%s %s %s", key, upper, hi.String))
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// extractIndexes reads all indexes, including filtered indexes and included
+// columns, and attaches them to the tables in s.
+func (e mssqlExtractor) extractIndexes(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionIndexes) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT
+          s.name AS schema_name,
+          t.name AS table_name,
+          i.name AS index_name,
+          i.is_unique,
+          i.is_primary_key,
+          coalesce(i.filter_definition, '') AS predicate,
+          lower(i.type_desc) AS method,
+          c.name AS column_name,
+          ic.is_included_column
+        FROM sys.indexes AS i
+        JOIN sys.tables AS t
+          ON t.object_id = i.object_id
+        JOIN sys.schemas AS s
+          ON s.schema_id = t.schema_id
+        JOIN sys.index_columns AS ic
+          ON ic.object_id = i.object_id
+         AND ic.index_id = i.index_id
+        JOIN sys.columns AS c
+          ON c.object_id = ic.object_id
+         AND c.column_id = ic.column_id
+        WHERE i.type > 0
+          AND i.is_hypothetical = 0`+e.opts.Filter.SQL("sqlserver", "s.name", "t.name")+`
+        ORDER BY s.name, t.name, i.name, ic.is_included_column, ic.key_ordinal, ic.index_column_id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table, col string
+		var idx introspect.Index
+		var included bool
+		if err := rows.Scan(&schema, &table, &idx.Name, &idx.Unique, &idx.Primary, &idx.Predicate, &idx.Method, &col, &included); err != nil {
+			return fmt.Errorf("scan index: %w", err)
+		}
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			addIndexColumn(t, idx, col, included)
+		}
+	}
+	return rows.Err()
+}
+
+// extractConstraints reads unique and check constraints and attaches them
+// to the tables in s.
+func (e mssqlExtractor) extractConstraints(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionConstraints) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT s.name, t.name, kc.name, 'UNIQUE', NULL, c.name, ic.key_ordinal
+        FROM sys.key_constraints AS kc
+        JOIN sys.tables AS t
+          ON t.object_id = kc.parent_object_id
+        JOIN sys.schemas AS s
+          ON s.schema_id = t.schema_id
+        JOIN sys.index_columns AS ic
+          ON ic.object_id = kc.parent_object_id
+         AND ic.index_id = kc.unique_index_id
+        JOIN sys.columns AS c
+          ON c.object_id = ic.object_id
+         AND c.column_id = ic.column_id
+        WHERE kc.type = 'UQ'`+e.opts.Filter.SQL("sqlserver", "s.name", "t.name")+`
+        UNION ALL
+        SELECT s.name, t.name, cc.name, 'CHECK', cc.definition, c.name, 0
+        FROM sys.check_constraints AS cc
+        JOIN sys.tables AS t
+          ON t.object_id = cc.parent_object_id
+        JOIN sys.schemas AS s
+          ON s.schema_id = t.schema_id
+        LEFT JOIN sys.columns AS c
+          ON c.object_id = cc.parent_object_id
+         AND c.column_id = cc.parent_column_id
+        WHERE 1 = 1`+e.opts.Filter.SQL("sqlserver", "s.name", "t.name")+`
+        ORDER BY 1, 2, 3, 7`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table string
+		var col, expr sql.NullString
+		var ord int
+		var c introspect.Constraint
+		if err := rows.Scan(&schema, &table, &c.Name, &c.Type, &expr, &col, &ord); err != nil {
+			return fmt.Errorf("scan constraint: %w", err)
+		}
+		c.Expression = expr.String
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			addConstraintColumn(t, c, col.String)
+		}
+	}
+	return rows.Err()
+}
+
+// extractViews reads views with their columns and the tables and views
+// they reference. Views with a clustered index are flagged as indexed.
+func (e mssqlExtractor) extractViews(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionViews) {
+		return nil
+	}
+	vr, err := dbConn.QueryContext(ctx, `
+        SELECT
+          s.name AS schema_name,
+          v.name AS view_name,
+          CASE WHEN EXISTS (SELECT 1 FROM sys.indexes AS i WHERE i.object_id = v.object_id AND i.index_id = 1)
+               THEN 1 ELSE 0 END AS is_indexed,
+          m.definition,
+          `+sectionSQL(e.opts, db.SectionComments, `CAST(sep.value AS nvarchar(max))`, `NULL`)+` AS comment
+        FROM sys.views AS v
+        JOIN sys.schemas AS s
+          ON s.schema_id = v.schema_id
+        LEFT JOIN sys.sql_modules AS m
+          ON m.object_id = v.object_id`+sectionSQL(e.opts, db.SectionComments, `
+        LEFT JOIN sys.extended_properties AS sep
+          ON v.object_id = sep.major_id
+         AND sep.minor_id = 0
+         AND sep.name = 'MS_Description'`, ``)+`
+        WHERE v.is_ms_shipped = 0`+e.opts.Filter.SQL("sqlserver", "s.name", "v.name")+`
+        ORDER BY s.name, v.name`)
+	if err != nil {
+		return err
+	}
+	defer vr.Close()
+	for vr.Next() {
+		var v introspect.View
+		var def sql.NullString
+		if err := vr.Scan(&v.Schema, &v.Name, &v.Indexed, &def, &v.Comment); err != nil {
+			return fmt.Errorf("scan view: %w", err)
+		}
+		v.Definition = def.String
+		s.Views = append(s.Views, v)
+	}
+	if err := vr.Err(); err != nil {
+		return err
+	}
+
+	cr, err := dbConn.QueryContext(ctx, `
+        SELECT s.name, v.name, c.name, TYPE_NAME(c.user_type_id), c.is_nullable,
+               `+sectionSQL(e.opts, db.SectionComments, `CAST(sep.value AS nvarchar(max))`, `NULL`)+`
+        FROM sys.columns AS c
+        JOIN sys.views AS v
+          ON v.object_id = c.object_id
+        JOIN sys.schemas AS s
+          ON s.schema_id = v.schema_id`+sectionSQL(e.opts, db.SectionComments, `
+        LEFT JOIN sys.extended_properties AS sep
+          ON sep.class = 1
+         AND sep.major_id = c.object_id
+         AND sep.minor_id = c.column_id
+         AND sep.name = 'MS_Description'`, ``)+`
+        WHERE v.is_ms_shipped = 0`+e.opts.Filter.SQL("sqlserver", "s.name", "v.name")+`
+        ORDER BY s.name, v.name, c.column_id`)
+	if err != nil {
+		return fmt.Errorf("query view columns: %w", err)
+	}
+	defer cr.Close()
+	if err := scanViewColumns(cr, s); err != nil {
+		return err
+	}
+
+	dr, err := dbConn.QueryContext(ctx, `
+        SELECT DISTINCT s.name, v.name, OBJECT_SCHEMA_NAME(d.referenced_id), OBJECT_NAME(d.referenced_id)
+        FROM sys.sql_expression_dependencies AS d
+        JOIN sys.views AS v
+          ON v.object_id = d.referencing_id
+        JOIN sys.schemas AS s
+          ON s.schema_id = v.schema_id
+        JOIN sys.objects AS o
+          ON o.object_id = d.referenced_id
+        WHERE o.type IN ('U', 'V')
+          AND v.is_ms_shipped = 0`+e.opts.Filter.SQL("sqlserver", "s.name", "v.name")+`
+        ORDER BY 1, 2, 3, 4`)
+	if err != nil {
+		return fmt.Errorf("query view dependencies: %w", err)
+	}
+	defer dr.Close()
+	return scanViewDependencies(dr, s)
+}
+
+// extractTypes reads user-defined alias types with their base type and
+// table types with their columns.
+func (e mssqlExtractor) extractTypes(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionTypes) {
+		return nil
+	}
+	tr, err := dbConn.QueryContext(ctx, `
+        SELECT
+          s.name AS schema_name,
+          t.name AS type_name,
+          CASE WHEN t.is_table_type = 1 THEN 'table' ELSE 'alias' END AS kind,
+          CASE WHEN t.is_table_type = 1 THEN '' ELSE TYPE_NAME(t.system_type_id) END AS base_type,
+          `+sectionSQL(e.opts, db.SectionComments, `CAST(sep.value AS nvarchar(max))`, `NULL`)+` AS comment
+        FROM sys.types AS t
+        JOIN sys.schemas AS s
+          ON s.schema_id = t.schema_id`+sectionSQL(e.opts, db.SectionComments, `
+        LEFT JOIN sys.extended_properties AS sep
+          ON sep.class = 6
+         AND sep.major_id = t.user_type_id
+         AND sep.minor_id = 0
+         AND sep.name = 'MS_Description'`, ``)+`
+        WHERE t.is_user_defined = 1`+e.opts.Filter.SQL("sqlserver", "s.name", "")+`
+        ORDER BY s.name, t.name`)
+	if err != nil {
+		return err
+	}
+	defer tr.Close()
+	for tr.Next() {
+		var ut introspect.UserType
+		if err := tr.Scan(&ut.Schema, &ut.Name, &ut.Kind, &ut.BaseType, &ut.Comment); err != nil {
+			return fmt.Errorf("scan type: %w", err)
+		}
+		s.Types = append(s.Types, ut)
+	}
+	if err := tr.Err(); err != nil {
+		return err
+	}
+
+	cr, err := dbConn.QueryContext(ctx, `
+        SELECT s.name, tt.name, c.name, TYPE_NAME(c.user_type_id), c.is_nullable
+        FROM sys.table_types AS tt
+        JOIN sys.schemas AS s
+          ON s.schema_id = tt.schema_id
+        JOIN sys.columns AS c
+          ON c.object_id = tt.type_table_object_id
+        WHERE 1 = 1`+e.opts.Filter.SQL("sqlserver", "s.name", "")+`
+        ORDER BY s.name, tt.name, c.column_id`)
+	if err != nil {
+		return fmt.Errorf("query table type columns: %w", err)
+	}
+	defer cr.Close()
+
+	types := map[string]*introspect.UserType{}
+	for i := range s.Types {
+		types[tableKey(s.Types[i].Schema, s.Types[i].Name)] = &s.Types[i]
+	}
+	for cr.Next() {
+		var schema, name string
+		var col introspect.Column
+		if err := cr.Scan(&schema, &name, &col.Name, &col.Type, &col.Nullable); err != nil {
+			return fmt.Errorf("scan table type column: %w", err)
+		}
+		if ut, ok := types[tableKey(schema, name)]; ok {
+			ut.Attributes = append(ut.Attributes, col)
+		}
+	}
+	return cr.Err()
+}
+
+// extractSequences reads all sequence objects. Identity columns are not
+// backed by sequences in SQL Server, so OwnedBy is never set.
+func (e mssqlExtractor) extractSequences(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionSequences) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT
+          s.name AS schema_name,
+          sq.name AS sequence_name,
+          TYPE_NAME(sq.user_type_id) AS data_type,
+          CAST(sq.start_value AS bigint) AS start_value,
+          CAST(sq.increment AS bigint) AS increment,
+          sq.is_cycling
+        FROM sys.sequences AS sq
+        JOIN sys.schemas AS s
+          ON s.schema_id = sq.schema_id
+        WHERE 1 = 1`+e.opts.Filter.SQL("sqlserver", "s.name", "")+`
+        ORDER BY s.name, sq.name`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var seq introspect.Sequence
+		if err := rows.Scan(&seq.Schema, &seq.Name, &seq.DataType, &seq.Start, &seq.Increment, &seq.Cycle); err != nil {
+			return fmt.Errorf("scan sequence: %w", err)
+		}
+		s.Sequences = append(s.Sequences, seq)
+	}
+	return rows.Err()
+}
+
+// extractTriggers reads DML triggers on tables and views. SQL Server
+// triggers always fire once per statement.
+func (e mssqlExtractor) extractTriggers(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionTriggers) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT
+          s.name AS schema_name,
+          tr.name AS trigger_name,
+          o.name AS table_name,
+          CASE WHEN tr.is_instead_of_trigger = 1 THEN 'INSTEAD OF' ELSE 'AFTER' END AS timing,
+          (SELECT STRING_AGG(te.type_desc, ',')
+           FROM sys.trigger_events AS te
+           WHERE te.object_id = tr.object_id) AS events,
+          tr.is_disabled,
+          coalesce(OBJECT_DEFINITION(tr.object_id), '') AS definition
+        FROM sys.triggers AS tr
+        JOIN sys.objects AS o
+          ON o.object_id = tr.parent_id
+        JOIN sys.schemas AS s
+          ON s.schema_id = o.schema_id
+        WHERE tr.parent_class = 1
+          AND tr.is_ms_shipped = 0`+e.opts.Filter.SQL("sqlserver", "s.name", "o.name")+`
+        ORDER BY s.name, o.name, tr.name`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		tr := introspect.Trigger{Level: "STATEMENT"}
+		var events sql.NullString
+		if err := rows.Scan(&tr.Schema, &tr.Name, &tr.Table.Name, &tr.Timing, &events, &tr.Disabled, &tr.Definition); err != nil {
+			return fmt.Errorf("scan trigger: %w", err)
+		}
+		tr.Table.Schema = tr.Schema
+		tr.Events = splitEvents(events.String)
+		s.Triggers = append(s.Triggers, tr)
+	}
+	return rows.Err()
+}
+
+// extractRoutines reads stored procedures and functions with their
+// parameters and the tables and views they reference.
+func (e mssqlExtractor) extractRoutines(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionRoutines) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT
+          o.object_id,
+          s.name AS schema_name,
+          o.name AS routine_name,
+          CASE WHEN o.type = 'P' THEN 'PROCEDURE' ELSE 'FUNCTION' END AS kind,
+          coalesce((SELECT STRING_AGG(p.name + ' ' + TYPE_NAME(p.user_type_id)
+                                      + CASE WHEN p.is_output = 1 THEN ' OUTPUT' ELSE '' END, ', ')
+                              WITHIN GROUP (ORDER BY p.parameter_id)
+                    FROM sys.parameters AS p
+                    WHERE p.object_id = o.object_id
+                      AND p.parameter_id > 0), '') AS arguments,
+          CASE o.type
+            WHEN 'FN' THEN coalesce((SELECT TYPE_NAME(p.user_type_id)
+                                     FROM sys.parameters AS p
+                                     WHERE p.object_id = o.object_id
+                                       AND p.parameter_id = 0), '')
+            WHEN 'P' THEN ''
+            ELSE 'TABLE'
+          END AS return_type,
+          'SQL' AS language,
+          coalesce(OBJECT_DEFINITION(o.object_id), '') AS definition
+        FROM sys.objects AS o
+        JOIN sys.schemas AS s
+          ON s.schema_id = o.schema_id
+        WHERE o.type IN ('P', 'FN', 'IF', 'TF')
+          AND o.is_ms_shipped = 0`+e.opts.Filter.SQL("sqlserver", "s.name", "")+`
+        ORDER BY s.name, o.name`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	byID := map[int64]int{}
+	for rows.Next() {
+		var id int64
+		var r introspect.Routine
+		if err := rows.Scan(&id, &r.Schema, &r.Name, &r.Kind, &r.Arguments, &r.ReturnType, &r.Language, &r.Definition); err != nil {
+			return fmt.Errorf("scan routine: %w", err)
+		}
+		byID[id] = len(s.Routines)
+		s.Routines = append(s.Routines, r)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	dr, err := dbConn.QueryContext(ctx, `
+        SELECT DISTINCT
+          o.object_id,
+          rs.name AS referenced_schema,
+          ro.name AS referenced_name
+        FROM sys.sql_expression_dependencies AS d
+        JOIN sys.objects AS o
+          ON o.object_id = d.referencing_id
+        JOIN sys.schemas AS s
+          ON s.schema_id = o.schema_id
+        JOIN sys.objects AS ro
+          ON ro.object_id = d.referenced_id
+        JOIN sys.schemas AS rs
+          ON rs.schema_id = ro.schema_id
+        WHERE o.type IN ('P', 'FN', 'IF', 'TF')
+          AND ro.type IN ('U', 'V')`+e.opts.Filter.SQL("sqlserver", "s.name", "")+`
+        ORDER BY o.object_id, rs.name, ro.name`)
+	if err != nil {
+		return fmt.Errorf("query routine dependencies: %w", err)
+	}
+	defer dr.Close()
+	return scanRoutineReferences(dr, s, byID)
+}
+
+// SchemaVersion combines the newest modify_date of the user objects, which
+// every ALTER updates, with the number of objects and user types, which
+// drops reduce, and a checksum of the descriptions.
+func (mssqlExtractor) SchemaVersion(ctx context.Context, dbConn *sql.DB) (string, error) {
+	var version string
+	err := dbConn.QueryRowContext(ctx, `
+        SELECT CONCAT(
+          (SELECT CONCAT(COUNT(*), ':', CONVERT(varchar(33), MAX(modify_date), 126)) FROM sys.objects WHERE is_ms_shipped = 0),
+          '/', (SELECT COUNT(*) FROM sys.types WHERE is_user_defined = 1),
+          '/', (SELECT CHECKSUM_AGG(CHECKSUM(major_id, minor_id, name, CAST(value AS nvarchar(4000))))
+                FROM sys.extended_properties
+                WHERE name = 'MS_Description'))`).Scan(&version)
+	return version, err
+}
+
+// Capabilities reports the sections the extractor can return.
+func (mssqlExtractor) Capabilities() db.Capabilities {
+	return db.Capabilities{Sections: db.AllSections, Filter: true}
+}
+
 func init() {
-	db.Register("sqlserver", mssqlExtractor{})
-	db.Register("mssql", mssqlExtractor{})
+	db.RegisterOptionsExtractor("sqlserver", mssqlExtractor{})
+	db.RegisterOptionsExtractor("mssql", mssqlExtractor{})
 }
@@ -0,0 +1,30 @@
+package extractors
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestMSSQLPartitionBound(t *testing.T) {
+	var tests = []struct {
+		name  string
+		right bool
+		lo    sql.NullString
+		hi    sql.NullString
+		bound string
+	}{
+		{"first left", false, sql.NullString{}, sql.NullString{String: "100", Valid: true}, "id <= 100"},
+		{"middle left", false, sql.NullString{String: "100", Valid: true}, sql.NullString{String: "200", Valid: true}, "id > 100 AND id <= 200"},
+		{"last right", true, sql.NullString{String: "200", Valid: true}, sql.NullString{}, "id >= 200"},
+		{"single", true, sql.NullString{}, sql.NullString{}, ""},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			if b := mssqlPartitionBound("id", tt.right, tt.lo, tt.hi); b != tt.bound {
+				t.Errorf("\ngot bound %q, wanted %q", b, tt.bound)
+			}
+		})
+	}
+}
@@ -4,24 +4,28 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"erddiagram/internal/db"
 	"erddiagram/internal/introspect"
-	"erddiagram/internal/logger"
 )
 
-// myExtractor implements Extractor for MySQL (information_schema).
-type myExtractor struct{}
+// myExtractor implements OptionsExtractor for MySQL (information_schema).
+type myExtractor struct {
+	opts db.Options
+}
 
 // This is the extractor for MySQL
-func (myExtractor) Extract(ctx context.Context, dbConn *sql.DB) (introspect.Schema, error) {
+func (e myExtractor) ExtractWithOptions(ctx context.Context, dbConn *sql.DB, opts db.Options) (introspect.Schema, error) {
+	e.opts = opts
 	var s introspect.Schema
 
 	tr, err := dbConn.QueryContext(ctx, `
-        SELECT table_schema, table_name, table_comment, round(data_length/8192) AS size_8k_pages
+        SELECT table_schema, table_name, `+sectionSQL(e.opts, db.SectionComments, `table_comment`, `NULL`)+`, round(data_length/8192) AS size_8k_pages,
+               coalesce(table_rows, 0) AS row_estimate
         FROM information_schema.tables
         WHERE table_type = 'BASE TABLE'
-          AND table_schema NOT IN ('mysql','information_schema','performance_schema','sys')
+          AND table_schema NOT IN ('mysql','information_schema','performance_schema','sys')`+e.opts.Filter.SQL("mysql", "table_schema", "table_name")+`
         ORDER BY table_schema, table_name`)
 	if err != nil {
 		return s, fmt.Errorf("query tables: %w", err)
@@ -30,83 +34,487 @@ func (myExtractor) Extract(ctx context.Context, dbConn *sql.DB) (introspect.Sche
 
 	for tr.Next() {
 		var tab introspect.Table
-		if err := tr.Scan(&tab.Schema, &tab.Name, &tab.Comment, &tab.Size8kPages); err != nil {
+		if err := tr.Scan(&tab.Schema, &tab.Name, &tab.Comment, &tab.Size8kPages, &tab.Rows); err != nil {
 			return s, fmt.Errorf("scan table row: %w", err)
 		}
 		s.Tables = append(s.Tables, tab)
 	}
 
-	for i := range s.Tables {
-		t := &s.Tables[i]
-		cr, err := dbConn.QueryContext(ctx, `
-            SELECT column_name, column_type, is_nullable = 'YES'
-            FROM information_schema.columns
-            WHERE table_schema = ? AND table_name = ?
-            ORDER BY ordinal_position`, t.Schema, t.Name)
-		if err != nil {
-			return s, fmt.Errorf("query columns for %s.%s: %w", t.Schema, t.Name, err)
-		}
-		for cr.Next() {
-			var col introspect.Column
-			if err := cr.Scan(&col.Name, &col.Type, &col.Nullable); err != nil {
-				cr.Close()
-				return s, fmt.Errorf("scan column for %s.%s: %w", t.Schema, t.Name, err)
-			}
-			t.Columns = append(t.Columns, col)
-		}
-		cr.Close()
-
-		pkr, err := dbConn.QueryContext(ctx, `
-            SELECT k.COLUMN_NAME
-            FROM information_schema.key_column_usage k
-            JOIN information_schema.table_constraints tc ON k.constraint_name = tc.constraint_name AND k.table_schema = tc.table_schema
-            WHERE tc.constraint_type = 'PRIMARY KEY' AND k.table_schema = ? AND k.table_name = ?`, t.Schema, t.Name)
-		if err == nil {
-			for pkr.Next() {
-				var pkcol string
-				if err := pkr.Scan(&pkcol); err == nil {
-					for j := range t.Columns {
-						if t.Columns[j].Name == pkcol {
-							t.Columns[j].PK = true
-						}
-					}
-				} else {
-					logger.Error("scan primary key: %v", err)
-				}
-			}
-			pkr.Close()
-		} else {
-			logger.Error("query primary key: %v", err)
-		}
+	if err := e.extractPartitions(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "partitions", err)
+	}
+
+	if err := e.extractColumns(ctx, dbConn, &s); err != nil {
+		return s, fmt.Errorf("query columns: %w", err)
+	}
+
+	if err := e.extractPrimaryKeys(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "primary keys", err)
+	}
+
+	if err := e.extractIndexes(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "indexes", err)
+	}
+
+	if err := e.extractConstraints(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "constraints", err)
+	}
+
+	if err := e.extractViews(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "views", err)
+	}
+
+	if err := e.extractTypes(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "types", err)
+	}
+
+	if err := e.extractTriggers(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "triggers", err)
+	}
+
+	if err := e.extractRoutines(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "routines", err)
 	}
 
 	fkr, err := dbConn.QueryContext(ctx, `
-        SELECT table_schema AS from_schema, table_name AS from_table, 
-		       group_concat(column_name separator ', ') AS from_column,
-               referenced_table_schema AS to_schema, referenced_table_name AS to_table, 
-			   group_concat(referenced_column_name separator ', ') AS to_column,
-			   constraint_name
-        FROM information_schema.key_column_usage
-        WHERE referenced_table_name IS NOT NULL AND table_schema NOT IN ('mysql','information_schema','performance_schema','sys')
-		GROUP BY table_schema, table_name, referenced_table_schema, referenced_table_name, constraint_name`)
+        SELECT kcu.table_schema AS from_schema, kcu.table_name AS from_table, kcu.column_name AS from_column,
+               kcu.referenced_table_schema AS to_schema, kcu.referenced_table_name AS to_table,
+               kcu.referenced_column_name AS to_column, kcu.constraint_name,
+               rc.delete_rule, rc.update_rule
+        FROM information_schema.key_column_usage kcu
+        JOIN information_schema.referential_constraints rc
+          ON rc.constraint_schema = kcu.constraint_schema
+         AND rc.constraint_name = kcu.constraint_name
+         AND rc.table_name = kcu.table_name
+        WHERE kcu.referenced_table_name IS NOT NULL AND kcu.table_schema NOT IN ('mysql','information_schema','performance_schema','sys')`+e.opts.Filter.SQL("mysql", "kcu.table_schema", "kcu.table_name")+`
+        ORDER BY kcu.table_schema, kcu.table_name, kcu.constraint_name, kcu.ordinal_position`)
 	if err == nil {
 		defer fkr.Close()
+		var failed rowErrors
 		for fkr.Next() {
 			var fk introspect.ForeignKey
-			if err := fkr.Scan(&fk.FromSchema, &fk.FromTable, &fk.FromColumn, &fk.ToSchema, &fk.ToTable, &fk.ToColumn, &fk.Constraint); err == nil {
-				s.ForeignKeys = append(s.ForeignKeys, fk)
+			var from, to string
+			if err := fkr.Scan(&fk.FromSchema, &fk.FromTable, &from, &fk.ToSchema, &fk.ToTable, &to, &fk.Constraint,
+				&fk.OnDelete, &fk.OnUpdate); err == nil {
+				addForeignKeyColumn(&s, fk, from, to)
 			} else {
-				logger.Error("scan foreign key: %v", err)
+				failed.add(err)
 			}
 		}
+		failed.warn(&s, "", "foreign keys", fkr)
 	} else {
-		logger.Error("query foreign key: %v", err)
+		warn(&s, "", "foreign keys", err)
 	}
 
 	return s, nil
 }
 
+// extractColumns reads the columns of all tables in one query and attaches
+// them to the tables in s.
+func (e myExtractor) extractColumns(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT table_schema, table_name, column_name, column_type, is_nullable = 'YES',
+               column_default,
+               extra LIKE '%auto_increment%',
+               nullif(generation_expression, ''),
+               character_maximum_length,
+               CASE WHEN data_type IN ('decimal', 'numeric') THEN numeric_precision END,
+               CASE WHEN data_type IN ('decimal', 'numeric') THEN numeric_scale END,
+               collation_name,
+               `+sectionSQL(e.opts, db.SectionComments, `nullif(column_comment, '')`, `NULL`)+`
+        FROM information_schema.columns
+        WHERE table_schema NOT IN ('mysql','information_schema','performance_schema','sys')`+e.opts.Filter.SQL("mysql", "table_schema", "table_name")+`
+        ORDER BY table_schema, table_name, ordinal_position`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table string
+		var col introspect.Column
+		if err := rows.Scan(&schema, &table, &col.Name, &col.Type, &col.Nullable, &col.Default, &col.Identity, &col.Generated,
+			&col.MaxLength, &col.Precision, &col.Scale, &col.Collation, &col.Comment); err != nil {
+			return fmt.Errorf("scan column: %w", err)
+		}
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			t.Columns = append(t.Columns, col)
+		}
+	}
+	return rows.Err()
+}
+
+// extractPrimaryKeys reads the primary key columns of all tables in one
+// query and flags them in s.
+func (e myExtractor) extractPrimaryKeys(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT k.table_schema, k.table_name, k.column_name
+        FROM information_schema.key_column_usage k
+        JOIN information_schema.table_constraints tc
+          ON k.constraint_name = tc.constraint_name
+         AND k.table_schema = tc.table_schema
+         AND k.table_name = tc.table_name
+        WHERE tc.constraint_type = 'PRIMARY KEY'
+          AND k.table_schema NOT IN ('mysql','information_schema','performance_schema','sys')`+e.opts.Filter.SQL("mysql", "k.table_schema", "k.table_name"))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table, pkcol string
+		if err := rows.Scan(&schema, &table, &pkcol); err != nil {
+			return fmt.Errorf("scan primary key: %w", err)
+		}
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			markPrimaryKey(t, pkcol)
+		}
+	}
+	return rows.Err()
+}
+
+// extractPartitions reads the partitioning method, the partition expression
+// and the partitions of all partitioned tables, summing up subpartitions.
+// MySQL keeps the partitions inside the table, so the table list is already
+// complete.
+func (e myExtractor) extractPartitions(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionPartitions) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT table_schema, table_name, partition_method, partition_expression,
+               partition_name, partition_ordinal_position, coalesce(partition_description, ''),
+               coalesce(sum(table_rows), 0), round(sum(data_length)/8192)
+        FROM information_schema.partitions
+        WHERE partition_name IS NOT NULL
+          AND table_schema NOT IN ('mysql','information_schema','performance_schema','sys')`+e.opts.Filter.SQL("mysql", "table_schema", "table_name")+`
+        GROUP BY table_schema, table_name, partition_method, partition_expression,
+                 partition_name, partition_ordinal_position, partition_description
+        ORDER BY table_schema, table_name, partition_ordinal_position`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table, method, description string
+		var expr sql.NullString
+		var p introspect.Partition
+		if err := rows.Scan(&schema, &table, &method, &expr, &p.Name, &p.Number, &description, &p.Rows, &p.Size8kPages); err != nil {
+			return fmt.Errorf("scan partition: %w", err)
+		}
+		t, ok := tables[tableKey(schema, table)]
+		if !ok {
+			continue
+		}
+		p.Schema = schema
+		switch {
+		case strings.HasPrefix(method, "RANGE"):
+			p.Bound = "VALUES LESS THAN (" + description + ")"
+		case strings.HasPrefix(method, "LIST"):
+			p.Bound = "VALUES IN (" + description + ")"
+		}
+		addPartition(t, method, p)
+		if t.Partitioning.Columns == nil {
+			for _, col := range splitList(expr.String) {
+				t.Partitioning.Columns = append(t.Partitioning.Columns, strings.Trim(col, "`"))
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// extractIndexes reads all indexes from information_schema.statistics
+// and attaches them to the tables in s.
+func (e myExtractor) extractIndexes(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionIndexes) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT table_schema, table_name, index_name, non_unique = 0, index_name = 'PRIMARY',
+               lower(index_type), column_name
+        FROM information_schema.statistics
+        WHERE table_schema NOT IN ('mysql','information_schema','performance_schema','sys')`+e.opts.Filter.SQL("mysql", "table_schema", "table_name")+`
+        ORDER BY table_schema, table_name, index_name, seq_in_index`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table string
+		var col sql.NullString
+		var idx introspect.Index
+		if err := rows.Scan(&schema, &table, &idx.Name, &idx.Unique, &idx.Primary, &idx.Method, &col); err != nil {
+			return fmt.Errorf("scan index: %w", err)
+		}
+		if !col.Valid {
+			// functional key part (MySQL 8.0.13+), the expression is not exposed here
+			col.String = "(expression)"
+		}
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			addIndexColumn(t, idx, col.String, false)
+		}
+	}
+	return rows.Err()
+}
+
+// extractConstraints reads unique and check constraints and attaches them
+// to the tables in s. Check constraints need MySQL 8.0.16 or MariaDB 10.2.
+func (e myExtractor) extractConstraints(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionConstraints) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT tc.table_schema, tc.table_name, tc.constraint_name, tc.constraint_type,
+               cc.check_clause, kcu.column_name
+        FROM information_schema.table_constraints tc
+        LEFT JOIN information_schema.key_column_usage kcu
+          ON kcu.constraint_schema = tc.constraint_schema
+         AND kcu.constraint_name = tc.constraint_name
+         AND kcu.table_name = tc.table_name
+        LEFT JOIN information_schema.check_constraints cc
+          ON cc.constraint_schema = tc.constraint_schema
+         AND cc.constraint_name = tc.constraint_name
+        WHERE tc.constraint_type IN ('UNIQUE', 'CHECK')
+          AND tc.table_schema NOT IN ('mysql','information_schema','performance_schema','sys')`+e.opts.Filter.SQL("mysql", "tc.table_schema", "tc.table_name")+`
+        ORDER BY tc.table_schema, tc.table_name, tc.constraint_name, kcu.ordinal_position`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table string
+		var col, expr sql.NullString
+		var c introspect.Constraint
+		if err := rows.Scan(&schema, &table, &c.Name, &c.Type, &expr, &col); err != nil {
+			return fmt.Errorf("scan constraint: %w", err)
+		}
+		c.Expression = expr.String
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			addConstraintColumn(t, c, col.String)
+		}
+	}
+	return rows.Err()
+}
+
+// extractViews reads views with their columns and, on MySQL 8.0.13 or
+// later, the tables and views they use.
+func (e myExtractor) extractViews(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionViews) {
+		return nil
+	}
+	vr, err := dbConn.QueryContext(ctx, `
+        SELECT table_schema, table_name, view_definition
+        FROM information_schema.views
+        WHERE table_schema NOT IN ('mysql','information_schema','performance_schema','sys')`+e.opts.Filter.SQL("mysql", "table_schema", "table_name")+`
+        ORDER BY table_schema, table_name`)
+	if err != nil {
+		return err
+	}
+	defer vr.Close()
+	for vr.Next() {
+		var v introspect.View
+		var def sql.NullString
+		if err := vr.Scan(&v.Schema, &v.Name, &def); err != nil {
+			return fmt.Errorf("scan view: %w", err)
+		}
+		v.Definition = def.String
+		s.Views = append(s.Views, v)
+	}
+	if err := vr.Err(); err != nil {
+		return err
+	}
+
+	cr, err := dbConn.QueryContext(ctx, `
+        SELECT c.table_schema, c.table_name, c.column_name, c.column_type, c.is_nullable = 'YES',
+               `+sectionSQL(e.opts, db.SectionComments, `nullif(c.column_comment, '')`, `NULL`)+`
+        FROM information_schema.columns c
+        JOIN information_schema.views v
+          ON v.table_schema = c.table_schema
+         AND v.table_name = c.table_name
+        WHERE c.table_schema NOT IN ('mysql','information_schema','performance_schema','sys')`+e.opts.Filter.SQL("mysql", "c.table_schema", "c.table_name")+`
+        ORDER BY c.table_schema, c.table_name, c.ordinal_position`)
+	if err != nil {
+		return fmt.Errorf("query view columns: %w", err)
+	}
+	defer cr.Close()
+	if err := scanViewColumns(cr, s); err != nil {
+		return err
+	}
+
+	dr, err := dbConn.QueryContext(ctx, `
+        SELECT view_schema, view_name, table_schema, table_name
+        FROM information_schema.view_table_usage
+        WHERE view_schema NOT IN ('mysql','information_schema','performance_schema','sys')`+e.opts.Filter.SQL("mysql", "view_schema", "view_name")+`
+        ORDER BY view_schema, view_name, table_schema, table_name`)
+	if err != nil {
+		return fmt.Errorf("query view dependencies: %w", err)
+	}
+	defer dr.Close()
+	return scanViewDependencies(dr, s)
+}
+
+// extractTypes reports the values of ENUM and SET columns as inline types
+// named table.column, MySQL has no named user-defined types.
+func (e myExtractor) extractTypes(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionTypes) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT table_schema, table_name, column_name, data_type, column_type
+        FROM information_schema.columns
+        WHERE data_type IN ('enum', 'set')
+          AND table_schema NOT IN ('mysql','information_schema','performance_schema','sys')`+e.opts.Filter.SQL("mysql", "table_schema", "")+`
+        ORDER BY table_schema, table_name, ordinal_position`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var schema, table, column, kind, ctype string
+		if err := rows.Scan(&schema, &table, &column, &kind, &ctype); err != nil {
+			return fmt.Errorf("scan enum column: %w", err)
+		}
+		s.Types = append(s.Types, introspect.UserType{
+			Schema: schema,
+			Name:   table + "." + column,
+			Kind:   strings.ToLower(kind),
+			Labels: mysqlEnumLabels(ctype),
+		})
+	}
+	return rows.Err()
+}
+
+// mysqlEnumLabels returns the values of a column type such as
+// enum('a','b”c') or set('x','y').
+func mysqlEnumLabels(ctype string) []string {
+	var labels []string
+	open := strings.IndexByte(ctype, '(')
+	if open < 0 {
+		return nil
+	}
+	var cur strings.Builder
+	inQuote := false
+	for i := open + 1; i < len(ctype); i++ {
+		c := ctype[i]
+		switch {
+		case inQuote && c == '\'' && i+1 < len(ctype) && ctype[i+1] == '\'':
+			cur.WriteByte(c)
+			i++
+		case inQuote && c == '\\' && i+1 < len(ctype):
+			i++
+			cur.WriteByte(ctype[i])
+		case c == '\'':
+			if inQuote {
+				labels = append(labels, cur.String())
+				cur.Reset()
+			}
+			inQuote = !inQuote
+		case inQuote:
+			cur.WriteByte(c)
+		}
+	}
+	return labels
+}
+
+// extractTriggers reads all triggers. MySQL triggers always fire for each row
+// and handle a single event.
+func (e myExtractor) extractTriggers(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionTriggers) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT trigger_schema, trigger_name, event_object_schema, event_object_table,
+               action_timing, event_manipulation, action_orientation, action_statement
+        FROM information_schema.triggers
+        WHERE trigger_schema NOT IN ('mysql','information_schema','performance_schema','sys')`+e.opts.Filter.SQL("mysql", "event_object_schema", "event_object_table")+`
+        ORDER BY event_object_schema, event_object_table, trigger_name`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tr introspect.Trigger
+		var events string
+		if err := rows.Scan(&tr.Schema, &tr.Name, &tr.Table.Schema, &tr.Table.Name,
+			&tr.Timing, &events, &tr.Level, &tr.Definition); err != nil {
+			return fmt.Errorf("scan trigger: %w", err)
+		}
+		tr.Events = splitEvents(events)
+		s.Triggers = append(s.Triggers, tr)
+	}
+	return rows.Err()
+}
+
+// extractRoutines reads stored functions and procedures with their
+// parameters. MySQL does not record which tables a routine uses.
+func (e myExtractor) extractRoutines(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionRoutines) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT r.routine_schema, r.routine_name, r.routine_type,
+               coalesce((SELECT group_concat(concat_ws(' ', p.parameter_mode, p.parameter_name, p.dtd_identifier)
+                                             ORDER BY p.ordinal_position SEPARATOR ', ')
+                         FROM information_schema.parameters p
+                         WHERE p.specific_schema = r.routine_schema
+                           AND p.specific_name = r.specific_name
+                           AND p.ordinal_position > 0), '') AS arguments,
+               coalesce(r.dtd_identifier, '') AS return_type,
+               r.routine_body,
+               coalesce(r.routine_definition, '')
+        FROM information_schema.routines r
+        WHERE r.routine_schema NOT IN ('mysql','information_schema','performance_schema','sys')`+e.opts.Filter.SQL("mysql", "r.routine_schema", "")+`
+        ORDER BY r.routine_schema, r.routine_name`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r introspect.Routine
+		if err := rows.Scan(&r.Schema, &r.Name, &r.Kind, &r.Arguments, &r.ReturnType, &r.Language, &r.Definition); err != nil {
+			return fmt.Errorf("scan routine: %w", err)
+		}
+		s.Routines = append(s.Routines, r)
+	}
+	return rows.Err()
+}
+
+// SchemaVersion combines the number and the newest create and update times
+// of the tables with the number of routines and triggers. These come from
+// the data dictionary without reading every column and index definition,
+// which would cost about as much as the extraction. ALTER TABLE changes the
+// create time when it rebuilds the table; changes that do not, such as new
+// comments, are picked up once the cached schema reaches cache.max_age.
+func (myExtractor) SchemaVersion(ctx context.Context, dbConn *sql.DB) (string, error) {
+	var version string
+	err := dbConn.QueryRowContext(ctx, `
+        SELECT CONCAT_WS('/',
+          (SELECT CONCAT(COUNT(*), ':', COALESCE(MAX(create_time), ''), ':', COALESCE(MAX(update_time), ''))
+           FROM information_schema.tables
+           WHERE table_schema NOT IN ('mysql','information_schema','performance_schema','sys')),
+          (SELECT CONCAT(COUNT(*), ':', COALESCE(MAX(last_altered), ''))
+           FROM information_schema.routines
+           WHERE routine_schema NOT IN ('mysql','information_schema','performance_schema','sys')),
+          (SELECT COUNT(*)
+           FROM information_schema.triggers
+           WHERE trigger_schema NOT IN ('mysql','information_schema','performance_schema','sys')))`).Scan(&version)
+	return version, err
+}
+
+// Capabilities reports the sections the extractor can return.
+func (myExtractor) Capabilities() db.Capabilities {
+	return db.Capabilities{Sections: db.AllSections &^ db.SectionSequences, Filter: true}
+}
+
 func init() {
-	db.Register("mysql", myExtractor{})
-	db.Register("mariadb", myExtractor{})
+	db.RegisterOptionsExtractor("mysql", myExtractor{})
+	db.RegisterOptionsExtractor("mariadb", myExtractor{})
 }
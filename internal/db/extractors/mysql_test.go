@@ -0,0 +1,27 @@
+package extractors
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMySQLEnumLabels(t *testing.T) {
+	var tests = []struct {
+		ctype  string
+		labels []string
+	}{
+		{"enum('new','paid','shipped')", []string{"new", "paid", "shipped"}},
+		{"set('a,b','it''s','back\\\\slash')", []string{"a,b", "it's", "back\\slash"}},
+		{"enum('')", []string{""}},
+		{"varchar(20)", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ctype, func(t *testing.T) {
+			labels := mysqlEnumLabels(tt.ctype)
+			if !reflect.DeepEqual(labels, tt.labels) {
+				t.Errorf("\ngot labels %q, wanted %q", labels, tt.labels)
+			}
+		})
+	}
+}
@@ -7,34 +7,42 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"erddiagram/internal/db"
 	"erddiagram/internal/introspect"
-	"erddiagram/internal/logger"
 )
 
-// oracleExtractor implements Extractor for Oracle.
-type oracleExtractor struct{}
+// oracleExtractor implements OptionsExtractor for Oracle.
+type oracleExtractor struct {
+	opts db.Options
+}
 
 // This is the extractor for Oracle
-func (oracleExtractor) Extract(ctx context.Context, dbConn *sql.DB) (introspect.Schema, error) {
+func (e oracleExtractor) ExtractWithOptions(ctx context.Context, dbConn *sql.DB, opts db.Options) (introspect.Schema, error) {
+	e.opts = opts
 	var s introspect.Schema
 
 	tr, err := dbConn.QueryContext(ctx, `
 	    SELECT 
 		   ausr.username, 
 		   atab.table_name, 
-		   acom.comments, 
-		   nvl(atab.blocks*nvl(ts.block_size, 8192)/8192, 1) size_8k_pages
+		   `+sectionSQL(e.opts, db.SectionComments, `acom.comments`, `NULL`)+` comments, 
+		   `+sectionSQL(e.opts, db.SectionSizes, `nvl(atab.blocks*nvl(ts.block_size, 8192)/8192, 1)`, `0`)+` size_8k_pages,
+		   nvl(atab.num_rows, 0) row_estimate
 	    FROM all_users ausr
 	    JOIN all_tables atab 
-		  ON ausr.username = atab.owner
+		  ON ausr.username = atab.owner`+sectionSQL(e.opts, db.SectionComments, `
 	    LEFT JOIN all_tab_comments acom 
 		  ON acom.owner = atab.owner 
-		 AND acom.table_name = atab.table_name
+		 AND acom.table_name = atab.table_name`, ``)+sectionSQL(e.opts, db.SectionSizes, `
 	    LEFT JOIN user_tablespaces ts 
-		  ON atab.tablespace_name = ts.tablespace_name
+		  ON atab.tablespace_name = ts.tablespace_name`, ``)+`
 	    WHERE ausr.oracle_maintained = 'N'
+	      AND NOT EXISTS (SELECT 1
+	                      FROM all_mviews amv
+	                      WHERE amv.owner = atab.owner
+	                        AND amv.mview_name = atab.table_name)`+e.opts.Filter.SQL("oracle", "atab.owner", "atab.table_name")+`
 	    ORDER BY ausr.username, atab.table_name`)
 	if err != nil {
 		return s, fmt.Errorf("query tables: %w", err)
@@ -43,64 +51,61 @@ func (oracleExtractor) Extract(ctx context.Context, dbConn *sql.DB) (introspect.
 
 	for tr.Next() {
 		var tab introspect.Table
-		if err := tr.Scan(&tab.Schema, &tab.Name, &tab.Comment, &tab.Size8kPages); err != nil {
+		if err := tr.Scan(&tab.Schema, &tab.Name, &tab.Comment, &tab.Size8kPages, &tab.Rows); err != nil {
 			return s, fmt.Errorf("scan table row: %w", err)
 		}
 		s.Tables = append(s.Tables, tab)
 	}
 
-	for i := range s.Tables {
-		t := &s.Tables[i]
-		cr, err := dbConn.QueryContext(ctx, `
-            SELECT column_name, data_type, nullable
-            FROM all_tab_columns
-            WHERE owner = :1 AND table_name = :2
-            ORDER BY column_id`, t.Schema, t.Name)
-		if err != nil {
-			return s, fmt.Errorf("query columns for %s.%s: %w", t.Schema, t.Name, err)
-		}
-		for cr.Next() {
-			var col introspect.Column
-			var nullable string
-			if err := cr.Scan(&col.Name, &col.Type, &nullable); err != nil {
-				cr.Close()
-				return s, fmt.Errorf("scan column for %s.%s: %w", t.Schema, t.Name, err)
-			}
-			col.Nullable = (nullable == "Y")
-			t.Columns = append(t.Columns, col)
-		}
-		cr.Close()
-
-		pkr, err := dbConn.QueryContext(ctx, `
-            SELECT acc.column_name
-            FROM all_cons_columns acc
-            JOIN all_constraints ac ON acc.owner = ac.owner AND acc.constraint_name = ac.constraint_name
-            WHERE ac.constraint_type = 'P' AND acc.owner = :1 AND acc.table_name = :2`, t.Schema, t.Name)
-		if err == nil {
-			for pkr.Next() {
-				var pkcol string
-				if err := pkr.Scan(&pkcol); err == nil {
-					for j := range t.Columns {
-						if t.Columns[j].Name == pkcol {
-							t.Columns[j].PK = true
-						}
-					}
-				} else {
-					logger.Error("scan primary key: %v", err)
-				}
-			}
-			pkr.Close()
-		} else {
-			logger.Error("query primary key: %v", err)
-		}
+	if err := e.extractPartitions(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "partitions", err)
+	}
+
+	if err := e.extractColumns(ctx, dbConn, &s); err != nil {
+		return s, fmt.Errorf("query columns: %w", err)
+	}
+
+	if err := e.extractPrimaryKeys(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "primary keys", err)
+	}
+
+	if err := e.extractIndexes(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "indexes", err)
+	}
+
+	if err := e.extractConstraints(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "constraints", err)
+	}
+
+	if err := e.extractViews(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "views", err)
+	}
+
+	if err := e.extractTypes(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "types", err)
+	}
+
+	if err := e.extractSequences(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "sequences", err)
+	}
+
+	if err := e.extractTriggers(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "triggers", err)
+	}
+
+	if err := e.extractRoutines(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "routines", err)
 	}
 
 	fkr, err := dbConn.QueryContext(ctx, `
-        SELECT a.owner AS from_schema, a.table_name AS from_table, 
-		       listagg(acc.column_name, ', ') within group (order by acc.position) AS from_column,
-               rcc.owner AS to_schema, rcc.table_name AS to_table, 
-			   listagg(rcc.column_name, ', ') within group (order by rcc.position) AS to_column,
-			   a.constraint_name
+        SELECT a.owner AS from_schema, a.table_name AS from_table, acc.column_name AS from_column,
+               rcc.owner AS to_schema, rcc.table_name AS to_table, rcc.column_name AS to_column,
+			   a.constraint_name,
+			   a.delete_rule,
+			   CASE WHEN a.deferrable = 'DEFERRABLE' THEN 1 ELSE 0 END AS is_deferrable,
+			   CASE WHEN a.deferred = 'DEFERRED' THEN 1 ELSE 0 END AS is_deferred,
+			   CASE WHEN a.status = 'DISABLED' THEN 1 ELSE 0 END AS is_disabled,
+			   CASE WHEN a.validated = 'NOT VALIDATED' THEN 1 ELSE 0 END AS not_validated
         FROM all_users ausr
 		JOIN all_constraints a
 		  ON ausr.username = a.owner
@@ -112,26 +117,550 @@ func (oracleExtractor) Extract(ctx context.Context, dbConn *sql.DB) (introspect.
 		 AND a.r_constraint_name = rcc.constraint_name
 		 AND nvl(acc.position, 0) = nvl(rcc.position, 0)
         WHERE a.constraint_type = 'R' 
-		  AND ausr.oracle_maintained = 'N'
-		GROUP BY a.owner, a.table_name, rcc.owner, rcc.table_name, a.constraint_name`)
+		  AND ausr.oracle_maintained = 'N'`+e.opts.Filter.SQL("oracle", "a.owner", "a.table_name")+`
+		ORDER BY a.owner, a.table_name, a.constraint_name, acc.position`)
 	if err == nil {
 		defer fkr.Close()
+		var failed rowErrors
 		for fkr.Next() {
 			var fk introspect.ForeignKey
-			if err := fkr.Scan(&fk.FromSchema, &fk.FromTable, &fk.FromColumn, &fk.ToSchema, &fk.ToTable, &fk.ToColumn, &fk.Constraint); err == nil {
-				s.ForeignKeys = append(s.ForeignKeys, fk)
+			var from, to string
+			var deferrable, deferred, disabled, notValidated int
+			if err := fkr.Scan(&fk.FromSchema, &fk.FromTable, &from, &fk.ToSchema, &fk.ToTable, &to, &fk.Constraint,
+				&fk.OnDelete, &deferrable, &deferred, &disabled, &notValidated); err == nil {
+				// Oracle has no ON UPDATE actions
+				fk.Deferrable = deferrable == 1
+				fk.InitiallyDeferred = deferred == 1
+				fk.Disabled = disabled == 1
+				fk.NotValidated = notValidated == 1
+				addForeignKeyColumn(&s, fk, from, to)
 			} else {
-				logger.Error("scan foreign key: %v", err)
+				failed.add(err)
 			}
 		}
+		failed.warn(&s, "", "foreign keys", fkr)
 	} else {
-		logger.Error("query foreign key: %v", err)
+		warn(&s, "", "foreign keys", err)
 	}
 
 	return s, nil
 }
 
+// extractColumns reads the columns of all tables in one query and attaches
+// them to the tables in s.
+func (e oracleExtractor) extractColumns(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT atc.owner, atc.table_name,
+               atc.column_name, atc.data_type, atc.nullable,
+               atc.data_default, atc.identity_column, atc.virtual_column,
+               CASE WHEN atc.char_length > 0 THEN atc.char_length END,
+               atc.data_precision, atc.data_scale, atc.collation,
+               `+sectionSQL(e.opts, db.SectionComments, `acc.comments`, `NULL`)+`
+        FROM all_tab_cols atc
+        JOIN all_users ausr
+          ON ausr.username = atc.owner`+sectionSQL(e.opts, db.SectionComments, `
+        LEFT JOIN all_col_comments acc
+          ON acc.owner = atc.owner
+         AND acc.table_name = atc.table_name
+         AND acc.column_name = atc.column_name`, ``)+`
+        WHERE ausr.oracle_maintained = 'N' AND atc.hidden_column = 'NO'`+e.opts.Filter.SQL("oracle", "atc.owner", "atc.table_name")+`
+        ORDER BY atc.owner, atc.table_name, atc.column_id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table string
+		var col introspect.Column
+		var nullable, identity, virtual string
+		if err := rows.Scan(&schema, &table, &col.Name, &col.Type, &nullable, &col.Default, &identity, &virtual,
+			&col.MaxLength, &col.Precision, &col.Scale, &col.Collation, &col.Comment); err != nil {
+			return fmt.Errorf("scan column: %w", err)
+		}
+		if col.Default != nil {
+			// DATA_DEFAULT is a LONG holding the text as written, often
+			// with a trailing newline
+			*col.Default = strings.TrimSpace(*col.Default)
+		}
+		col.Nullable = (nullable == "Y")
+		col.Identity = (identity == "YES")
+		if virtual == "YES" {
+			// the expression of a virtual column is stored as its default
+			col.Generated, col.Default = col.Default, nil
+		} else if col.Identity {
+			// the default of an identity column is the internal sequence
+			col.Default = nil
+		}
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			t.Columns = append(t.Columns, col)
+		}
+	}
+	return rows.Err()
+}
+
+// extractPrimaryKeys reads the primary key columns of all tables in one
+// query and flags them in s.
+func (e oracleExtractor) extractPrimaryKeys(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT acc.owner, acc.table_name, acc.column_name
+        FROM all_cons_columns acc
+        JOIN all_constraints ac ON acc.owner = ac.owner AND acc.constraint_name = ac.constraint_name
+        JOIN all_users ausr ON ausr.username = acc.owner
+        WHERE ac.constraint_type = 'P' AND ausr.oracle_maintained = 'N'`+e.opts.Filter.SQL("oracle", "acc.owner", "acc.table_name"))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table, pkcol string
+		if err := rows.Scan(&schema, &table, &pkcol); err != nil {
+			return fmt.Errorf("scan primary key: %w", err)
+		}
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			markPrimaryKey(t, pkcol)
+		}
+	}
+	return rows.Err()
+}
+
+// extractPartitions reads the partitioning type, the partition key columns
+// and the partitions of all partitioned tables. The partition bounds are
+// stored in a LONG column and are not read.
+func (e oracleExtractor) extractPartitions(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionPartitions) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT apt.owner, apt.table_name, apt.partitioning_type,
+               (SELECT LISTAGG(apkc.column_name, ',') WITHIN GROUP (ORDER BY apkc.column_position)
+                FROM all_part_key_columns apkc
+                WHERE apkc.owner = apt.owner
+                  AND apkc.name = apt.table_name
+                  AND apkc.object_type = 'TABLE') AS key_columns,
+               atp.partition_name, atp.partition_position,
+               nvl(atp.num_rows, 0) row_estimate,
+               nvl(atp.blocks*nvl(ts.block_size, 8192)/8192, 0) size_8k_pages
+        FROM all_part_tables apt
+        JOIN all_users ausr
+          ON ausr.username = apt.owner
+        JOIN all_tab_partitions atp
+          ON atp.table_owner = apt.owner
+         AND atp.table_name = apt.table_name
+        LEFT JOIN user_tablespaces ts
+          ON atp.tablespace_name = ts.tablespace_name
+        WHERE ausr.oracle_maintained = 'N'`+e.opts.Filter.SQL("oracle", "apt.owner", "apt.table_name")+`
+        ORDER BY apt.owner, apt.table_name, atp.partition_position`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table, strategy string
+		var keys sql.NullString
+		var p introspect.Partition
+		if err := rows.Scan(&schema, &table, &strategy, &keys, &p.Name, &p.Number, &p.Rows, &p.Size8kPages); err != nil {
+			return fmt.Errorf("scan partition: %w", err)
+		}
+		t, ok := tables[tableKey(schema, table)]
+		if !ok {
+			continue
+		}
+		p.Schema = schema
+		addPartition(t, strategy, p)
+		if keys.Valid {
+			t.Partitioning.Columns = strings.Split(keys.String, ",")
+		}
+	}
+	return rows.Err()
+}
+
+// extractIndexes reads all indexes of non-maintained users and attaches
+// them to the tables in s.
+func (e oracleExtractor) extractIndexes(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionIndexes) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT
+          ai.table_owner,
+          ai.table_name,
+          ai.index_name,
+          CASE WHEN ai.uniqueness = 'UNIQUE' THEN 1 ELSE 0 END AS is_unique,
+          CASE WHEN ac.constraint_name IS NOT NULL THEN 1 ELSE 0 END AS is_primary,
+          lower(ai.index_type) AS method,
+          aic.column_name
+        FROM all_users ausr
+        JOIN all_indexes ai
+          ON ausr.username = ai.table_owner
+        JOIN all_ind_columns aic
+          ON aic.index_owner = ai.owner
+         AND aic.index_name = ai.index_name
+        LEFT JOIN all_constraints ac
+          ON ac.owner = ai.table_owner
+         AND ac.table_name = ai.table_name
+         AND ac.index_name = ai.index_name
+         AND ac.constraint_type = 'P'
+        WHERE ausr.oracle_maintained = 'N'`+e.opts.Filter.SQL("oracle", "ai.table_owner", "ai.table_name")+`
+          AND ai.index_type <> 'LOB'
+        ORDER BY ai.table_owner, ai.table_name, ai.index_name, aic.column_position`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table, col string
+		var unique, primary int
+		var idx introspect.Index
+		if err := rows.Scan(&schema, &table, &idx.Name, &unique, &primary, &idx.Method, &col); err != nil {
+			return fmt.Errorf("scan index: %w", err)
+		}
+		idx.Unique = unique == 1
+		idx.Primary = primary == 1
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			addIndexColumn(t, idx, col, false)
+		}
+	}
+	return rows.Err()
+}
+
+// extractConstraints reads unique and check constraints and attaches them
+// to the tables in s. The NOT NULL checks Oracle generates for every
+// mandatory column are skipped. search_condition_vc needs Oracle 12.2.
+func (e oracleExtractor) extractConstraints(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionConstraints) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT
+          ac.owner,
+          ac.table_name,
+          ac.constraint_name,
+          CASE ac.constraint_type WHEN 'U' THEN 'UNIQUE' ELSE 'CHECK' END AS constraint_type,
+          ac.search_condition_vc,
+          acc.column_name
+        FROM all_users ausr
+        JOIN all_constraints ac
+          ON ausr.username = ac.owner
+        LEFT JOIN all_cons_columns acc
+          ON acc.owner = ac.owner
+         AND acc.constraint_name = ac.constraint_name
+         AND acc.table_name = ac.table_name
+        WHERE ausr.oracle_maintained = 'N'`+e.opts.Filter.SQL("oracle", "ac.owner", "ac.table_name")+`
+          AND ac.constraint_type IN ('U', 'C')
+          AND NOT (ac.constraint_type = 'C'
+                   AND ac.generated = 'GENERATED NAME'
+                   AND ac.search_condition_vc LIKE '% IS NOT NULL')
+        ORDER BY ac.owner, ac.table_name, ac.constraint_name, acc.position`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table string
+		var col, expr sql.NullString
+		var c introspect.Constraint
+		if err := rows.Scan(&schema, &table, &c.Name, &c.Type, &expr, &col); err != nil {
+			return fmt.Errorf("scan constraint: %w", err)
+		}
+		c.Expression = expr.String
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			addConstraintColumn(t, c, col.String)
+		}
+	}
+	return rows.Err()
+}
+
+// extractViews reads views and materialized views with their columns and
+// the tables and views they depend on. The query of a materialized view is
+// a LONG column and is not read.
+func (e oracleExtractor) extractViews(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionViews) {
+		return nil
+	}
+	vr, err := dbConn.QueryContext(ctx, `
+        SELECT v.owner, v.view_name, v.materialized, v.text_vc, `+sectionSQL(e.opts, db.SectionComments, `acom.comments`, `NULL`)+`
+        FROM (SELECT owner, view_name, 0 AS materialized, text_vc
+              FROM all_views
+              UNION ALL
+              SELECT owner, mview_name, 1, NULL
+              FROM all_mviews) v
+        JOIN all_users ausr
+          ON ausr.username = v.owner`+sectionSQL(e.opts, db.SectionComments, `
+        LEFT JOIN all_tab_comments acom
+          ON acom.owner = v.owner
+         AND acom.table_name = v.view_name`, ``)+`
+        WHERE ausr.oracle_maintained = 'N'`+e.opts.Filter.SQL("oracle", "v.owner", "v.view_name")+`
+        ORDER BY v.owner, v.view_name`)
+	if err != nil {
+		return err
+	}
+	defer vr.Close()
+	for vr.Next() {
+		var v introspect.View
+		var materialized int
+		var def sql.NullString
+		if err := vr.Scan(&v.Schema, &v.Name, &materialized, &def, &v.Comment); err != nil {
+			return fmt.Errorf("scan view: %w", err)
+		}
+		v.Materialized = materialized == 1
+		v.Definition = def.String
+		s.Views = append(s.Views, v)
+	}
+	if err := vr.Err(); err != nil {
+		return err
+	}
+
+	cr, err := dbConn.QueryContext(ctx, `
+        SELECT atc.owner, atc.table_name, atc.column_name, atc.data_type,
+               CASE WHEN atc.nullable = 'Y' THEN 1 ELSE 0 END, `+sectionSQL(e.opts, db.SectionComments, `acc.comments`, `NULL`)+`
+        FROM all_tab_columns atc
+        JOIN all_objects ao
+          ON ao.owner = atc.owner
+         AND ao.object_name = atc.table_name
+         AND ao.object_type IN ('VIEW', 'MATERIALIZED VIEW')
+        JOIN all_users ausr
+          ON ausr.username = atc.owner`+sectionSQL(e.opts, db.SectionComments, `
+        LEFT JOIN all_col_comments acc
+          ON acc.owner = atc.owner
+         AND acc.table_name = atc.table_name
+         AND acc.column_name = atc.column_name`, ``)+`
+        WHERE ausr.oracle_maintained = 'N'`+e.opts.Filter.SQL("oracle", "atc.owner", "atc.table_name")+`
+        ORDER BY atc.owner, atc.table_name, atc.column_id`)
+	if err != nil {
+		return fmt.Errorf("query view columns: %w", err)
+	}
+	defer cr.Close()
+	if err := scanViewColumns(cr, s); err != nil {
+		return err
+	}
+
+	dr, err := dbConn.QueryContext(ctx, `
+        SELECT DISTINCT ad.owner, ad.name, ad.referenced_owner, ad.referenced_name
+        FROM all_dependencies ad
+        JOIN all_users ausr
+          ON ausr.username = ad.owner
+        WHERE ausr.oracle_maintained = 'N'`+e.opts.Filter.SQL("oracle", "ad.owner", "ad.name")+`
+          AND ad.type IN ('VIEW', 'MATERIALIZED VIEW')
+          AND ad.referenced_type IN ('TABLE', 'VIEW', 'MATERIALIZED VIEW')
+          AND NOT (ad.referenced_owner = ad.owner AND ad.referenced_name = ad.name)
+        ORDER BY ad.owner, ad.name, ad.referenced_owner, ad.referenced_name`)
+	if err != nil {
+		return fmt.Errorf("query view dependencies: %w", err)
+	}
+	defer dr.Close()
+	return scanViewDependencies(dr, s)
+}
+
+// extractTypes reads object types with their attributes as composite types.
+func (e oracleExtractor) extractTypes(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionTypes) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT ata.owner, ata.type_name, ata.attr_name, ata.attr_type_name
+        FROM all_type_attrs ata
+        JOIN all_users ausr
+          ON ausr.username = ata.owner
+        WHERE ausr.oracle_maintained = 'N'`+e.opts.Filter.SQL("oracle", "ata.owner", "")+`
+        ORDER BY ata.owner, ata.type_name, ata.attr_no`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var schema, name string
+		col := introspect.Column{Nullable: true}
+		if err := rows.Scan(&schema, &name, &col.Name, &col.Type); err != nil {
+			return fmt.Errorf("scan type attribute: %w", err)
+		}
+		ut := userType(s, schema, name, introspect.TypeComposite)
+		ut.Attributes = append(ut.Attributes, col)
+	}
+	return rows.Err()
+}
+
+// extractSequences reads all sequences together with the identity column
+// that owns them. Oracle does not expose the start value of a sequence.
+func (e oracleExtractor) extractSequences(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionSequences) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT asq.sequence_owner, asq.sequence_name, asq.increment_by,
+               CASE WHEN asq.cycle_flag = 'Y' THEN 1 ELSE 0 END AS cycle,
+               aic.owner, aic.table_name, aic.column_name
+        FROM all_sequences asq
+        JOIN all_users ausr
+          ON ausr.username = asq.sequence_owner
+        LEFT JOIN all_tab_identity_cols aic
+          ON aic.owner = asq.sequence_owner
+         AND aic.sequence_name = asq.sequence_name
+        WHERE ausr.oracle_maintained = 'N'`+e.opts.Filter.SQL("oracle", "asq.sequence_owner", "")+`
+        ORDER BY asq.sequence_owner, asq.sequence_name`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		seq := introspect.Sequence{DataType: "NUMBER"}
+		var ownerSchema, ownerTable, ownerColumn sql.NullString
+		if err := rows.Scan(&seq.Schema, &seq.Name, &seq.Increment, &seq.Cycle,
+			&ownerSchema, &ownerTable, &ownerColumn); err != nil {
+			return fmt.Errorf("scan sequence: %w", err)
+		}
+		if ownerColumn.Valid {
+			seq.OwnedBy = &introspect.ColumnRef{Schema: ownerSchema.String, Table: ownerTable.String, Column: ownerColumn.String}
+		}
+		s.Sequences = append(s.Sequences, seq)
+	}
+	return rows.Err()
+}
+
+// extractTriggers reads all triggers on tables and views. The trigger body is
+// a LONG column, so the short description is used as definition.
+func (e oracleExtractor) extractTriggers(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionTriggers) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT atr.owner, atr.trigger_name, atr.table_owner, atr.table_name,
+               CASE
+                 WHEN atr.trigger_type LIKE 'BEFORE%' THEN 'BEFORE'
+                 WHEN atr.trigger_type LIKE 'AFTER%' THEN 'AFTER'
+                 WHEN atr.trigger_type LIKE 'INSTEAD OF%' THEN 'INSTEAD OF'
+                 ELSE atr.trigger_type
+               END AS timing,
+               atr.triggering_event,
+               CASE
+                 WHEN atr.trigger_type LIKE '%EACH ROW' OR atr.trigger_type LIKE 'INSTEAD OF%' THEN 'ROW'
+                 WHEN atr.trigger_type LIKE '%STATEMENT' THEN 'STATEMENT'
+               END AS trigger_level,
+               CASE WHEN atr.status = 'DISABLED' THEN 1 ELSE 0 END AS disabled,
+               atr.description
+        FROM all_triggers atr
+        JOIN all_users ausr
+          ON ausr.username = atr.owner
+        WHERE ausr.oracle_maintained = 'N'`+e.opts.Filter.SQL("oracle", "atr.table_owner", "atr.table_name")+`
+          AND atr.base_object_type IN ('TABLE', 'VIEW')
+        ORDER BY atr.table_owner, atr.table_name, atr.trigger_name`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tr introspect.Trigger
+		var events, level, definition sql.NullString
+		if err := rows.Scan(&tr.Schema, &tr.Name, &tr.Table.Schema, &tr.Table.Name, &tr.Timing,
+			&events, &level, &tr.Disabled, &definition); err != nil {
+			return fmt.Errorf("scan trigger: %w", err)
+		}
+		tr.Events = splitEvents(events.String)
+		tr.Level = level.String
+		tr.Definition = definition.String
+		s.Triggers = append(s.Triggers, tr)
+	}
+	return rows.Err()
+}
+
+// extractRoutines reads standalone functions and procedures and packages,
+// with the tables and views they reference.
+func (e oracleExtractor) extractRoutines(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionRoutines) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT ao.object_id, ao.owner, ao.object_name, ao.object_type,
+               (SELECT LISTAGG(aa.argument_name || ' ' || aa.in_out || ' ' || aa.data_type, ', ')
+                         WITHIN GROUP (ORDER BY aa.position)
+                FROM all_arguments aa
+                WHERE aa.owner = ao.owner
+                  AND aa.object_name = ao.object_name
+                  AND aa.package_name IS NULL
+                  AND aa.data_level = 0
+                  AND aa.position > 0) AS arguments,
+               (SELECT MAX(aa.data_type)
+                FROM all_arguments aa
+                WHERE aa.owner = ao.owner
+                  AND aa.object_name = ao.object_name
+                  AND aa.package_name IS NULL
+                  AND aa.data_level = 0
+                  AND aa.position = 0) AS return_type
+        FROM all_objects ao
+        JOIN all_users ausr
+          ON ausr.username = ao.owner
+        WHERE ausr.oracle_maintained = 'N'`+e.opts.Filter.SQL("oracle", "ao.owner", "")+`
+          AND ao.object_type IN ('FUNCTION', 'PROCEDURE', 'PACKAGE')
+        ORDER BY ao.owner, ao.object_name`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	byID := map[int64]int{}
+	for rows.Next() {
+		var id int64
+		r := introspect.Routine{Language: "PL/SQL"}
+		var args, ret sql.NullString
+		if err := rows.Scan(&id, &r.Schema, &r.Name, &r.Kind, &args, &ret); err != nil {
+			return fmt.Errorf("scan routine: %w", err)
+		}
+		r.Arguments = args.String
+		r.ReturnType = ret.String
+		byID[id] = len(s.Routines)
+		s.Routines = append(s.Routines, r)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	dr, err := dbConn.QueryContext(ctx, `
+        SELECT DISTINCT ao.object_id, ad.referenced_owner, ad.referenced_name
+        FROM all_dependencies ad
+        JOIN all_objects ao
+          ON ao.owner = ad.owner
+         AND ao.object_name = ad.name
+         AND ao.object_type = CASE ad.type WHEN 'PACKAGE BODY' THEN 'PACKAGE' ELSE ad.type END
+        JOIN all_users ausr
+          ON ausr.username = ad.owner
+        WHERE ausr.oracle_maintained = 'N'`+e.opts.Filter.SQL("oracle", "ad.owner", "")+`
+          AND ad.type IN ('FUNCTION', 'PROCEDURE', 'PACKAGE', 'PACKAGE BODY')
+          AND ad.referenced_type IN ('TABLE', 'VIEW', 'MATERIALIZED VIEW')
+        ORDER BY ao.object_id, ad.referenced_owner, ad.referenced_name`)
+	if err != nil {
+		return fmt.Errorf("query routine dependencies: %w", err)
+	}
+	defer dr.Close()
+	return scanRoutineReferences(dr, s, byID)
+}
+
+// SchemaVersion combines the number of user objects with their newest
+// last_ddl_time, which DDL and COMMENT statements update.
+func (oracleExtractor) SchemaVersion(ctx context.Context, dbConn *sql.DB) (string, error) {
+	var version string
+	err := dbConn.QueryRowContext(ctx, `
+	    SELECT count(*) || '/' || to_char(max(ao.last_ddl_time), 'YYYYMMDDHH24MISS')
+	    FROM all_objects ao
+	    JOIN all_users ausr
+	      ON ausr.username = ao.owner
+	    WHERE ausr.oracle_maintained = 'N'`).Scan(&version)
+	return version, err
+}
+
+// Capabilities reports the sections the extractor can return.
+func (oracleExtractor) Capabilities() db.Capabilities {
+	return db.Capabilities{Sections: db.AllSections, Filter: true}
+}
+
 func init() {
-	db.Register("godror", oracleExtractor{})
-	db.Register("oracle", oracleExtractor{})
+	db.RegisterOptionsExtractor("godror", oracleExtractor{})
+	db.RegisterOptionsExtractor("oracle", oracleExtractor{})
 }
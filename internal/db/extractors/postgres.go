@@ -4,26 +4,32 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"erddiagram/internal/db"
 	"erddiagram/internal/introspect"
-	"erddiagram/internal/logger"
 )
 
-// pgExtractor implements Extractor using information_schema + pg_catalog queries.
-type pgExtractor struct{}
+// pgExtractor implements OptionsExtractor using information_schema + pg_catalog queries.
+type pgExtractor struct {
+	opts db.Options
+}
 
 // This is the extractor for PostgreSQL
-func (pgExtractor) Extract(ctx context.Context, dbConn *sql.DB) (introspect.Schema, error) {
+func (e pgExtractor) ExtractWithOptions(ctx context.Context, dbConn *sql.DB, opts db.Options) (introspect.Schema, error) {
+	e.opts = opts
 	var s introspect.Schema
 
 	tr, err := dbConn.QueryContext(ctx, `
         SELECT table_schema, table_name, 
-		       obj_description((quote_ident(table_schema)||'.'||quote_ident(table_name))::regclass) AS table_comment,
-			   pg_table_size(quote_ident(table_schema)||'.'||quote_ident(table_name))/8192 AS size_8k_pages
+		       `+sectionSQL(e.opts, db.SectionComments, `obj_description((quote_ident(table_schema)||'.'||quote_ident(table_name))::regclass)`, `NULL`)+` AS table_comment,
+			   `+sectionSQL(e.opts, db.SectionSizes, `pg_table_size(quote_ident(table_schema)||'.'||quote_ident(table_name))/8192`, `0`)+` AS size_8k_pages,
+               (SELECT greatest(c.reltuples, 0)::bigint
+                FROM pg_class c
+                WHERE c.oid = (quote_ident(table_schema)||'.'||quote_ident(table_name))::regclass) AS row_estimate
         FROM information_schema.tables
         WHERE table_type = 'BASE TABLE'
-          AND table_schema NOT IN ('pg_catalog','information_schema','pg_toast')
+          AND table_schema NOT IN ('pg_catalog','information_schema','pg_toast')`+e.opts.Filter.SQL("postgres", "table_schema", "table_name")+`
         ORDER BY table_schema, table_name`)
 	if err != nil {
 		return s, fmt.Errorf("query tables: %w", err)
@@ -32,98 +38,669 @@ func (pgExtractor) Extract(ctx context.Context, dbConn *sql.DB) (introspect.Sche
 
 	for tr.Next() {
 		var tab introspect.Table
-		if err := tr.Scan(&tab.Schema, &tab.Name, &tab.Comment, &tab.Size8kPages); err != nil {
+		if err := tr.Scan(&tab.Schema, &tab.Name, &tab.Comment, &tab.Size8kPages, &tab.Rows); err != nil {
 			return s, fmt.Errorf("scan table row: %w", err)
 		}
 		s.Tables = append(s.Tables, tab)
 	}
 
-	for i := range s.Tables {
-		t := &s.Tables[i]
-		cr, err := dbConn.QueryContext(ctx, `
-            SELECT column_name, data_type, is_nullable = 'YES'
-            FROM information_schema.columns
-            WHERE table_schema = $1 AND table_name = $2
-            ORDER BY ordinal_position`, t.Schema, t.Name)
-		if err != nil {
-			return s, fmt.Errorf("query columns for %s.%s: %w", t.Schema, t.Name, err)
-		}
-		for cr.Next() {
-			var col introspect.Column
-			if err := cr.Scan(&col.Name, &col.Type, &col.Nullable); err != nil {
-				cr.Close()
-				return s, fmt.Errorf("scan column for %s.%s: %w", t.Schema, t.Name, err)
-			}
-			t.Columns = append(t.Columns, col)
-		}
-		cr.Close()
-
-		pkr, err := dbConn.QueryContext(ctx, `
-            SELECT a.attname
-            FROM pg_index i
-            JOIN pg_class c ON i.indrelid = c.oid
-            JOIN pg_namespace ns ON c.relnamespace = ns.oid
-            JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = ANY(i.indkey)
-            WHERE ns.nspname = $1 AND c.relname = $2 AND i.indisprimary`, t.Schema, t.Name)
-		if err == nil {
-			for pkr.Next() {
-				var pkcol string
-				if err := pkr.Scan(&pkcol); err == nil {
-					for j := range t.Columns {
-						if t.Columns[j].Name == pkcol {
-							t.Columns[j].PK = true
-						}
-					}
-				} else {
-					logger.Error("scan primary key: %v", err)
-				}
-			}
-			pkr.Close()
-		} else {
-			logger.Error("query primary key: %v", err)
-		}
+	if err := e.extractPartitions(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "partitions", err)
+	}
+
+	if err := e.extractColumns(ctx, dbConn, &s); err != nil {
+		return s, fmt.Errorf("query columns: %w", err)
+	}
+
+	if err := e.extractPrimaryKeys(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "primary keys", err)
+	}
+
+	if err := e.extractIndexes(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "indexes", err)
+	}
+
+	if err := e.extractConstraints(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "constraints", err)
+	}
+
+	if err := e.extractViews(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "views", err)
+	}
+
+	if err := e.extractTypes(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "types", err)
+	}
+
+	if err := e.extractSequences(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "sequences", err)
+	}
+
+	if err := e.extractTriggers(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "triggers", err)
+	}
+
+	if err := e.extractRoutines(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "routines", err)
 	}
 
 	fkr, err := dbConn.QueryContext(ctx, `
         SELECT
-          tc.table_schema from_schema, 
-          tc.table_name from_table, 
-          string_agg(kcu.column_name, ', ' ORDER BY kcu.ordinal_position) from_columns, 
-          rkcu.table_schema to_schema, 
-          rkcu.table_name to_table,
-          string_agg(rkcu.column_name, ', ' ORDER BY rkcu.ordinal_position) to_columns,
-		  tc.constraint_name
-        FROM information_schema.table_constraints tc
-        JOIN information_schema.key_column_usage kcu
-          ON tc.constraint_name = kcu.constraint_name 
-         AND tc.constraint_schema = kcu.constraint_schema
-        JOIN information_schema.referential_constraints rc
-          ON tc.constraint_name = rc.constraint_name 
-         AND tc.constraint_schema = rc.constraint_schema
-        JOIN information_schema.key_column_usage rkcu
-          ON rc.unique_constraint_name = rkcu.constraint_name 
-         AND rc.unique_constraint_schema = rkcu.constraint_schema 
-         AND kcu.ordinal_position = rkcu.ordinal_position
-        WHERE tc.constraint_type = 'FOREIGN KEY'
-          AND tc.table_schema NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
-        GROUP BY tc.table_schema, tc.table_name, rkcu.table_schema, rkcu.table_name, tc.constraint_name`)
+          fns.nspname from_schema,
+          ft.relname from_table,
+          fa.attname from_column,
+          tns.nspname to_schema,
+          tt.relname to_table,
+          ta.attname to_column,
+          c.conname,
+          CASE c.confdeltype
+            WHEN 'a' THEN 'NO ACTION' WHEN 'r' THEN 'RESTRICT' WHEN 'c' THEN 'CASCADE'
+            WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT'
+          END delete_rule,
+          CASE c.confupdtype
+            WHEN 'a' THEN 'NO ACTION' WHEN 'r' THEN 'RESTRICT' WHEN 'c' THEN 'CASCADE'
+            WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT'
+          END update_rule,
+          CASE c.confmatchtype WHEN 'f' THEN 'FULL' WHEN 'p' THEN 'PARTIAL' ELSE 'SIMPLE' END match_type,
+          c.condeferrable,
+          c.condeferred,
+          NOT c.convalidated not_validated
+        FROM pg_constraint c
+        JOIN pg_class ft ON ft.oid = c.conrelid
+        JOIN pg_namespace fns ON fns.oid = ft.relnamespace
+        JOIN pg_class tt ON tt.oid = c.confrelid
+        JOIN pg_namespace tns ON tns.oid = tt.relnamespace
+        CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(from_attnum, to_attnum, ord)
+        JOIN pg_attribute fa ON fa.attrelid = c.conrelid AND fa.attnum = k.from_attnum
+        JOIN pg_attribute ta ON ta.attrelid = c.confrelid AND ta.attnum = k.to_attnum
+        WHERE c.contype = 'f'
+          AND c.conparentid = 0 -- skip the copies on partitions
+          AND fns.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`+e.opts.Filter.SQL("postgres", "fns.nspname", "ft.relname")+`
+        ORDER BY fns.nspname, ft.relname, c.conname, k.ord`)
 	if err == nil {
 		defer fkr.Close()
+		var failed rowErrors
 		for fkr.Next() {
 			var fk introspect.ForeignKey
-			if err := fkr.Scan(&fk.FromSchema, &fk.FromTable, &fk.FromColumn, &fk.ToSchema, &fk.ToTable, &fk.ToColumn, &fk.Constraint); err == nil {
-				s.ForeignKeys = append(s.ForeignKeys, fk)
+			var from, to string
+			if err := fkr.Scan(&fk.FromSchema, &fk.FromTable, &from, &fk.ToSchema, &fk.ToTable, &to, &fk.Constraint,
+				&fk.OnDelete, &fk.OnUpdate, &fk.MatchType, &fk.Deferrable, &fk.InitiallyDeferred, &fk.NotValidated); err == nil {
+				addForeignKeyColumn(&s, fk, from, to)
 			} else {
-				logger.Error("scan foreign key: %v", err)
+				failed.add(err)
 			}
 		}
+		failed.warn(&s, "", "foreign keys", fkr)
 	} else {
-		logger.Error("query foreign key: %v", err)
+		warn(&s, "", "foreign keys", err)
 	}
 	return s, nil
 }
 
+// extractColumns reads the columns of all tables in one query and attaches
+// them to the tables in s.
+func (e pgExtractor) extractColumns(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT table_schema, table_name, column_name,
+               coalesce(domain_name, CASE WHEN data_type = 'USER-DEFINED' THEN udt_name END, data_type),
+               is_nullable = 'YES',
+               column_default,
+               is_identity = 'YES' OR coalesce(column_default, '') LIKE 'nextval(%',
+               CASE WHEN is_generated = 'ALWAYS' THEN generation_expression END,
+               character_maximum_length,
+               CASE WHEN data_type = 'numeric' THEN numeric_precision END,
+               CASE WHEN data_type = 'numeric' THEN numeric_scale END,
+               collation_name,
+               `+sectionSQL(e.opts, db.SectionComments, `col_description((quote_ident(table_schema)||'.'||quote_ident(table_name))::regclass, ordinal_position)`, `NULL`)+` AS column_comment
+        FROM information_schema.columns
+        WHERE table_schema NOT IN ('pg_catalog','information_schema','pg_toast')`+e.opts.Filter.SQL("postgres", "table_schema", "table_name")+`
+        ORDER BY table_schema, table_name, ordinal_position`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table string
+		var col introspect.Column
+		if err := rows.Scan(&schema, &table, &col.Name, &col.Type, &col.Nullable, &col.Default, &col.Identity, &col.Generated,
+			&col.MaxLength, &col.Precision, &col.Scale, &col.Collation, &col.Comment); err != nil {
+			return fmt.Errorf("scan column: %w", err)
+		}
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			t.Columns = append(t.Columns, col)
+		}
+	}
+	return rows.Err()
+}
+
+// extractPrimaryKeys reads the primary key columns of all tables in one
+// query and flags them in s.
+func (e pgExtractor) extractPrimaryKeys(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT ns.nspname, c.relname, a.attname
+        FROM pg_index i
+        JOIN pg_class c ON i.indrelid = c.oid
+        JOIN pg_namespace ns ON c.relnamespace = ns.oid
+        JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = ANY(i.indkey)
+        WHERE i.indisprimary
+          AND ns.nspname NOT IN ('pg_catalog','information_schema','pg_toast')`+e.opts.Filter.SQL("postgres", "ns.nspname", "c.relname"))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table, pkcol string
+		if err := rows.Scan(&schema, &table, &pkcol); err != nil {
+			return fmt.Errorf("scan primary key: %w", err)
+		}
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			markPrimaryKey(t, pkcol)
+		}
+	}
+	return rows.Err()
+}
+
+// extractPartitions reads the partition key and the partitions of all
+// partitioned tables, attaches the partitions to their parent and removes
+// them from the table list, so the diagram shows one table per partitioned
+// table. Sub-partitions are counted in the size of their top partition.
+func (e pgExtractor) extractPartitions(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionPartitions) {
+		return nil
+	}
+	kr, err := dbConn.QueryContext(ctx, `
+        SELECT ns.nspname, c.relname, pg_get_partkeydef(c.oid)
+        FROM pg_class c
+        JOIN pg_namespace ns ON ns.oid = c.relnamespace
+        WHERE c.relkind = 'p'
+          AND ns.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`+e.opts.Filter.SQL("postgres", "ns.nspname", "c.relname")+`
+        ORDER BY ns.nspname, c.relname`)
+	if err != nil {
+		return err
+	}
+	defer kr.Close()
+
+	tables := tableMap(s)
+	for kr.Next() {
+		var schema, table, keydef string
+		if err := kr.Scan(&schema, &table, &keydef); err != nil {
+			return fmt.Errorf("scan partition key: %w", err)
+		}
+		// keydef looks like "RANGE (created_at)" or "HASH (a, lower(b))"
+		strategy, key, _ := strings.Cut(keydef, " ")
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			t.Partitioning = &introspect.Partitioning{Strategy: strategy, Columns: splitList(groupInner(strings.TrimSpace(key)))}
+		}
+	}
+	if err := kr.Err(); err != nil {
+		return err
+	}
+
+	pr, err := dbConn.QueryContext(ctx, `
+        SELECT pns.nspname, pc.relname, cns.nspname, cc.relname,
+               coalesce(pg_get_expr(cc.relpartbound, cc.oid), ''),
+               (SELECT coalesce(sum(pg_table_size(pt.relid)), 0)
+                FROM pg_partition_tree(cc.oid) pt)::bigint / 8192 AS size_8k_pages,
+               (SELECT coalesce(sum(greatest(tc.reltuples, 0)), 0)
+                FROM pg_partition_tree(cc.oid) pt
+                JOIN pg_class tc ON tc.oid = pt.relid
+                WHERE pt.isleaf)::bigint AS row_estimate
+        FROM pg_inherits i
+        JOIN pg_class pc ON pc.oid = i.inhparent
+        JOIN pg_namespace pns ON pns.oid = pc.relnamespace
+        JOIN pg_class cc ON cc.oid = i.inhrelid
+        JOIN pg_namespace cns ON cns.oid = cc.relnamespace
+        WHERE pc.relkind = 'p'
+          AND pns.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`+e.opts.Filter.SQL("postgres", "pns.nspname", "pc.relname")+`
+        ORDER BY pns.nspname, pc.relname, cns.nspname, cc.relname`)
+	if err != nil {
+		return fmt.Errorf("query partition children: %w", err)
+	}
+	defer pr.Close()
+
+	children := map[string]bool{}
+	for pr.Next() {
+		var schema, table string
+		var p introspect.Partition
+		if err := pr.Scan(&schema, &table, &p.Schema, &p.Name, &p.Bound, &p.Size8kPages, &p.Rows); err != nil {
+			return fmt.Errorf("scan partition: %w", err)
+		}
+		children[tableKey(p.Schema, p.Name)] = true
+		if t, ok := tables[tableKey(schema, table)]; ok && t.Partitioning != nil {
+			addPartition(t, t.Partitioning.Strategy, p)
+		}
+	}
+	if err := pr.Err(); err != nil {
+		return err
+	}
+
+	kept := s.Tables[:0]
+	for _, t := range s.Tables {
+		if !children[tableKey(t.Schema, t.Name)] {
+			kept = append(kept, t)
+		}
+	}
+	s.Tables = kept
+	return nil
+}
+
+// extractIndexes reads all indexes, including expression, partial and
+// covering indexes, and attaches them to the tables in s.
+func (e pgExtractor) extractIndexes(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionIndexes) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT
+          ns.nspname AS schema_name,
+          t.relname AS table_name,
+          i.relname AS index_name,
+          ix.indisunique,
+          ix.indisprimary,
+          coalesce(pg_get_expr(ix.indpred, ix.indrelid, true), '') AS predicate,
+          am.amname AS method,
+          pg_get_indexdef(ix.indexrelid, k.n, true) AS column_name,
+          k.n > ix.indnkeyatts AS is_included
+        FROM pg_index ix
+        JOIN pg_class t ON t.oid = ix.indrelid
+        JOIN pg_class i ON i.oid = ix.indexrelid
+        JOIN pg_namespace ns ON ns.oid = t.relnamespace
+        JOIN pg_am am ON am.oid = i.relam
+        CROSS JOIN LATERAL generate_series(1, ix.indnatts::int) AS k(n)
+        WHERE t.relkind IN ('r', 'p')
+          AND ns.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`+e.opts.Filter.SQL("postgres", "ns.nspname", "t.relname")+`
+        ORDER BY ns.nspname, t.relname, i.relname, k.n`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table, col string
+		var idx introspect.Index
+		var included bool
+		if err := rows.Scan(&schema, &table, &idx.Name, &idx.Unique, &idx.Primary, &idx.Predicate, &idx.Method, &col, &included); err != nil {
+			return fmt.Errorf("scan index: %w", err)
+		}
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			addIndexColumn(t, idx, col, included)
+		}
+	}
+	return rows.Err()
+}
+
+// extractConstraints reads unique, check and exclusion constraints and
+// attaches them to the tables in s.
+func (e pgExtractor) extractConstraints(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionConstraints) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT
+          ns.nspname AS schema_name,
+          t.relname AS table_name,
+          c.conname AS constraint_name,
+          CASE c.contype WHEN 'u' THEN 'UNIQUE' WHEN 'c' THEN 'CHECK' ELSE 'EXCLUDE' END AS constraint_type,
+          CASE c.contype
+            WHEN 'c' THEN pg_get_expr(c.conbin, c.conrelid, true)
+            WHEN 'x' THEN pg_get_constraintdef(c.oid, true)
+          END AS expression,
+          a.attname AS column_name
+        FROM pg_constraint c
+        JOIN pg_class t ON t.oid = c.conrelid
+        JOIN pg_namespace ns ON ns.oid = t.relnamespace
+        LEFT JOIN LATERAL unnest(c.conkey) WITH ORDINALITY AS k(attnum, ord) ON true
+        LEFT JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+        WHERE c.contype IN ('u', 'c', 'x')
+          AND ns.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`+e.opts.Filter.SQL("postgres", "ns.nspname", "t.relname")+`
+        ORDER BY ns.nspname, t.relname, c.conname, k.ord`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var schema, table string
+		var col, expr sql.NullString
+		var c introspect.Constraint
+		if err := rows.Scan(&schema, &table, &c.Name, &c.Type, &expr, &col); err != nil {
+			return fmt.Errorf("scan constraint: %w", err)
+		}
+		c.Expression = expr.String
+		if t, ok := tables[tableKey(schema, table)]; ok {
+			addConstraintColumn(t, c, col.String)
+		}
+	}
+	return rows.Err()
+}
+
+// extractViews reads views and materialized views with their columns and
+// the relations their rewrite rules depend on.
+func (e pgExtractor) extractViews(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionViews) {
+		return nil
+	}
+	vr, err := dbConn.QueryContext(ctx, `
+        SELECT ns.nspname, c.relname, c.relkind = 'm', pg_get_viewdef(c.oid, true), `+sectionSQL(e.opts, db.SectionComments, `obj_description(c.oid, 'pg_class')`, `NULL`)+`
+        FROM pg_class c
+        JOIN pg_namespace ns ON ns.oid = c.relnamespace
+        WHERE c.relkind IN ('v', 'm')
+          AND ns.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`+e.opts.Filter.SQL("postgres", "ns.nspname", "c.relname")+`
+        ORDER BY ns.nspname, c.relname`)
+	if err != nil {
+		return err
+	}
+	defer vr.Close()
+	for vr.Next() {
+		var v introspect.View
+		var def sql.NullString
+		if err := vr.Scan(&v.Schema, &v.Name, &v.Materialized, &def, &v.Comment); err != nil {
+			return fmt.Errorf("scan view: %w", err)
+		}
+		v.Definition = def.String
+		s.Views = append(s.Views, v)
+	}
+	if err := vr.Err(); err != nil {
+		return err
+	}
+
+	// information_schema.columns does not cover materialized views
+	cr, err := dbConn.QueryContext(ctx, `
+        SELECT ns.nspname, c.relname, a.attname, format_type(a.atttypid, a.atttypmod),
+               NOT a.attnotnull, `+sectionSQL(e.opts, db.SectionComments, `col_description(c.oid, a.attnum)`, `NULL`)+`
+        FROM pg_attribute a
+        JOIN pg_class c ON c.oid = a.attrelid
+        JOIN pg_namespace ns ON ns.oid = c.relnamespace
+        WHERE c.relkind IN ('v', 'm')
+          AND a.attnum > 0
+          AND NOT a.attisdropped
+          AND ns.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`+e.opts.Filter.SQL("postgres", "ns.nspname", "c.relname")+`
+        ORDER BY ns.nspname, c.relname, a.attnum`)
+	if err != nil {
+		return fmt.Errorf("query view columns: %w", err)
+	}
+	defer cr.Close()
+	if err := scanViewColumns(cr, s); err != nil {
+		return err
+	}
+
+	dr, err := dbConn.QueryContext(ctx, `
+        SELECT DISTINCT vns.nspname, v.relname, rns.nspname, r.relname
+        FROM pg_depend d
+        JOIN pg_rewrite rw ON rw.oid = d.objid
+        JOIN pg_class v ON v.oid = rw.ev_class
+        JOIN pg_namespace vns ON vns.oid = v.relnamespace
+        JOIN pg_class r ON r.oid = d.refobjid
+        JOIN pg_namespace rns ON rns.oid = r.relnamespace
+        WHERE d.classid = 'pg_rewrite'::regclass
+          AND d.refclassid = 'pg_class'::regclass
+          AND v.relkind IN ('v', 'm')
+          AND r.relkind IN ('r', 'p', 'v', 'm', 'f')
+          AND r.oid <> v.oid
+          AND vns.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`+e.opts.Filter.SQL("postgres", "vns.nspname", "v.relname")+`
+        ORDER BY vns.nspname, v.relname, rns.nspname, r.relname`)
+	if err != nil {
+		return fmt.Errorf("query view dependencies: %w", err)
+	}
+	defer dr.Close()
+	return scanViewDependencies(dr, s)
+}
+
+// extractTypes reads enums with their labels, domains with their base type
+// and checks, and standalone composite types with their attributes.
+func (e pgExtractor) extractTypes(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionTypes) {
+		return nil
+	}
+	er, err := dbConn.QueryContext(ctx, `
+        SELECT ns.nspname, t.typname, e.enumlabel, `+sectionSQL(e.opts, db.SectionComments, `obj_description(t.oid, 'pg_type')`, `NULL`)+`
+        FROM pg_type t
+        JOIN pg_enum e ON e.enumtypid = t.oid
+        JOIN pg_namespace ns ON ns.oid = t.typnamespace
+        WHERE ns.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`+e.opts.Filter.SQL("postgres", "ns.nspname", "")+`
+        ORDER BY ns.nspname, t.typname, e.enumsortorder`)
+	if err != nil {
+		return err
+	}
+	defer er.Close()
+	for er.Next() {
+		var schema, name, label string
+		var comment *string
+		if err := er.Scan(&schema, &name, &label, &comment); err != nil {
+			return fmt.Errorf("scan enum: %w", err)
+		}
+		ut := userType(s, schema, name, introspect.TypeEnum)
+		ut.Labels = append(ut.Labels, label)
+		ut.Comment = comment
+	}
+	if err := er.Err(); err != nil {
+		return err
+	}
+
+	dr, err := dbConn.QueryContext(ctx, `
+        SELECT ns.nspname, t.typname, format_type(t.typbasetype, t.typtypmod),
+               (SELECT string_agg(pg_get_constraintdef(c.oid, true), ' AND ' ORDER BY c.conname)
+                FROM pg_constraint c
+                WHERE c.contypid = t.oid),
+               `+sectionSQL(e.opts, db.SectionComments, `obj_description(t.oid, 'pg_type')`, `NULL`)+`
+        FROM pg_type t
+        JOIN pg_namespace ns ON ns.oid = t.typnamespace
+        WHERE t.typtype = 'd'
+          AND ns.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`+e.opts.Filter.SQL("postgres", "ns.nspname", "")+`
+        ORDER BY ns.nspname, t.typname`)
+	if err != nil {
+		return fmt.Errorf("query domains: %w", err)
+	}
+	defer dr.Close()
+	for dr.Next() {
+		ut := introspect.UserType{Kind: introspect.TypeDomain}
+		var check sql.NullString
+		if err := dr.Scan(&ut.Schema, &ut.Name, &ut.BaseType, &check, &ut.Comment); err != nil {
+			return fmt.Errorf("scan domain: %w", err)
+		}
+		ut.Check = check.String
+		s.Types = append(s.Types, ut)
+	}
+	if err := dr.Err(); err != nil {
+		return err
+	}
+
+	cr, err := dbConn.QueryContext(ctx, `
+        SELECT ns.nspname, t.typname, a.attname, format_type(a.atttypid, a.atttypmod)
+        FROM pg_type t
+        JOIN pg_namespace ns ON ns.oid = t.typnamespace
+        JOIN pg_class c ON c.oid = t.typrelid AND c.relkind = 'c'
+        JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+        WHERE t.typtype = 'c'
+          AND ns.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`+e.opts.Filter.SQL("postgres", "ns.nspname", "")+`
+        ORDER BY ns.nspname, t.typname, a.attnum`)
+	if err != nil {
+		return fmt.Errorf("query composite types: %w", err)
+	}
+	defer cr.Close()
+	for cr.Next() {
+		var schema, name string
+		col := introspect.Column{Nullable: true}
+		if err := cr.Scan(&schema, &name, &col.Name, &col.Type); err != nil {
+			return fmt.Errorf("scan composite type: %w", err)
+		}
+		ut := userType(s, schema, name, introspect.TypeComposite)
+		ut.Attributes = append(ut.Attributes, col)
+	}
+	return cr.Err()
+}
+
+// extractSequences reads all sequences together with the serial or identity
+// column that owns them.
+func (e pgExtractor) extractSequences(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionSequences) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT ns.nspname, c.relname, format_type(sq.seqtypid, NULL),
+               sq.seqstart, sq.seqincrement, sq.seqcycle,
+               tns.nspname, tc.relname, a.attname
+        FROM pg_sequence sq
+        JOIN pg_class c ON c.oid = sq.seqrelid
+        JOIN pg_namespace ns ON ns.oid = c.relnamespace
+        LEFT JOIN pg_depend d ON d.classid = 'pg_class'::regclass AND d.objid = c.oid
+          AND d.refclassid = 'pg_class'::regclass AND d.deptype IN ('a', 'i')
+        LEFT JOIN pg_class tc ON tc.oid = d.refobjid
+        LEFT JOIN pg_namespace tns ON tns.oid = tc.relnamespace
+        LEFT JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+        WHERE ns.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`+e.opts.Filter.SQL("postgres", "ns.nspname", "")+`
+        ORDER BY ns.nspname, c.relname`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var seq introspect.Sequence
+		var ownerSchema, ownerTable, ownerColumn sql.NullString
+		if err := rows.Scan(&seq.Schema, &seq.Name, &seq.DataType, &seq.Start, &seq.Increment, &seq.Cycle,
+			&ownerSchema, &ownerTable, &ownerColumn); err != nil {
+			return fmt.Errorf("scan sequence: %w", err)
+		}
+		if ownerColumn.Valid {
+			seq.OwnedBy = &introspect.ColumnRef{Schema: ownerSchema.String, Table: ownerTable.String, Column: ownerColumn.String}
+		}
+		s.Sequences = append(s.Sequences, seq)
+	}
+	return rows.Err()
+}
+
+// extractTriggers reads all user defined triggers on tables and views.
+func (e pgExtractor) extractTriggers(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionTriggers) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT ns.nspname, t.tgname, c.relname,
+               CASE WHEN t.tgtype & 2 <> 0 THEN 'BEFORE' WHEN t.tgtype & 64 <> 0 THEN 'INSTEAD OF' ELSE 'AFTER' END,
+               concat_ws(',',
+                 CASE WHEN t.tgtype & 4 <> 0 THEN 'INSERT' END,
+                 CASE WHEN t.tgtype & 16 <> 0 THEN 'UPDATE' END,
+                 CASE WHEN t.tgtype & 8 <> 0 THEN 'DELETE' END,
+                 CASE WHEN t.tgtype & 32 <> 0 THEN 'TRUNCATE' END),
+               CASE WHEN t.tgtype & 1 <> 0 THEN 'ROW' ELSE 'STATEMENT' END,
+               t.tgenabled = 'D',
+               pg_get_triggerdef(t.oid, true)
+        FROM pg_trigger t
+        JOIN pg_class c ON c.oid = t.tgrelid
+        JOIN pg_namespace ns ON ns.oid = c.relnamespace
+        WHERE NOT t.tgisinternal
+          AND ns.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`+e.opts.Filter.SQL("postgres", "ns.nspname", "c.relname")+`
+        ORDER BY ns.nspname, c.relname, t.tgname`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tr introspect.Trigger
+		var events string
+		if err := rows.Scan(&tr.Schema, &tr.Name, &tr.Table.Name, &tr.Timing, &events, &tr.Level, &tr.Disabled, &tr.Definition); err != nil {
+			return fmt.Errorf("scan trigger: %w", err)
+		}
+		tr.Table.Schema = tr.Schema
+		tr.Events = splitEvents(events)
+		s.Triggers = append(s.Triggers, tr)
+	}
+	return rows.Err()
+}
+
+// extractRoutines reads functions and procedures that do not belong to an
+// extension. PostgreSQL only records the tables a routine uses for routines
+// with an SQL-standard body, so References stays empty for all others.
+func (e pgExtractor) extractRoutines(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionRoutines) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+        SELECT p.oid::bigint, ns.nspname, p.proname,
+               CASE p.prokind WHEN 'p' THEN 'PROCEDURE' ELSE 'FUNCTION' END,
+               pg_get_function_arguments(p.oid),
+               CASE WHEN p.prokind = 'p' THEN '' ELSE pg_get_function_result(p.oid) END,
+               l.lanname,
+               coalesce(p.prosrc, '')
+        FROM pg_proc p
+        JOIN pg_namespace ns ON ns.oid = p.pronamespace
+        JOIN pg_language l ON l.oid = p.prolang
+        WHERE p.prokind IN ('f', 'p')
+          AND ns.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`+e.opts.Filter.SQL("postgres", "ns.nspname", "")+`
+          AND NOT EXISTS (
+            SELECT 1 FROM pg_depend d
+            WHERE d.classid = 'pg_proc'::regclass AND d.objid = p.oid AND d.deptype = 'e')
+        ORDER BY ns.nspname, p.proname, 5`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	byID := map[int64]int{}
+	for rows.Next() {
+		var id int64
+		var r introspect.Routine
+		if err := rows.Scan(&id, &r.Schema, &r.Name, &r.Kind, &r.Arguments, &r.ReturnType, &r.Language, &r.Definition); err != nil {
+			return fmt.Errorf("scan routine: %w", err)
+		}
+		byID[id] = len(s.Routines)
+		s.Routines = append(s.Routines, r)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	dr, err := dbConn.QueryContext(ctx, `
+        SELECT DISTINCT p.oid::bigint, rns.nspname, rc.relname
+        FROM pg_depend d
+        JOIN pg_proc p ON p.oid = d.objid
+        JOIN pg_namespace ns ON ns.oid = p.pronamespace
+        JOIN pg_class rc ON rc.oid = d.refobjid
+        JOIN pg_namespace rns ON rns.oid = rc.relnamespace
+        WHERE d.classid = 'pg_proc'::regclass
+          AND d.refclassid = 'pg_class'::regclass
+          AND rc.relkind IN ('r', 'p', 'v', 'm', 'f')
+          AND ns.nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`+e.opts.Filter.SQL("postgres", "ns.nspname", "")+`
+        ORDER BY 1, 2, 3`)
+	if err != nil {
+		return fmt.Errorf("query routine dependencies: %w", err)
+	}
+	defer dr.Close()
+	return scanRoutineReferences(dr, s, byID)
+}
+
+// SchemaVersion counts the rows of the catalogs the extraction reads and
+// takes the newest transaction that wrote one of them. DDL always writes
+// catalog rows, and so do VACUUM and ANALYZE, which change the size and
+// row estimates.
+func (pgExtractor) SchemaVersion(ctx context.Context, dbConn *sql.DB) (string, error) {
+	var version string
+	err := dbConn.QueryRowContext(ctx, `
+        SELECT concat_ws('/', count(*), max(xmin::text::bigint))
+        FROM (SELECT xmin FROM pg_namespace
+              UNION ALL SELECT xmin FROM pg_class
+              UNION ALL SELECT xmin FROM pg_attribute
+              UNION ALL SELECT xmin FROM pg_attrdef
+              UNION ALL SELECT xmin FROM pg_constraint
+              UNION ALL SELECT xmin FROM pg_index
+              UNION ALL SELECT xmin FROM pg_type
+              UNION ALL SELECT xmin FROM pg_proc
+              UNION ALL SELECT xmin FROM pg_trigger
+              UNION ALL SELECT xmin FROM pg_description) c`).Scan(&version)
+	return version, err
+}
+
+// Capabilities reports the sections the extractor can return.
+func (pgExtractor) Capabilities() db.Capabilities {
+	return db.Capabilities{Sections: db.AllSections, Filter: true}
+}
+
 func init() {
-	db.Register("postgres", pgExtractor{})
-	db.Register("postgresql", pgExtractor{})
+	db.RegisterOptionsExtractor("postgres", pgExtractor{})
+	db.RegisterOptionsExtractor("postgresql", pgExtractor{})
 }
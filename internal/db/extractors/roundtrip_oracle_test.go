@@ -0,0 +1,8 @@
+//go:build oracle
+// +build oracle
+
+package extractors
+
+func init() {
+	batchedExtractors["oracle"] = oracleExtractor{}
+}
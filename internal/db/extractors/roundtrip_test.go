@@ -0,0 +1,260 @@
+package extractors
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"erddiagram/internal/db"
+)
+
+// batchedExtractors are the extractors whose number of catalog queries must
+// not depend on the number of tables.
+var batchedExtractors = map[string]db.OptionsExtractor{
+	"postgres":  pgExtractor{},
+	"mysql":     myExtractor{},
+	"sqlserver": mssqlExtractor{},
+}
+
+// countingConnector is a database/sql connector that counts and records
+// queries. The first query, the table list, returns tables rows of (schema,
+// name, comment, size, rows) unless tables is 0, and so do queries
+// containing failing, which the extractors cannot scan. All other queries
+// return the rows of rows, if set, or no rows.
+type countingConnector struct {
+	tables  int
+	failing string
+	rows    func(query string) [][]driver.Value
+	queries atomic.Int64
+
+	mu    sync.Mutex
+	texts []string
+}
+
+func (c *countingConnector) Connect(context.Context) (driver.Conn, error) {
+	return countingConn{c}, nil
+}
+func (c *countingConnector) Driver() driver.Driver { return nil }
+
+type countingConn struct{ c *countingConnector }
+
+func (countingConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (countingConn) Close() error                        { return nil }
+func (countingConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+func (countingConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (cc countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	cc.c.mu.Lock()
+	cc.c.texts = append(cc.c.texts, query)
+	cc.c.mu.Unlock()
+	if (cc.c.queries.Add(1) == 1 && cc.c.tables > 0) || (cc.c.failing != "" && strings.Contains(query, cc.c.failing)) {
+		return &tableRows{n: cc.c.tables}, nil
+	}
+	if cc.c.rows != nil {
+		return &valueRows{rows: cc.c.rows(query)}, nil
+	}
+	return &tableRows{}, nil
+}
+
+type tableRows struct{ i, n int }
+
+func (r *tableRows) Columns() []string {
+	return []string{"schema", "name", "comment", "size", "rows"}
+}
+func (r *tableRows) Close() error { return nil }
+func (r *tableRows) Next(dest []driver.Value) error {
+	if r.i >= r.n {
+		return io.EOF
+	}
+	r.i++
+	copy(dest, []driver.Value{"s", fmt.Sprintf("t%d", r.i), "", "1", "1"})
+	return nil
+}
+
+// valueRows returns the given rows, which all have the same length.
+type valueRows struct{ rows [][]driver.Value }
+
+func (r *valueRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+func (r *valueRows) Close() error { return nil }
+func (r *valueRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// runQueries runs extractor e against a fake database with the given
+// number of tables and options opts and returns the connector, which holds
+// the queries it issued.
+func runQueries(t testing.TB, e db.OptionsExtractor, tables int, opts db.Options) *countingConnector {
+	c := &countingConnector{tables: tables}
+	dbConn := sql.OpenDB(c)
+	defer dbConn.Close()
+	s, err := e.ExtractWithOptions(context.Background(), dbConn, opts)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if len(s.Tables) != tables {
+		t.Fatalf("\ngot %d tables, wanted %d", len(s.Tables), tables)
+	}
+	return c
+}
+
+// countQueries returns the number of queries runQueries issued.
+func countQueries(t testing.TB, e db.OptionsExtractor, tables int, opts db.Options) int64 {
+	return runQueries(t, e, tables, opts).queries.Load()
+}
+
+func TestRoundTripsDoNotScaleWithTables(t *testing.T) {
+	for name, e := range batchedExtractors {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(name, func(t *testing.T) {
+			small, large := countQueries(t, e, 10, db.Options{}), countQueries(t, e, 1000, db.Options{})
+			if small != large {
+				t.Errorf("\ngot %d queries for 10 tables and %d for 1000 tables, wanted the same", small, large)
+			}
+		})
+	}
+}
+
+// skippedSQL lists catalog lookups of optional sections that the queries
+// of each extractor must leave out when the sections are skipped.
+var skippedSQL = map[string][]string{
+	"postgres":  {"obj_description", "col_description", "pg_table_size"},
+	"mysql":     {"information_schema.statistics", "information_schema.views"},
+	"sqlserver": {"extended_properties", "COLUMNPROPERTY(sep.major_id", "used_pages"},
+	"oracle":    {"all_tab_comments", "all_col_comments", "user_tablespaces"},
+}
+
+func TestSkippedSectionsSaveQueries(t *testing.T) {
+	for name, e := range batchedExtractors {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(name, func(t *testing.T) {
+			all, none := runQueries(t, e, 10, db.Options{}), runQueries(t, e, 10, db.Options{Skip: db.AllSections})
+			// tables, columns, primary keys and foreign keys are always read
+			if n, m := all.queries.Load(), none.queries.Load(); m != 4 || n <= m {
+				t.Errorf("\ngot %d queries with all sections and %d without, wanted 4 without and more with all", n, m)
+			}
+			allSQL, noneSQL := strings.Join(all.texts, "\n"), strings.Join(none.texts, "\n")
+			for _, lookup := range skippedSQL[name] {
+				if !strings.Contains(allSQL, lookup) {
+					t.Errorf("\ngot no %s with all sections, wanted it", lookup)
+				}
+				if strings.Contains(noneSQL, lookup) {
+					t.Errorf("\ngot %s with all sections skipped, wanted it left out", lookup)
+				}
+			}
+		})
+	}
+}
+
+func TestColumnComments(t *testing.T) {
+	// a condition only the column query of each extractor has, its comment
+	// lookup, and a column row of table s.t1 without the comment
+	var tests = []struct {
+		name   string
+		query  string
+		lookup string
+		row    []driver.Value
+	}{
+		{"postgres", "AS column_comment", "col_description",
+			[]driver.Value{"s", "t1", "id", "integer", false, nil, false, nil, nil, nil, nil, nil}},
+		{"mysql", "nullif(generation_expression", "nullif(column_comment",
+			[]driver.Value{"s", "t1", "id", "int", false, nil, false, nil, nil, nil, nil, nil}},
+		{"sqlserver", "FROM INFORMATION_SCHEMA.COLUMNS c", "extended_properties",
+			[]driver.Value{"s", "t1", "id", "int", int64(0), nil, int64(0), nil, nil, nil, nil, nil}},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			for _, skip := range []db.Section{0, db.SectionComments} {
+				// the fake database answers like a real one: with the comment
+				// only if the query looks it up
+				c := &countingConnector{tables: 1, rows: func(query string) [][]driver.Value {
+					if !strings.Contains(query, tt.query) {
+						return nil
+					}
+					var comment driver.Value
+					if strings.Contains(query, tt.lookup) {
+						comment = "the id"
+					}
+					return [][]driver.Value{append(slices.Clone(tt.row), comment)}
+				}}
+				dbConn := sql.OpenDB(c)
+				s, err := batchedExtractors[tt.name].ExtractWithOptions(t.Context(), dbConn, db.Options{Skip: skip})
+				dbConn.Close()
+				if err != nil {
+					t.Fatalf("\ngot unexpected error: \"%v\"", err)
+				}
+				if len(s.Tables) != 1 || len(s.Tables[0].Columns) != 1 {
+					t.Fatalf("\ngot tables %+v, wanted one with one column", s.Tables)
+				}
+				comment := s.Tables[0].Columns[0].Comment
+				if skip == 0 && (comment == nil || *comment != "the id") {
+					t.Errorf("\ngot comment %v, wanted \"the id\"", comment)
+				}
+				if skip != 0 && comment != nil {
+					t.Errorf("\ngot comment %q with comments skipped, wanted none", *comment)
+				}
+			}
+		})
+	}
+}
+
+func TestScanWarningsPerQuery(t *testing.T) {
+	// a condition only the foreign key query of each extractor has
+	fkQueries := map[string]string{
+		"postgres":  "c.contype = 'f'",
+		"mysql":     "kcu.referenced_table_name IS NOT NULL",
+		"sqlserver": "fkc.constraint_column_id",
+		"oracle":    "a.constraint_type = 'R'",
+	}
+	for name, e := range batchedExtractors {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(name, func(t *testing.T) {
+			dbConn := sql.OpenDB(&countingConnector{tables: 3, failing: fkQueries[name]})
+			defer dbConn.Close()
+			s, err := e.ExtractWithOptions(t.Context(), dbConn, db.Options{})
+			if err != nil {
+				t.Fatalf("\ngot unexpected error: \"%v\"", err)
+			}
+			if len(s.Warnings) != 1 || s.Warnings[0].Phase != "foreign keys" || !strings.Contains(s.Warnings[0].Message, "skipped 3 row(s)") {
+				t.Errorf("\ngot warnings %+v, wanted one for the 3 foreign key rows", s.Warnings)
+			}
+		})
+	}
+}
+
+// BenchmarkExtractRoundTrips reports the queries per extraction for growing
+// table counts, run it with go test -bench RoundTrips ./internal/db/extractors
+func BenchmarkExtractRoundTrips(b *testing.B) {
+	for name, e := range batchedExtractors {
+		for _, tables := range []int{10, 100, 1000} {
+			b.Run(fmt.Sprintf("%s/%d", name, tables), func(b *testing.B) {
+				var queries int64
+				for b.Loop() {
+					queries = countQueries(b, e, tables, db.Options{})
+				}
+				b.ReportMetric(float64(queries), "queries/op")
+			})
+		}
+	}
+}
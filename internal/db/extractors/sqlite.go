@@ -3,42 +3,52 @@ package extractors
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"erddiagram/internal/db"
 	"erddiagram/internal/introspect"
 	"erddiagram/internal/logger"
 )
 
-// sqliteExtractor implements Extractor for SQLite.
-type sqliteExtractor struct{}
+// sqliteExtractor implements OptionsExtractor for SQLite.
+type sqliteExtractor struct {
+	opts db.Options
+}
 
 // This is the extractor for SQLite
-func (sqliteExtractor) Extract(ctx context.Context, dbConn *sql.DB) (introspect.Schema, error) {
+func (e sqliteExtractor) ExtractWithOptions(ctx context.Context, dbConn *sql.DB, opts db.Options) (introspect.Schema, error) {
+	e.opts = opts
 	var s introspect.Schema
 	dbName := "main"
 
-	if rows, err := dbConn.QueryContext(ctx, `PRAGMA database_list`); err == nil {
-		defer rows.Close()
-		var seq int
-		var name, file sql.NullString
-		if rows.Next() {
-			if err := rows.Scan(&seq, &name, &file); err == nil && name.Valid {
-				dbName = name.String
-			}
+	// the first row is the main database; QueryRow closes the rows after
+	// the scan, so the connection is free for the queries below
+	var seq int
+	var name, file sql.NullString
+	if err := dbConn.QueryRowContext(ctx, `PRAGMA database_list`).Scan(&seq, &name, &file); err == nil {
+		if name.Valid {
+			dbName = name.String
 		}
-	} else {
-		logger.Error("database list: %v", err)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		warn(&s, "", "database list", err)
 	}
 
-	trQuery := `
-	    SELECT m.name, s.size_8k_pages
-		FROM sqlite_master m
+	// dbstat walks every page of the database, so sizes are read only on request
+	size, sizeJoin := `0`, ``
+	if e.opts.Wants(db.SectionSizes) {
+		size, sizeJoin = `s.size_8k_pages`, `
 		LEFT JOIN (SELECT name, CAST(CEIL(SUM(pgsize) / 8192.0) AS integer) AS size_8k_pages
 				FROM dbstat
-				GROUP BY name) s ON m.name = s.name
+				GROUP BY name) s ON m.name = s.name`
+	}
+	trQuery := `
+	    SELECT m.name, m.sql, ` + size + `
+		FROM sqlite_master m` + sizeJoin + `
 		WHERE m.type='table' 
-		AND m.name NOT LIKE 'sqlite_%' 
+		AND m.name NOT LIKE 'sqlite_%'` + e.opts.Filter.SQL("sqlite", "", "m.name") + `
 		ORDER BY m.name`
 	//fmt.Sprintf("SELECT name FROM %s.sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%%' ORDER BY name", dbName)
 	tr, err := dbConn.QueryContext(ctx, trQuery)
@@ -47,72 +57,346 @@ func (sqliteExtractor) Extract(ctx context.Context, dbConn *sql.DB) (introspect.
 	}
 	defer tr.Close()
 
+	// CREATE TABLE statements by table name, parsed for details the pragmas do not report
+	ddl := map[string]string{}
 	for tr.Next() {
 		var tab introspect.Table
-		if err := tr.Scan(&tab.Name, &tab.Size8kPages); err != nil {
+		var tabDDL sql.NullString
+		if err := tr.Scan(&tab.Name, &tabDDL, &tab.Size8kPages); err != nil {
 			return s, fmt.Errorf("scan table row: %w", err)
 		}
+		ddl[tab.Name] = tabDDL.String
 		s.Tables = append(s.Tables, tab)
 	}
 
-	for i := range s.Tables {
+	// each table needs its own pragma queries, so they are spread over a
+	// pool of connections; every worker only writes to the slots of its table
+	pks := make([][]string, len(s.Tables))
+	parts := make([]introspect.Schema, len(s.Tables))
+	err = db.ForEach(ctx, len(s.Tables), e.opts.Concurrency, func(ctx context.Context, i int) error {
 		t := &s.Tables[i]
-		tiQuery := fmt.Sprintf("PRAGMA %s.table_info('%s')", dbName, t.Name)
-		pr, err := dbConn.QueryContext(ctx, tiQuery)
-		if err != nil {
-			return s, fmt.Errorf("query columns for %s.%s: %w", t.Schema, t.Name, err)
-		}
-		for pr.Next() {
-			var cid int
-			var name, ctype string
-			var notnull, pk int
-			var dflt sql.NullString
-			if err := pr.Scan(&cid, &name, &ctype, &notnull, &dflt, &pk); err != nil {
-				pr.Close()
-				return s, fmt.Errorf("scan column for %s.%s: %w", t.Schema, t.Name, err)
-			}
-			col := introspect.Column{
-				Name:     name,
-				Type:     ctype,
-				Nullable: notnull == 0,
-				PK:       pk != 0,
+		var err error
+		pks[i], err = e.extractTable(ctx, dbConn, dbName, t, ddl[t.Name], &parts[i])
+		return err
+	})
+	if err != nil {
+		return s, err
+	}
+
+	// primary key columns in key order by table name
+	pkCols := map[string][]string{}
+	for i, t := range s.Tables {
+		pkCols[t.Name] = pks[i]
+		s.ForeignKeys = append(s.ForeignKeys, parts[i].ForeignKeys...)
+		s.Warnings = append(s.Warnings, parts[i].Warnings...)
+	}
+
+	resolveImplicitReferences(&s, pkCols)
+
+	if err := e.extractRowEstimates(ctx, dbConn, &s); err != nil {
+		// sqlite_stat1 only exists once ANALYZE has been run
+		logger.Debug("query row estimates: %v", err)
+	}
+
+	if err := e.extractViews(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "views", err)
+	}
+
+	if err := e.extractTriggers(ctx, dbConn, &s); err != nil {
+		warn(&s, "", "triggers", err)
+	}
+
+	return s, nil
+}
+
+// extractTable reads the columns, indexes and foreign keys of table t and
+// returns its primary key columns in key order. The foreign keys and the
+// warnings of the table are added to part.
+func (e sqliteExtractor) extractTable(ctx context.Context, dbConn *sql.DB, dbName string, t *introspect.Table, ddl string, part *introspect.Schema) ([]string, error) {
+	var pkCols []string
+
+	def := parseSQLiteCreateTable(ddl)
+	t.Constraints = def.Constraints
+
+	// table_xinfo is table_info plus generated columns
+	tiQuery := fmt.Sprintf("PRAGMA %s.table_xinfo('%s')", dbName, t.Name)
+	pr, err := dbConn.QueryContext(ctx, tiQuery)
+	if err != nil {
+		return nil, fmt.Errorf("query columns for %s.%s: %w", t.Schema, t.Name, err)
+	}
+	for pr.Next() {
+		var cid int
+		var name, ctype string
+		var notnull, pk, hidden int
+		var dflt sql.NullString
+		if err := pr.Scan(&cid, &name, &ctype, &notnull, &dflt, &pk, &hidden); err != nil {
+			pr.Close()
+			return nil, fmt.Errorf("scan column for %s.%s: %w", t.Schema, t.Name, err)
+		}
+		if hidden == 1 {
+			// hidden column of a virtual table
+			continue
+		}
+		coldef := def.Columns[name]
+		col := introspect.Column{
+			Name:      name,
+			Type:      ctype,
+			Nullable:  notnull == 0,
+			PK:        pk != 0,
+			Identity:  coldef.Autoincrement,
+			Collation: stringOrNil(coldef.Collation),
+		}
+		if dflt.Valid {
+			col.Default = &dflt.String
+		}
+		if hidden == 2 || hidden == 3 {
+			// virtual or stored generated column
+			col.Generated = stringOrNil(coldef.Generated)
+		}
+		col.MaxLength, col.Precision, col.Scale = sqliteTypeSize(ctype)
+		t.Columns = append(t.Columns, col)
+		if pk > 0 {
+			// pk is the 1-based position of the column in the primary key
+			for len(pkCols) < pk {
+				pkCols = append(pkCols, "")
 			}
-			t.Columns = append(t.Columns, col)
-		}
-		pr.Close()
-
-		fkQuery := fmt.Sprintf(`
-		    SELECT "table", string_agg("from", ', ') AS from_column, string_agg("to", ', ') AS to_column
-		    FROM pragma_foreign_key_list('%s') 
-			GROUP BY "table"`, t.Name)
-		fkRows, err := dbConn.QueryContext(ctx, fkQuery)
-
-		if err == nil {
-			for fkRows.Next() {
-				var table, from, to sql.NullString
-				if err := fkRows.Scan(&table, &from, &to); err == nil {
-					if table.Valid && from.Valid && to.Valid {
-						s.ForeignKeys = append(s.ForeignKeys, introspect.ForeignKey{
-							FromTable:  t.Name,
-							FromColumn: from.String,
-							ToTable:    table.String,
-							ToColumn:   to.String,
-						})
-					}
-				} else {
-					logger.Error("scan foreign key: %v", err)
+			pkCols[pk-1] = name
+		}
+	}
+	pr.Close()
+
+	if err := e.extractIndexes(ctx, dbConn, t); err != nil {
+		warn(part, t.Name, "indexes", err)
+	}
+
+	fkRows, err := dbConn.QueryContext(ctx, `
+	    SELECT id, "table", "from", "to", on_delete, on_update
+	    FROM pragma_foreign_key_list(?)
+	    ORDER BY id, seq`, t.Name)
+	if err == nil {
+		// one row per column pair, the id identifies the constraint
+		first, lastID := len(part.ForeignKeys), -1
+		var failed rowErrors
+		for fkRows.Next() {
+			var id int
+			var table, from, to, onDelete, onUpdate sql.NullString
+			if err := fkRows.Scan(&id, &table, &from, &to, &onDelete, &onUpdate); err == nil {
+				if !table.Valid || !from.Valid {
+					continue
+				}
+				if id != lastID {
+					part.ForeignKeys = append(part.ForeignKeys, introspect.ForeignKey{
+						FromTable: t.Name,
+						ToTable:   table.String,
+						OnDelete:  onDelete.String,
+						OnUpdate:  onUpdate.String,
+					})
+					lastID = id
 				}
+				// "to" is NULL when the primary key is referenced implicitly
+				part.ForeignKeys[len(part.ForeignKeys)-1].AddColumn(from.String, to.String)
+			} else {
+				failed.add(err)
+			}
+		}
+		failed.warn(part, t.Name, "foreign keys", fkRows)
+		fkRows.Close()
+		// the pragma does not report whether a key is deferrable
+		used := map[int]bool{}
+		for i := first; i < len(part.ForeignKeys); i++ {
+			fk := &part.ForeignKeys[i]
+			var from []string
+			for _, c := range fk.Columns {
+				from = append(from, c.From)
+			}
+			if fkdef, ok := def.matchForeignKey(fk.ToTable, from, used); ok {
+				fk.Deferrable, fk.InitiallyDeferred = fkdef.Deferrable, fkdef.InitiallyDeferred
 			}
-			fkRows.Close()
-		} else {
-			logger.Error("query foreign key: %v", err)
 		}
+	} else {
+		warn(part, t.Name, "foreign keys", err)
 	}
+	return pkCols, nil
+}
 
-	return s, nil
+// extractRowEstimates reads the row counts ANALYZE stores in sqlite_stat1.
+// The first number of each stat entry is the row count of the table.
+func (e sqliteExtractor) extractRowEstimates(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	rows, err := dbConn.QueryContext(ctx, `
+	    SELECT tbl, max(CAST(substr(stat, 1, instr(stat || ' ', ' ') - 1) AS integer))
+	    FROM sqlite_stat1
+	    GROUP BY tbl`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := tableMap(s)
+	for rows.Next() {
+		var table string
+		var n int64
+		if err := rows.Scan(&table, &n); err != nil {
+			return fmt.Errorf("scan row estimate: %w", err)
+		}
+		if t, ok := tables[tableKey("", table)]; ok {
+			t.Rows = n
+		}
+	}
+	return rows.Err()
+}
+
+// resolveImplicitReferences fills in the referenced columns of foreign keys
+// declared as "REFERENCES parent" without a column list, which reference
+// the primary key of the parent table.
+func resolveImplicitReferences(s *introspect.Schema, pkCols map[string][]string) {
+	for i := range s.ForeignKeys {
+		fk := &s.ForeignKeys[i]
+		pk := pkCols[fk.ToTable]
+		if len(fk.Columns) == 0 || fk.Columns[0].To != "" || len(pk) != len(fk.Columns) {
+			continue
+		}
+		cols := fk.Columns
+		fk.Columns, fk.FromColumn, fk.ToColumn = nil, "", ""
+		for j, c := range cols {
+			fk.AddColumn(c.From, pk[j])
+		}
+	}
+}
+
+// extractViews reads views and their columns. SQLite does not record
+// view dependencies, so DependsOn stays empty.
+func (e sqliteExtractor) extractViews(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionViews) {
+		return nil
+	}
+	vr, err := dbConn.QueryContext(ctx, `
+	    SELECT name, sql
+	    FROM sqlite_master
+	    WHERE type = 'view'`+e.opts.Filter.SQL("sqlite", "", "name")+`
+	    ORDER BY name`)
+	if err != nil {
+		return err
+	}
+	defer vr.Close()
+	for vr.Next() {
+		var v introspect.View
+		var def sql.NullString
+		if err := vr.Scan(&v.Name, &def); err != nil {
+			return fmt.Errorf("scan view: %w", err)
+		}
+		v.Definition = def.String
+		s.Views = append(s.Views, v)
+	}
+	if err := vr.Err(); err != nil {
+		return err
+	}
+
+	return db.ForEach(ctx, len(s.Views), e.opts.Concurrency, func(ctx context.Context, i int) error {
+		v := &s.Views[i]
+		cr, err := dbConn.QueryContext(ctx, `SELECT name, type, "notnull" = 0 FROM pragma_table_info(?)`, v.Name)
+		if err != nil {
+			return fmt.Errorf("query columns for view %s: %w", v.Name, err)
+		}
+		defer cr.Close()
+		for cr.Next() {
+			var col introspect.Column
+			if err := cr.Scan(&col.Name, &col.Type, &col.Nullable); err != nil {
+				return fmt.Errorf("scan column for view %s: %w", v.Name, err)
+			}
+			v.Columns = append(v.Columns, col)
+		}
+		return cr.Err()
+	})
+}
+
+// extractTriggers reads the triggers stored in sqlite_master. SQLite has no
+// sequences or stored routines, and its triggers always fire for each row.
+func (e sqliteExtractor) extractTriggers(ctx context.Context, dbConn *sql.DB, s *introspect.Schema) error {
+	if !e.opts.Wants(db.SectionTriggers) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+	    SELECT name, tbl_name, sql
+	    FROM sqlite_master
+	    WHERE type = 'trigger'`+e.opts.Filter.SQL("sqlite", "", "tbl_name")+`
+	    ORDER BY tbl_name, name`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		tr := introspect.Trigger{Level: "ROW"}
+		var def sql.NullString
+		if err := rows.Scan(&tr.Name, &tr.Table.Name, &def); err != nil {
+			return fmt.Errorf("scan trigger: %w", err)
+		}
+		tr.Definition = def.String
+		tr.Timing, tr.Events = parseSQLiteTrigger(tr.Definition)
+		s.Triggers = append(s.Triggers, tr)
+	}
+	return rows.Err()
+}
+
+// extractIndexes reads the indexes of table t, including the automatic
+// indexes behind PRIMARY KEY and UNIQUE constraints.
+func (e sqliteExtractor) extractIndexes(ctx context.Context, dbConn *sql.DB, t *introspect.Table) error {
+	if !e.opts.Wants(db.SectionIndexes) {
+		return nil
+	}
+	rows, err := dbConn.QueryContext(ctx, `
+	    SELECT il.name, il."unique", il.origin = 'pk', ii.name, m.sql
+	    FROM pragma_index_list(?) il
+	    JOIN pragma_index_info(il.name) ii
+	    LEFT JOIN sqlite_master m
+	      ON m.type = 'index'
+	     AND m.name = il.name
+	    ORDER BY il.name, ii.seqno`, t.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var idx introspect.Index
+		var col, ddl sql.NullString
+		if err := rows.Scan(&idx.Name, &idx.Unique, &idx.Primary, &col, &ddl); err != nil {
+			return fmt.Errorf("scan index: %w", err)
+		}
+		if !col.Valid {
+			// expression index, pragma_index_info does not expose the expression
+			col.String = "(expression)"
+		}
+		idx.Predicate = sqlitePartialPredicate(ddl.String)
+		addIndexColumn(t, idx, col.String, false)
+	}
+	return rows.Err()
+}
+
+// sqlitePartialPredicate returns the WHERE clause of a CREATE INDEX
+// statement, or an empty string for a full index.
+func sqlitePartialPredicate(ddl string) string {
+	if m := partialIndexRe.FindStringSubmatch(ddl); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	return ""
+}
+
+var partialIndexRe = regexp.MustCompile(`(?is)\)\s*WHERE\s+(.+)$`)
+
+// SchemaVersion returns the schema cookie, which SQLite increments on
+// every schema change.
+func (sqliteExtractor) SchemaVersion(ctx context.Context, dbConn *sql.DB) (string, error) {
+	var version string
+	err := dbConn.QueryRowContext(ctx, `PRAGMA schema_version`).Scan(&version)
+	return version, err
+}
+
+// Capabilities reports the sections the extractor can return.
+func (sqliteExtractor) Capabilities() db.Capabilities {
+	return db.Capabilities{Sections: db.SectionSizes | db.SectionIndexes | db.SectionConstraints | db.SectionViews | db.SectionTriggers, Filter: true, Concurrency: true}
 }
 
 func init() {
-	db.Register("sqlite3", sqliteExtractor{})
-	db.Register("sqlite", sqliteExtractor{})
+	db.RegisterOptionsExtractor("sqlite3", sqliteExtractor{})
+	db.RegisterOptionsExtractor("sqlite", sqliteExtractor{})
 }
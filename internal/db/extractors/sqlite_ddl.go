@@ -0,0 +1,379 @@
+package extractors
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"erddiagram/internal/introspect"
+)
+
+// sqliteTableDef holds the details of a CREATE TABLE statement that SQLite
+// does not expose through its pragmas.
+type sqliteTableDef struct {
+	Columns     map[string]sqliteColumnDef // by column name
+	Constraints []introspect.Constraint
+	ForeignKeys []sqliteForeignKeyDef // in declaration order
+}
+
+// sqliteColumnDef holds the column details parsed from a column definition.
+type sqliteColumnDef struct {
+	Autoincrement bool
+	Generated     string // expression of a generated column
+	Collation     string
+}
+
+// sqliteForeignKeyDef holds the foreign key details that
+// pragma_foreign_key_list leaves out.
+type sqliteForeignKeyDef struct {
+	From              []string // referencing columns
+	Table             string   // referenced table
+	Deferrable        bool
+	InitiallyDeferred bool
+}
+
+// parseSQLiteCreateTable parses the CREATE TABLE statement stored in
+// sqlite_master. It is lenient: anything it does not understand is skipped.
+func parseSQLiteCreateTable(ddl string) sqliteTableDef {
+	def := sqliteTableDef{Columns: map[string]sqliteColumnDef{}}
+
+	var body string
+	for _, tok := range sqliteTokens(ddl) {
+		if strings.HasPrefix(tok, "(") {
+			body = tok
+			break
+		}
+	}
+	if body == "" {
+		return def
+	}
+
+	for _, item := range splitSQLiteItems(sqliteTokens(groupInner(body))) {
+		if len(item) == 0 {
+			continue
+		}
+		switch strings.ToUpper(item[0]) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			def.Constraints = append(def.Constraints, sqliteConstraints(item, "")...)
+			def.ForeignKeys = append(def.ForeignKeys, sqliteForeignKeys(item, "")...)
+		default:
+			col := unquoteSQLiteIdent(item[0])
+			def.Columns[col] = sqliteColumn(item[1:])
+			def.Constraints = append(def.Constraints, sqliteConstraints(item[1:], col)...)
+			def.ForeignKeys = append(def.ForeignKeys, sqliteForeignKeys(item[1:], col)...)
+		}
+	}
+	return def
+}
+
+// sqliteColumn collects the details of a column definition from the tokens
+// following the column name.
+func sqliteColumn(toks []string) sqliteColumnDef {
+	var col sqliteColumnDef
+	for i := 0; i < len(toks); i++ {
+		switch strings.ToUpper(toks[i]) {
+		case "AUTOINCREMENT":
+			col.Autoincrement = true
+		case "COLLATE":
+			if i+1 < len(toks) {
+				i++
+				col.Collation = unquoteSQLiteIdent(toks[i])
+			}
+		case "AS":
+			// GENERATED ALWAYS AS (expr) or the short form AS (expr)
+			if i+1 < len(toks) && strings.HasPrefix(toks[i+1], "(") {
+				i++
+				col.Generated = strings.TrimSpace(groupInner(toks[i]))
+			}
+		}
+	}
+	return col
+}
+
+// sqliteTypeSize returns the declared length of a character or binary
+// column type such as VARCHAR(20), or the precision and scale of other
+// types such as DECIMAL(10, 2). SQLite does not enforce them, but they
+// document the intended column type.
+func sqliteTypeSize(ctype string) (length, precision, scale *int64) {
+	for _, tok := range sqliteTokens(ctype) {
+		if !strings.HasPrefix(tok, "(") {
+			continue
+		}
+		var size *int64
+		items := splitSQLiteItems(sqliteTokens(groupInner(tok)))
+		if len(items) > 0 && len(items[0]) == 1 {
+			if n, err := strconv.ParseInt(items[0][0], 10, 64); err == nil {
+				size = &n
+			}
+		}
+		if len(items) > 1 && len(items[1]) == 1 {
+			if n, err := strconv.ParseInt(items[1][0], 10, 64); err == nil {
+				scale = &n
+			}
+		}
+		// the affinity rules of SQLite: types with CHAR, CLOB or TEXT in
+		// their name hold text
+		upper := strings.ToUpper(ctype)
+		if scale == nil && (strings.Contains(upper, "CHAR") || strings.Contains(upper, "CLOB") ||
+			strings.Contains(upper, "TEXT") || strings.Contains(upper, "BINARY") || strings.Contains(upper, "BLOB")) {
+			return size, nil, nil
+		}
+		return nil, size, scale
+	}
+	return nil, nil, nil
+}
+
+// sqliteConstraints collects UNIQUE and CHECK constraints from the tokens of
+// a table constraint (col == "") or of the column definition of col.
+func sqliteConstraints(toks []string, col string) []introspect.Constraint {
+	var cons []introspect.Constraint
+	var name string
+	for i := 0; i < len(toks); i++ {
+		switch strings.ToUpper(toks[i]) {
+		case "CONSTRAINT":
+			if i+1 < len(toks) {
+				i++
+				name = unquoteSQLiteIdent(toks[i])
+			}
+			continue
+		case "UNIQUE":
+			c := introspect.Constraint{Name: name, Type: introspect.ConstraintUnique}
+			if col != "" {
+				c.Columns = []string{col}
+			} else if i+1 < len(toks) && strings.HasPrefix(toks[i+1], "(") {
+				i++
+				c.Columns = sqliteColumnList(toks[i])
+			}
+			cons = append(cons, c)
+		case "CHECK":
+			c := introspect.Constraint{Name: name, Type: introspect.ConstraintCheck}
+			if col != "" {
+				c.Columns = []string{col}
+			}
+			if i+1 < len(toks) && strings.HasPrefix(toks[i+1], "(") {
+				i++
+				c.Expression = strings.TrimSpace(groupInner(toks[i]))
+			}
+			cons = append(cons, c)
+		case "PRIMARY", "NOT", "NULL", "DEFAULT", "REFERENCES", "FOREIGN", "COLLATE", "GENERATED", "AS":
+		default:
+			continue
+		}
+		// a constraint name only applies to the constraint that follows it
+		name = ""
+	}
+	return cons
+}
+
+// sqliteForeignKeys collects the foreign keys from the tokens of a table
+// constraint (col == "") or of the column definition of col.
+func sqliteForeignKeys(toks []string, col string) []sqliteForeignKeyDef {
+	var fks []sqliteForeignKeyDef
+	from := []string{col}
+	for i := 0; i < len(toks); i++ {
+		switch strings.ToUpper(toks[i]) {
+		case "FOREIGN":
+			// FOREIGN KEY (a, b)
+			if i+2 < len(toks) && strings.HasPrefix(toks[i+2], "(") {
+				i += 2
+				from = sqliteColumnList(toks[i])
+			}
+		case "REFERENCES":
+			if i+1 < len(toks) {
+				i++
+				fks = append(fks, sqliteForeignKeyDef{From: from, Table: unquoteSQLiteIdent(toks[i])})
+			}
+		case "NOT":
+			// NOT DEFERRABLE is the default, unlike NOT NULL it needs no action
+			if i+1 < len(toks) && strings.EqualFold(toks[i+1], "DEFERRABLE") {
+				i++
+			}
+		case "DEFERRABLE":
+			if n := len(fks); n > 0 {
+				fks[n-1].Deferrable = true
+				fks[n-1].InitiallyDeferred = i+2 < len(toks) && strings.EqualFold(toks[i+1], "INITIALLY") &&
+					strings.EqualFold(toks[i+2], "DEFERRED")
+			}
+		}
+	}
+	return fks
+}
+
+// matchForeignKey returns the foreign key of def that references table from the
+// columns from and that no earlier call returned, marking it as used.
+func (def sqliteTableDef) matchForeignKey(table string, from []string, used map[int]bool) (sqliteForeignKeyDef, bool) {
+	for i, fk := range def.ForeignKeys {
+		if !used[i] && strings.EqualFold(fk.Table, table) && slices.EqualFunc(fk.From, from, strings.EqualFold) {
+			used[i] = true
+			return fk, true
+		}
+	}
+	return sqliteForeignKeyDef{}, false
+}
+
+// sqliteColumnList returns the column names of a parenthesized column list
+// such as "(a, b COLLATE NOCASE, c DESC)".
+func sqliteColumnList(group string) []string {
+	var cols []string
+	for _, item := range splitSQLiteItems(sqliteTokens(groupInner(group))) {
+		if len(item) > 0 {
+			cols = append(cols, unquoteSQLiteIdent(item[0]))
+		}
+	}
+	return cols
+}
+
+// splitSQLiteItems splits a token list at top level commas.
+func splitSQLiteItems(toks []string) [][]string {
+	var items [][]string
+	var cur []string
+	for _, tok := range toks {
+		if tok == "," {
+			items = append(items, cur)
+			cur = nil
+			continue
+		}
+		cur = append(cur, tok)
+	}
+	return append(items, cur)
+}
+
+// groupInner strips the enclosing parentheses of a group token.
+func groupInner(group string) string {
+	group = strings.TrimPrefix(group, "(")
+	return strings.TrimSuffix(group, ")")
+}
+
+// unquoteSQLiteIdent removes SQLite identifier quoting ("x", [x], `x`).
+func unquoteSQLiteIdent(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	switch {
+	case s[0] == '"' && s[len(s)-1] == '"':
+		return strings.ReplaceAll(s[1:len(s)-1], `""`, `"`)
+	case s[0] == '`' && s[len(s)-1] == '`':
+		return strings.ReplaceAll(s[1:len(s)-1], "``", "`")
+	case s[0] == '[' && s[len(s)-1] == ']':
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// sqliteTokens splits SQL text into words, quoted strings and identifiers,
+// commas and parenthesized groups. A group is returned as one token
+// including its parentheses, so nested expressions stay intact.
+// Comments are dropped.
+func sqliteTokens(sql string) []string {
+	var toks []string
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return toks
+			}
+			i += end + 4
+		case c == ',':
+			toks = append(toks, ",")
+			i++
+		case c == '(':
+			end := sqliteGroupEnd(sql, i)
+			toks = append(toks, sql[i:end])
+			i = end
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			end := sqliteQuoteEnd(sql, i)
+			toks = append(toks, sql[i:end])
+			i = end
+		default:
+			start := i
+			for i < len(sql) && !strings.ContainsRune(" \t\n\r,()'\"`[", rune(sql[i])) {
+				i++
+			}
+			if i == start {
+				// unbalanced closing parenthesis, skip it
+				i++
+				continue
+			}
+			toks = append(toks, sql[start:i])
+		}
+	}
+	return toks
+}
+
+// sqliteQuoteEnd returns the index just past the quoted string or
+// identifier starting at sql[start].
+func sqliteQuoteEnd(sql string, start int) int {
+	closer := sql[start]
+	if closer == '[' {
+		closer = ']'
+	}
+	for i := start + 1; i < len(sql); i++ {
+		if sql[i] != closer {
+			continue
+		}
+		// doubled quotes are escapes, brackets cannot be escaped
+		if closer != ']' && i+1 < len(sql) && sql[i+1] == closer {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(sql)
+}
+
+// sqliteGroupEnd returns the index just past the parenthesis matching the
+// one at sql[start].
+func sqliteGroupEnd(sql string, start int) int {
+	depth := 0
+	for i := start; i < len(sql); {
+		switch sql[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case '\'', '"', '`', '[':
+			i = sqliteQuoteEnd(sql, i)
+			continue
+		}
+		i++
+	}
+	return len(sql)
+}
+
+// parseSQLiteTrigger returns the timing and the events of a CREATE TRIGGER
+// statement. The timing defaults to BEFORE when the statement omits it.
+func parseSQLiteTrigger(ddl string) (timing string, events []string) {
+	toks := sqliteTokens(ddl)
+	start := len(toks)
+	for i, tok := range toks {
+		if strings.EqualFold(tok, "TRIGGER") {
+			start = i + 1
+			break
+		}
+	}
+	timing = "BEFORE"
+	for _, tok := range toks[start:] {
+		switch kw := strings.ToUpper(tok); kw {
+		case "BEFORE", "AFTER":
+			timing = kw
+		case "INSTEAD":
+			timing = "INSTEAD OF"
+		case "DELETE", "INSERT", "UPDATE":
+			events = append(events, kw)
+		case "ON":
+			return timing, events
+		}
+	}
+	return timing, events
+}
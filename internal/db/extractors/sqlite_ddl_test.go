@@ -0,0 +1,182 @@
+package extractors
+
+import (
+	"reflect"
+	"testing"
+
+	"erddiagram/internal/introspect"
+)
+
+func TestParseSQLiteCreateTable(t *testing.T) {
+	var tests = []struct {
+		name        string
+		ddl         string
+		constraints []introspect.Constraint
+	}{
+		{"no constraints",
+			`CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT NOT NULL)`,
+			nil},
+		{"column constraints",
+			`CREATE TABLE "t" ("id" INTEGER, email TEXT UNIQUE, total REAL CONSTRAINT pos CHECK (total >= 0))`,
+			[]introspect.Constraint{
+				{Type: introspect.ConstraintUnique, Columns: []string{"email"}},
+				{Name: "pos", Type: introspect.ConstraintCheck, Columns: []string{"total"}, Expression: "total >= 0"},
+			}},
+		{"table constraints",
+			`CREATE TABLE [t] (
+				a INT, -- a comment, with a comma
+				[b c] TEXT DEFAULT 'x,(y',
+				CONSTRAINT uq_ab UNIQUE (a, [b c] COLLATE NOCASE),
+				CHECK (length("b c") > (a + 1)),
+				FOREIGN KEY (a) REFERENCES other(id)
+			) WITHOUT ROWID`,
+			[]introspect.Constraint{
+				{Name: "uq_ab", Type: introspect.ConstraintUnique, Columns: []string{"a", "b c"}},
+				{Type: introspect.ConstraintCheck, Expression: `length("b c") > (a + 1)`},
+			}},
+		{"empty", ``, nil},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			def := parseSQLiteCreateTable(tt.ddl)
+			if !reflect.DeepEqual(def.Constraints, tt.constraints) {
+				t.Errorf("\ngot constraints %+v, wanted %+v", def.Constraints, tt.constraints)
+			}
+		})
+	}
+}
+
+func TestParseSQLiteForeignKeys(t *testing.T) {
+	var tests = []struct {
+		name string
+		ddl  string
+		fks  []sqliteForeignKeyDef
+	}{
+		{"column and table keys",
+			`CREATE TABLE t (
+				a INT REFERENCES "p" DEFERRABLE INITIALLY DEFERRED NOT NULL,
+				b INT, c INT,
+				CONSTRAINT fk_bc FOREIGN KEY (b, [c]) REFERENCES q(x, y) ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE
+			)`,
+			[]sqliteForeignKeyDef{
+				{From: []string{"a"}, Table: "p", Deferrable: true, InitiallyDeferred: true},
+				{From: []string{"b", "c"}, Table: "q", Deferrable: true},
+			}},
+		{"not deferrable",
+			`CREATE TABLE t (a INT REFERENCES p(id) NOT DEFERRABLE INITIALLY DEFERRED)`,
+			[]sqliteForeignKeyDef{{From: []string{"a"}, Table: "p"}}},
+		{"none", `CREATE TABLE t (a INT NOT NULL)`, nil},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			def := parseSQLiteCreateTable(tt.ddl)
+			if !reflect.DeepEqual(def.ForeignKeys, tt.fks) {
+				t.Errorf("\ngot foreign keys %+v, wanted %+v", def.ForeignKeys, tt.fks)
+			}
+		})
+	}
+}
+
+func TestSQLitePartialPredicate(t *testing.T) {
+	var tests = []struct {
+		ddl       string
+		predicate string
+	}{
+		{`CREATE INDEX ix ON t(a)`, ""},
+		{`CREATE UNIQUE INDEX ux ON t(a, lower(b)) WHERE a IS NOT NULL`, "a IS NOT NULL"},
+		{``, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ddl, func(t *testing.T) {
+			if p := sqlitePartialPredicate(tt.ddl); p != tt.predicate {
+				t.Errorf("\ngot predicate %q, wanted %q", p, tt.predicate)
+			}
+		})
+	}
+}
+
+func TestParseSQLiteColumns(t *testing.T) {
+	ddl := `CREATE TABLE t (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT COLLATE "NOCASE" NOT NULL,
+		total REAL,
+		doubled REAL GENERATED ALWAYS AS (total * 2) STORED,
+		tripled REAL AS (total * 3)
+	)`
+	want := map[string]sqliteColumnDef{
+		"id":      {Autoincrement: true},
+		"name":    {Collation: "NOCASE"},
+		"total":   {},
+		"doubled": {Generated: "total * 2"},
+		"tripled": {Generated: "total * 3"},
+	}
+
+	def := parseSQLiteCreateTable(ddl)
+	if !reflect.DeepEqual(def.Columns, want) {
+		t.Errorf("\ngot columns %+v, wanted %+v", def.Columns, want)
+	}
+}
+
+func TestSQLiteTypeSize(t *testing.T) {
+	var tests = []struct {
+		ctype     string
+		length    int64 // -1 == none
+		precision int64 // -1 == none
+		scale     int64 // -1 == none
+	}{
+		{"INTEGER", -1, -1, -1},
+		{"VARCHAR(20)", 20, -1, -1},
+		{"NVARCHAR(20)", 20, -1, -1},
+		{"VARBINARY(16)", 16, -1, -1},
+		{"DECIMAL(10, 2)", -1, 10, 2},
+		{"DECIMAL(10)", -1, 10, -1},
+		{"FLOAT(24)", -1, 24, -1},
+	}
+
+	is := func(got *int64, want int64) bool {
+		return (got == nil) == (want == -1) && (got == nil || *got == want)
+	}
+	for _, tt := range tests {
+		t.Run(tt.ctype, func(t *testing.T) {
+			length, precision, scale := sqliteTypeSize(tt.ctype)
+			if !is(length, tt.length) {
+				t.Errorf("\ngot length %v, wanted %v", length, tt.length)
+			}
+			if !is(precision, tt.precision) {
+				t.Errorf("\ngot precision %v, wanted %v", precision, tt.precision)
+			}
+			if !is(scale, tt.scale) {
+				t.Errorf("\ngot scale %v, wanted %v", scale, tt.scale)
+			}
+		})
+	}
+}
+
+func TestParseSQLiteTrigger(t *testing.T) {
+	var tests = []struct {
+		ddl    string
+		timing string
+		events []string
+	}{
+		{`CREATE TRIGGER trg AFTER INSERT ON t BEGIN SELECT 1; END`, "AFTER", []string{"INSERT"}},
+		{`CREATE TEMP TRIGGER IF NOT EXISTS "main"."trg" INSTEAD OF UPDATE OF a, b ON v BEGIN DELETE FROM t; END`, "INSTEAD OF", []string{"UPDATE"}},
+		{`create trigger trg delete on t begin insert into log values (1); end`, "BEFORE", []string{"DELETE"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ddl, func(t *testing.T) {
+			timing, events := parseSQLiteTrigger(tt.ddl)
+			if timing != tt.timing {
+				t.Errorf("\ngot timing %q, wanted %q", timing, tt.timing)
+			}
+			if !reflect.DeepEqual(events, tt.events) {
+				t.Errorf("\ngot events %v, wanted %v", events, tt.events)
+			}
+		})
+	}
+}
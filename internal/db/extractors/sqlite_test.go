@@ -0,0 +1,96 @@
+package extractors
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"erddiagram/internal/db"
+	"erddiagram/internal/introspect"
+)
+
+func TestSQLiteExtract(t *testing.T) {
+	dbConn, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "extract.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbConn.Close()
+	if _, err := dbConn.Exec(`
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, email TEXT UNIQUE);
+		CREATE TABLE orders (
+			id INTEGER PRIMARY KEY,
+			customer_id INTEGER REFERENCES customers DEFERRABLE INITIALLY DEFERRED,
+			total REAL
+		);
+		CREATE INDEX orders_open ON orders (customer_id) WHERE total > 0;
+		CREATE VIEW big_orders AS SELECT id, total FROM orders WHERE total > 100;`); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := sqliteExtractor{}.ExtractWithOptions(t.Context(), dbConn, db.Options{})
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	if len(s.Warnings) != 0 {
+		t.Errorf("\ngot warnings %+v, wanted none", s.Warnings)
+	}
+
+	indexes := map[string][]introspect.Index{}
+	for _, tab := range s.Tables {
+		indexes[tab.Name] = tab.Indexes
+	}
+	wantIndexes := map[string][]introspect.Index{
+		"customers": {{Name: "sqlite_autoindex_customers_1", Columns: []string{"email"}, Unique: true}},
+		"orders":    {{Name: "orders_open", Columns: []string{"customer_id"}, Predicate: "total > 0"}},
+	}
+	if !reflect.DeepEqual(indexes, wantIndexes) {
+		t.Errorf("\ngot indexes %+v, wanted %+v", indexes, wantIndexes)
+	}
+
+	if len(s.ForeignKeys) != 1 {
+		t.Fatalf("\ngot foreign keys %+v, wanted one", s.ForeignKeys)
+	}
+	fk := s.ForeignKeys[0]
+	if fk.FromTable != "orders" || fk.ToTable != "customers" || fk.FromColumn != "customer_id" || fk.ToColumn != "id" {
+		t.Errorf("\ngot foreign key %+v, wanted orders.customer_id to customers.id", fk)
+	}
+	if !fk.Deferrable || !fk.InitiallyDeferred {
+		t.Errorf("\ngot deferrable %v, initially deferred %v, wanted both", fk.Deferrable, fk.InitiallyDeferred)
+	}
+
+	if len(s.Views) != 1 {
+		t.Fatalf("\ngot views %+v, wanted one", s.Views)
+	}
+	v := s.Views[0]
+	wantColumns := []introspect.Column{{Name: "id", Type: "INTEGER", Nullable: true}, {Name: "total", Type: "REAL", Nullable: true}}
+	if v.Name != "big_orders" || !reflect.DeepEqual(v.Columns, wantColumns) {
+		t.Errorf("\ngot view %s with columns %+v, wanted big_orders with %+v", v.Name, v.Columns, wantColumns)
+	}
+	if v.Definition == "" {
+		t.Errorf("\ngot no view definition, wanted the CREATE VIEW statement")
+	}
+}
+
+func TestSQLiteExtractSmallPool(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pool.sqlite")
+	dbConn, err := sql.Open("sqlite", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbConn.Exec(`CREATE TABLE a (id INTEGER PRIMARY KEY); CREATE TABLE b (a_id INTEGER REFERENCES a)`); err != nil {
+		t.Fatal(err)
+	}
+	dbConn.Close()
+
+	// every query must free its connection before the next one starts
+	m := db.NewManager(db.PoolConfig{MaxOpen: 1})
+	defer m.Close()
+	s, err := m.Extract("sqlite", file, 3, db.Options{Skip: db.SectionSizes})
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	if len(s.Tables) != 2 || len(s.ForeignKeys) != 1 {
+		t.Errorf("\ngot %d tables and %d foreign keys, wanted 2 and 1", len(s.Tables), len(s.ForeignKeys))
+	}
+}
@@ -0,0 +1,215 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"erddiagram/internal/introspect"
+)
+
+// Filter selects the schemas and tables to extract. Every rule is a glob
+// pattern with * and ?, matched case-insensitively, or a regular expression
+// when it is wrapped in slashes, like /^tenant_[0-9]+$/. Table rules that
+// contain a dot are matched against schema.table, all others against the
+// table name. An object is extracted when it matches any include rule, or
+// there are none, and no exclude rule.
+type Filter struct {
+	IncludeSchemas []string
+	ExcludeSchemas []string
+	IncludeTables  []string
+	ExcludeTables  []string
+}
+
+// IsZero reports whether f has no rules and selects everything.
+func (f Filter) IsZero() bool {
+	return len(f.IncludeSchemas)+len(f.ExcludeSchemas)+len(f.IncludeTables)+len(f.ExcludeTables) == 0
+}
+
+// Validate reports the first rule that is not a valid regular expression.
+func (f Filter) Validate() error {
+	_, err := f.compile()
+	return err
+}
+
+// filterRule is a compiled include or exclude rule.
+type filterRule struct {
+	re        *regexp.Regexp
+	glob      string // original glob, empty for regular expressions
+	qualified bool   // matched against schema.table
+}
+
+type compiledFilter struct {
+	includeSchemas, excludeSchemas []filterRule
+	includeTables, excludeTables   []filterRule
+}
+
+func (f Filter) compile() (compiledFilter, error) {
+	var c compiledFilter
+	var err error
+	for _, r := range []struct {
+		rules  []string
+		out    *[]filterRule
+		tables bool
+	}{
+		{f.IncludeSchemas, &c.includeSchemas, false},
+		{f.ExcludeSchemas, &c.excludeSchemas, false},
+		{f.IncludeTables, &c.includeTables, true},
+		{f.ExcludeTables, &c.excludeTables, true},
+	} {
+		for _, rule := range r.rules {
+			var fr filterRule
+			if len(rule) >= 2 && strings.HasPrefix(rule, "/") && strings.HasSuffix(rule, "/") {
+				if fr.re, err = regexp.Compile(rule[1 : len(rule)-1]); err != nil {
+					return c, fmt.Errorf("filter rule %q: %w", rule, err)
+				}
+			} else {
+				fr.glob = rule
+				fr.re = regexp.MustCompile("(?i)^" + strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(rule)) + "$")
+			}
+			fr.qualified = r.tables && strings.Contains(rule, ".")
+			*r.out = append(*r.out, fr)
+		}
+	}
+	return c, nil
+}
+
+func (r filterRule) match(schema, name string) bool {
+	if r.qualified {
+		return r.re.MatchString(schema + "." + name)
+	}
+	return r.re.MatchString(name)
+}
+
+func matchAny(rules []filterRule, schema, name string) bool {
+	for _, r := range rules {
+		if r.match(schema, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// schema reports whether objects in schema are selected.
+func (c compiledFilter) schema(schema string) bool {
+	return (len(c.includeSchemas) == 0 || matchAny(c.includeSchemas, "", schema)) &&
+		!matchAny(c.excludeSchemas, "", schema)
+}
+
+// table reports whether table schema.name is selected.
+func (c compiledFilter) table(schema, name string) bool {
+	return c.schema(schema) &&
+		(len(c.includeTables) == 0 || matchAny(c.includeTables, schema, name)) &&
+		!matchAny(c.excludeTables, schema, name)
+}
+
+// Match reports whether table schema.name is selected by f.
+// Invalid regular expressions never match.
+func (f Filter) Match(schema, table string) bool {
+	c, err := f.compile()
+	return err == nil && c.table(schema, table)
+}
+
+// SQL returns an " AND ..." condition that restricts the schema and table
+// name columns of a catalog query to f, or "" when there is nothing to
+// restrict. Only glob rules are translated to LIKE; regular expressions
+// differ between databases and are applied by Prune after the extraction,
+// so the condition may select more than f but never less. schemaCol may be
+// empty for dialects without schemas.
+func (f Filter) SQL(dialect, schemaCol, tableCol string) string {
+	c, err := f.compile()
+	if err != nil {
+		return ""
+	}
+	var conds []string
+	add := func(col string, include, exclude []filterRule) {
+		if col == "" {
+			return
+		}
+		var likes []string
+		for _, r := range include {
+			if r.glob == "" || r.qualified {
+				// a single rule SQL cannot express makes the includes unusable
+				likes = nil
+				break
+			}
+			likes = append(likes, likeSQL(dialect, col, r.glob))
+		}
+		if len(likes) > 0 {
+			conds = append(conds, "("+strings.Join(likes, " OR ")+")")
+		}
+		for _, r := range exclude {
+			if r.glob != "" && !r.qualified {
+				conds = append(conds, "NOT "+likeSQL(dialect, col, r.glob))
+			}
+		}
+	}
+	add(schemaCol, c.includeSchemas, c.excludeSchemas)
+	add(tableCol, c.includeTables, c.excludeTables)
+	if len(conds) == 0 {
+		return ""
+	}
+	return " AND " + strings.Join(conds, " AND ")
+}
+
+// likeSQL returns a case-insensitive LIKE condition on col for glob, with
+// the pattern as an escaped string literal.
+func likeSQL(dialect, col, glob string) string {
+	pattern := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_", "*", "%", "?", "_").Replace(strings.ToLower(glob))
+	if dialect == "sqlserver" {
+		// [ starts a character class in SQL Server patterns
+		pattern = strings.ReplaceAll(pattern, "[", "![")
+	}
+	pattern = strings.ReplaceAll(pattern, "'", "''")
+	if dialect == "mysql" {
+		pattern = strings.ReplaceAll(pattern, `\`, `\\`)
+	}
+	return fmt.Sprintf("LOWER(%s) LIKE '%s' ESCAPE '!'", col, pattern)
+}
+
+// Prune removes everything f does not select from s: tables, views and
+// their foreign keys and triggers by schema and name, and other objects by
+// schema. References of the remaining objects to removed tables and views
+// are dropped too.
+func (f Filter) Prune(s *introspect.Schema) {
+	c, err := f.compile()
+	if err != nil || f.IsZero() {
+		return
+	}
+	s.Tables = keep(s.Tables, func(t introspect.Table) bool { return c.table(t.Schema, t.Name) })
+	s.Views = keep(s.Views, func(v introspect.View) bool { return c.table(v.Schema, v.Name) })
+	s.ForeignKeys = keep(s.ForeignKeys, func(fk introspect.ForeignKey) bool {
+		return c.table(fk.FromSchema, fk.FromTable) && c.table(fk.ToSchema, fk.ToTable)
+	})
+	for i := range s.Groups {
+		s.Groups[i].Tables = keep(s.Groups[i].Tables, func(t introspect.ObjectRef) bool { return c.table(t.Schema, t.Name) })
+	}
+	s.Triggers = keep(s.Triggers, func(tr introspect.Trigger) bool { return c.table(tr.Table.Schema, tr.Table.Name) })
+	s.Types = keep(s.Types, func(ut introspect.UserType) bool { return c.schema(ut.Schema) })
+	s.Sequences = keep(s.Sequences, func(seq introspect.Sequence) bool { return c.schema(seq.Schema) })
+	s.Routines = keep(s.Routines, func(r introspect.Routine) bool { return c.schema(r.Schema) })
+
+	selected := func(ref introspect.ObjectRef) bool { return c.table(ref.Schema, ref.Name) }
+	for i := range s.Views {
+		s.Views[i].DependsOn = keep(s.Views[i].DependsOn, selected)
+	}
+	for i := range s.Routines {
+		s.Routines[i].References = keep(s.Routines[i].References, selected)
+	}
+	for i, seq := range s.Sequences {
+		if seq.OwnedBy != nil && !c.table(seq.OwnedBy.Schema, seq.OwnedBy.Table) {
+			s.Sequences[i].OwnedBy = nil
+		}
+	}
+}
+
+// keep returns the elements of list for which ok is true, reusing list.
+func keep[T any](list []T, ok func(T) bool) []T {
+	kept := list[:0]
+	for _, v := range list {
+		if ok(v) {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
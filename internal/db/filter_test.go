@@ -0,0 +1,126 @@
+package db
+
+import (
+	"testing"
+
+	"erddiagram/internal/introspect"
+)
+
+func TestFilterMatch(t *testing.T) {
+	f := Filter{
+		IncludeSchemas: []string{"sales", "/^tenant_[0-9]+$/"},
+		ExcludeTables:  []string{"tmp_*", "sales.audit?"},
+	}
+
+	var tests = []struct {
+		schema string
+		table  string
+		match  bool
+	}{
+		{"sales", "orders", true},
+		{"SALES", "orders", true},
+		{"tenant_42", "orders", true},
+		{"tenant_x", "orders", false},
+		{"hr", "orders", false},
+		{"sales", "TMP_load", false},
+		{"sales", "audit1", false},
+		{"tenant_1", "audit1", true},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.schema+"."+tt.table, func(t *testing.T) {
+			if m := f.Match(tt.schema, tt.table); m != tt.match {
+				t.Errorf("\ngot match %v, wanted %v", m, tt.match)
+			}
+		})
+	}
+}
+
+func TestFilterSQL(t *testing.T) {
+	var tests = []struct {
+		name    string
+		filter  Filter
+		dialect string
+		sql     string
+	}{
+		{"empty", Filter{}, "postgres", ""},
+		{"globs",
+			Filter{IncludeSchemas: []string{"sales*", "hr"}, ExcludeTables: []string{"tmp_?"}},
+			"postgres",
+			` AND (LOWER(s) LIKE 'sales%' ESCAPE '!' OR LOWER(s) LIKE 'hr' ESCAPE '!') AND NOT LOWER(t) LIKE 'tmp!__' ESCAPE '!'`},
+		{"regex include is left to Prune",
+			Filter{IncludeSchemas: []string{"sales", "/^hr$/"}, ExcludeSchemas: []string{"x"}},
+			"postgres",
+			` AND NOT LOWER(s) LIKE 'x' ESCAPE '!'`},
+		{"quotes are escaped",
+			Filter{ExcludeTables: []string{`it's\`}},
+			"mysql",
+			` AND NOT LOWER(t) LIKE 'it''s\\' ESCAPE '!'`},
+		{"brackets are escaped for sql server",
+			Filter{ExcludeTables: []string{"[tmp]*"}},
+			"sqlserver",
+			` AND NOT LOWER(t) LIKE '![tmp]%' ESCAPE '!'`},
+		{"brackets are plain elsewhere",
+			Filter{ExcludeTables: []string{"[tmp]*"}},
+			"postgres",
+			` AND NOT LOWER(t) LIKE '[tmp]%' ESCAPE '!'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if sql := tt.filter.SQL(tt.dialect, "s", "t"); sql != tt.sql {
+				t.Errorf("\ngot %q, wanted %q", sql, tt.sql)
+			}
+		})
+	}
+}
+
+func TestFilterPrune(t *testing.T) {
+	s := introspect.Schema{
+		Tables: []introspect.Table{{Schema: "a", Name: "keep"}, {Schema: "a", Name: "tmp_x"}, {Schema: "b", Name: "keep"}},
+		ForeignKeys: []introspect.ForeignKey{
+			{FromSchema: "a", FromTable: "keep", ToSchema: "a", ToTable: "keep"},
+			{FromSchema: "a", FromTable: "keep", ToSchema: "a", ToTable: "tmp_x"},
+		},
+		Views: []introspect.View{{Schema: "a", Name: "v", DependsOn: []introspect.ObjectRef{{Schema: "a", Name: "keep"}, {Schema: "a", Name: "tmp_x"}}}},
+		Sequences: []introspect.Sequence{
+			{Schema: "a", Name: "s1", OwnedBy: &introspect.ColumnRef{Schema: "a", Table: "tmp_x", Column: "id"}},
+			{Schema: "b", Name: "s2"},
+		},
+		Routines: []introspect.Routine{{Schema: "a", Name: "f", References: []introspect.ObjectRef{{Schema: "b", Name: "keep"}, {Schema: "a", Name: "v"}}}},
+		Groups:   []introspect.TableGroup{{Name: "g", Tables: []introspect.ObjectRef{{Schema: "a", Name: "keep"}, {Schema: "b", Name: "keep"}}}},
+	}
+	Filter{IncludeSchemas: []string{"a"}, ExcludeTables: []string{"/^tmp_/"}}.Prune(&s)
+
+	if len(s.Tables) != 1 || s.Tables[0].Name != "keep" || s.Tables[0].Schema != "a" {
+		t.Errorf("\ngot tables %+v, wanted only a.keep", s.Tables)
+	}
+	if len(s.ForeignKeys) != 1 {
+		t.Errorf("\ngot foreign keys %+v, wanted only a.keep -> a.keep", s.ForeignKeys)
+	}
+	if len(s.Sequences) != 1 || s.Sequences[0].Schema != "a" {
+		t.Errorf("\ngot sequences %+v, wanted only those of schema a", s.Sequences)
+	}
+	if len(s.Groups[0].Tables) != 1 || s.Groups[0].Tables[0].Schema != "a" {
+		t.Errorf("\ngot group tables %+v, wanted only a.keep", s.Groups[0].Tables)
+	}
+	if deps := s.Views[0].DependsOn; len(deps) != 1 || deps[0].Name != "keep" {
+		t.Errorf("\ngot view dependencies %+v, wanted only a.keep", deps)
+	}
+	if refs := s.Routines[0].References; len(refs) != 1 || refs[0].Name != "v" {
+		t.Errorf("\ngot routine references %+v, wanted only a.v", refs)
+	}
+	if s.Sequences[0].OwnedBy != nil {
+		t.Errorf("\ngot sequence owned by %+v, wanted no owner for a removed table", *s.Sequences[0].OwnedBy)
+	}
+}
+
+func TestFilterValidate(t *testing.T) {
+	if err := (Filter{IncludeTables: []string{"/[/"}}).Validate(); err == nil {
+		t.Errorf("\ngot no error for an invalid regular expression")
+	}
+	if err := (Filter{IncludeTables: []string{"[a]*"}}).Validate(); err != nil {
+		t.Errorf("\ngot error %v for a glob", err)
+	}
+}
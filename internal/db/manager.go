@@ -0,0 +1,225 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"erddiagram/internal/introspect"
+	"erddiagram/internal/logger"
+	"erddiagram/pkg/config"
+)
+
+// PoolConfig bounds the connections of each pool a Manager keeps.
+// Zero values keep the database/sql defaults.
+type PoolConfig struct {
+	MaxOpen     int           // open connections, 0 for unlimited
+	MaxIdle     int           // idle connections kept for reuse
+	MaxLifetime time.Duration // age after which a connection is replaced
+	MaxIdleTime time.Duration // idle time after which a connection is closed
+	Concurrency int           // Options.Concurrency of the extractions, see NewManager
+}
+
+// ErrManagerClosed is returned by a Manager after Close.
+var ErrManagerClosed = errors.New("connection manager closed")
+
+// Manager keeps one *sql.DB pool per connection, so that repeated
+// extractions reuse their connections instead of opening new ones. A pool
+// is kept while the connection is held, see Acquire; the pool of a
+// connection nobody holds is closed when the call that opened it returns.
+// It is safe for concurrent use.
+type Manager struct {
+	pool PoolConfig
+
+	mu     sync.Mutex
+	pools  map[poolKey]*entry
+	closed bool
+}
+
+type poolKey struct {
+	driver, dsn string
+}
+
+// entry is the pool of a connection, opened on first use.
+type entry struct {
+	db    *sql.DB
+	holds int // Acquire calls not yet released
+	busy  int // calls running on db
+}
+
+// NewManager returns a Manager that configures its pools with pool. An
+// extraction uses up to max(2, pool.Concurrency) connections at once, so a
+// lower MaxOpen is raised to that rather than letting extractions wait for
+// a connection until they time out.
+func NewManager(pool PoolConfig) *Manager {
+	if least := max(2, pool.Concurrency); pool.MaxOpen > 0 && pool.MaxOpen < least {
+		logger.Warn("pool max_open %d is below the %d connections an extraction uses, using %d", pool.MaxOpen, least, least)
+		pool.MaxOpen = least
+	}
+	return &Manager{pool: pool, pools: map[poolKey]*entry{}}
+}
+
+// Acquire holds the pool for driver and dsn, so that it stays open between
+// calls until every Acquire is matched by a Release. The pool itself is
+// opened on first use.
+func (m *Manager) Acquire(driver, dsn string) {
+	key := poolKey{config.NormalizeDriver(driver), dsn}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	p, ok := m.pools[key]
+	if !ok {
+		p = &entry{}
+		m.pools[key] = p
+	}
+	p.holds++
+}
+
+// Release undoes an Acquire of the pool for driver and dsn. Once it is not
+// held anymore, the pool is closed, or when the calls still running on it
+// return.
+func (m *Manager) Release(driver, dsn string) error {
+	key := poolKey{config.NormalizeDriver(driver), dsn}
+	m.mu.Lock()
+	p, ok := m.pools[key]
+	if !ok {
+		m.mu.Unlock()
+		return nil
+	}
+	p.holds--
+	dbConn := m.drop(key, p)
+	m.mu.Unlock()
+	if dbConn == nil {
+		return nil
+	}
+	return dbConn.Close()
+}
+
+// drop removes the pool p of key if it is neither held nor busy, and
+// returns its *sql.DB to close, if it was opened. m.mu must be held.
+func (m *Manager) drop(key poolKey, p *entry) *sql.DB {
+	if p.holds > 0 || p.busy > 0 {
+		return nil
+	}
+	delete(m.pools, key)
+	return p.db
+}
+
+// use returns the pool for driver and dsn, opening it if necessary, for a
+// call that must call done when it no longer uses it.
+func (m *Manager) use(driver, dsn string) (dbConn *sql.DB, done func(), err error) {
+	key := poolKey{config.NormalizeDriver(driver), dsn}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, nil, ErrManagerClosed
+	}
+	p, ok := m.pools[key]
+	if !ok {
+		p = &entry{}
+		m.pools[key] = p
+	}
+	if p.db == nil {
+		if p.db, err = m.open(key); err != nil {
+			m.drop(key, p)
+			return nil, nil, err
+		}
+	}
+	p.busy++
+	done = func() {
+		m.mu.Lock()
+		p.busy--
+		var dbConn *sql.DB
+		if m.pools[key] == p {
+			dbConn = m.drop(key, p)
+		}
+		m.mu.Unlock()
+		if dbConn != nil {
+			dbConn.Close()
+		}
+	}
+	return p.db, done, nil
+}
+
+// open opens a pool for key configured with m.pool.
+func (m *Manager) open(key poolKey) (*sql.DB, error) {
+	dbConn, err := sql.Open(key.driver, key.dsn)
+	if err != nil {
+		return nil, err
+	}
+	if m.pool.MaxOpen > 0 {
+		dbConn.SetMaxOpenConns(m.pool.MaxOpen)
+	}
+	if m.pool.MaxIdle > 0 {
+		dbConn.SetMaxIdleConns(m.pool.MaxIdle)
+	}
+	if m.pool.MaxLifetime > 0 {
+		dbConn.SetConnMaxLifetime(m.pool.MaxLifetime)
+	}
+	if m.pool.MaxIdleTime > 0 {
+		dbConn.SetConnMaxIdleTime(m.pool.MaxIdleTime)
+	}
+	return dbConn, nil
+}
+
+// Close closes all pools. Later calls of Extract fail.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	pools := m.pools
+	m.pools = map[poolKey]*entry{}
+	m.closed = true
+	m.mu.Unlock()
+	var errs []error
+	for _, p := range pools {
+		if p.db != nil {
+			errs = append(errs, p.db.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Extract is ConnectAndExtractWithOptions on the pool for driver and dsn.
+// A held pool stays open for later extractions.
+func (m *Manager) Extract(driver, dsn string, timeoutSec int, opts Options) (introspect.Schema, error) {
+	driver = config.NormalizeDriver(driver)
+	if err := checkExtract(driver, opts); err != nil {
+		return introspect.Schema{}, err
+	}
+	if src, ok := sources[driver]; ok {
+		return load(src, dsn, timeoutSec, opts)
+	}
+	dbConn, done, err := m.use(driver, dsn)
+	if err != nil {
+		return introspect.Schema{}, err
+	}
+	defer done()
+	return extract(dbConn, driver, timeoutSec, opts)
+}
+
+// SchemaVersion runs the change probe of the dialect of driver on the pool
+// for driver and dsn, or asks the source of driver for the version of dsn.
+// ok is false if the dialect has no probe.
+func (m *Manager) SchemaVersion(driver, dsn string, timeoutSec int) (version string, ok bool, err error) {
+	driver = config.NormalizeDriver(driver)
+	if src, ok := sources[driver]; ok {
+		version, err = src.Version(dsn)
+		return version, true, err
+	}
+	prober, ok := dialects[driver].(ChangeProber)
+	if !ok {
+		return "", false, nil
+	}
+	dbConn, done, err := m.use(driver, dsn)
+	if err != nil {
+		return "", true, err
+	}
+	defer done()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+	version, err = prober.SchemaVersion(ctx, dbConn)
+	return version, true, err
+}
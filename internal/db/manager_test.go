@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"erddiagram/internal/introspect"
+)
+
+func TestManagerAcquire(t *testing.T) {
+	m := NewManager(PoolConfig{MaxOpen: 2, MaxIdle: 2})
+	m.Acquire("sqlite", ":memory:")
+
+	a, done, err := m.use("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	done()
+	if b, done, _ := m.use("sqlite", ":memory:"); b != a {
+		t.Errorf("\ngot a new pool for a held connection, wanted the open one")
+	} else {
+		done()
+	}
+	if n := a.Stats().MaxOpenConnections; n != 2 {
+		t.Errorf("\ngot %d max open connections, wanted 2", n)
+	}
+
+	// a connection nobody holds gets a pool for the call only
+	c, done, _ := m.use("sqlite", "file:other?mode=memory")
+	if c == a {
+		t.Errorf("\ngot the same pool for another dsn, wanted a new one")
+	}
+	done()
+	if err := c.Ping(); err == nil {
+		t.Errorf("\nexpected the pool of a connection nobody holds to be closed")
+	}
+
+	// a pool released while in use is closed when the call returns
+	a, done, _ = m.use("sqlite3", ":memory:")
+	if err := m.Release("sqlite", ":memory:"); err != nil {
+		t.Errorf("\ngot unexpected error: \"%v\"", err)
+	}
+	if err := a.Ping(); err != nil {
+		t.Errorf("\ngot error %v from the released pool in use, wanted it open", err)
+	}
+	done()
+	if err := a.Ping(); err == nil {
+		t.Errorf("\nexpected the released pool to be closed")
+	}
+	if b, done, _ := m.use("sqlite", ":memory:"); b == a {
+		t.Errorf("\ngot the released pool, wanted a new one")
+	} else {
+		done()
+	}
+
+	if err := m.Close(); err != nil {
+		t.Errorf("\ngot unexpected error: \"%v\"", err)
+	}
+	if _, _, err := m.use("sqlite", ":memory:"); !errors.Is(err, ErrManagerClosed) {
+		t.Errorf("\ngot error %v after Close, wanted %v", err, ErrManagerClosed)
+	}
+}
+
+func TestManagerMaxOpen(t *testing.T) {
+	var tests = []struct {
+		name string
+		pool PoolConfig
+		want int
+	}{
+		{"unlimited", PoolConfig{}, 0},
+		{"raised to 2", PoolConfig{MaxOpen: 1}, 2},
+		{"raised to concurrency", PoolConfig{MaxOpen: 2, Concurrency: 4}, 4},
+		{"kept", PoolConfig{MaxOpen: 8, Concurrency: 4}, 8},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(tt.pool)
+			defer m.Close()
+			dbConn, done, err := m.use("sqlite", ":memory:")
+			if err != nil {
+				t.Fatalf("\ngot unexpected error: \"%v\"", err)
+			}
+			defer done()
+			if n := dbConn.Stats().MaxOpenConnections; n != tt.want {
+				t.Errorf("\ngot %d max open connections, wanted %d", n, tt.want)
+			}
+		})
+	}
+}
+
+// poolExtractor records the pools it extracts from.
+type poolExtractor struct {
+	pools *[]*sql.DB
+}
+
+func (e poolExtractor) ExtractWithOptions(ctx context.Context, dbConn *sql.DB, opts Options) (introspect.Schema, error) {
+	*e.pools = append(*e.pools, dbConn)
+	return introspect.Schema{Tables: []introspect.Table{{Name: "t"}}}, nil
+}
+
+func (poolExtractor) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+func TestManagerExtract(t *testing.T) {
+	var pools []*sql.DB
+	RegisterOptionsExtractor("sqlite", poolExtractor{&pools})
+	defer delete(dialects, "sqlite")
+	m := NewManager(PoolConfig{})
+	defer m.Close()
+	m.Acquire("sqlite", ":memory:")
+
+	for range 2 {
+		s, err := m.Extract("sqlite", ":memory:", 10, Options{})
+		if err != nil {
+			t.Fatalf("\ngot unexpected error: \"%v\"", err)
+		}
+		if len(s.Tables) != 1 {
+			t.Errorf("\ngot %d tables, wanted 1", len(s.Tables))
+		}
+	}
+	if len(pools) != 2 || pools[0] != pools[1] {
+		t.Errorf("\ngot pools %v, wanted the same pool twice", pools)
+	}
+
+	if _, err := m.Extract("nosuchdialect", "", 10, Options{}); err == nil {
+		t.Errorf("\nexpected an error, did not receive one")
+	}
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"time"
+)
+
+// DefaultRowCountTimeout bounds each COUNT(*) query when Options.RowCountTimeout is not set.
+const DefaultRowCountTimeout = 5 * time.Second
+
+// Options controls optional, potentially expensive parts of the extraction.
+type Options struct {
+	// ExactRowCounts replaces the catalog row estimates with COUNT(*) results.
+	ExactRowCounts bool
+	// RowCountTimeout bounds the COUNT(*) query of each table, within the
+	// timeout of the extraction.
+	RowCountTimeout time.Duration
+	// Concurrency is the number of tables extracted or counted in parallel
+	// where a dialect needs per-table queries. Values below 2 run sequentially.
+	Concurrency int
+	// Filter selects the schemas and tables to extract.
+	Filter Filter
+	// Skip lists the optional sections the caller does not need. Extractors
+	// leave out the queries for them, but may still return details that
+	// come with the tables at no extra cost.
+	Skip Section
+}
+
+// Wants reports whether the caller needs section s.
+func (o Options) Wants(s Section) bool {
+	return o.Skip&s == 0
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// ForEach calls fn for the indexes 0 to n-1 from at most workers goroutines
+// and returns the first error. After a failure, or once ctx is done, no
+// further calls are started. fn should only write to state that belongs to
+// its index, so that the result does not depend on the scheduling.
+func ForEach(ctx context.Context, n, workers int, fn func(ctx context.Context, i int) error) error {
+	workers = max(1, min(workers, n))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var next atomic.Int64
+	var once sync.Once
+	var firstErr error
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= n || ctx.Err() != nil {
+					return
+				}
+				if err := fn(ctx, i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEach(t *testing.T) {
+	var tests = []struct {
+		name    string
+		n       int
+		workers int
+		failAt  int // -1 == never
+	}{
+		{"sequential", 10, 1, -1},
+		{"more workers than items", 3, 8, -1},
+		{"bounded", 100, 4, -1},
+		{"no items", 0, 4, -1},
+		{"zero workers", 5, 0, -1},
+		{"failure", 100, 4, 10},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			out := make([]int, tt.n)
+			var running, peak atomic.Int64
+			err := ForEach(context.Background(), tt.n, tt.workers, func(ctx context.Context, i int) error {
+				r := running.Add(1)
+				defer running.Add(-1)
+				for p := peak.Load(); r > p && !peak.CompareAndSwap(p, r); p = peak.Load() {
+				}
+				if i == tt.failAt {
+					return errors.New("failed")
+				}
+				out[i] = i * i
+				return nil
+			})
+
+			if (err != nil) != (tt.failAt >= 0) {
+				t.Errorf("\ngot error %v, wanted failure %v", err, tt.failAt >= 0)
+			}
+			if p := peak.Load(); p > int64(max(1, tt.workers)) {
+				t.Errorf("\ngot %d concurrent calls, wanted at most %d", p, tt.workers)
+			}
+			if tt.failAt >= 0 {
+				return
+			}
+			for i, v := range out {
+				if v != i*i {
+					t.Errorf("\ngot out[%d] = %d, wanted %d", i, v, i*i)
+				}
+			}
+		})
+	}
+}
+
+func TestForEachCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var calls atomic.Int64
+	err := ForEach(ctx, 10, 2, func(ctx context.Context, i int) error {
+		calls.Add(1)
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || calls.Load() != 0 {
+		t.Errorf("\ngot error %v after %d calls, wanted %v after 0 calls", err, calls.Load(), context.Canceled)
+	}
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"erddiagram/internal/introspect"
+	"erddiagram/internal/logger"
+)
+
+// countRows sets Table.Rows to the exact row count of every table in s,
+// counting up to concurrency tables in parallel. Each COUNT(*) runs with its
+// own timeout within ctx; tables that cannot be counted in time keep their
+// catalog estimate and get a warning. Once ctx is done, the remaining tables
+// are not counted and get a single warning.
+func countRows(ctx context.Context, dbConn *sql.DB, driver string, s *introspect.Schema, timeout time.Duration, concurrency int) {
+	if timeout <= 0 {
+		timeout = DefaultRowCountTimeout
+	}
+	errs := make([]error, len(s.Tables))
+	// failures are recorded in errs, so ForEach only stops early when ctx is done
+	_ = ForEach(ctx, len(s.Tables), concurrency, func(ctx context.Context, i int) error {
+		t := &s.Tables[i]
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		var n int64
+		if errs[i] = dbConn.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+qualifiedName(driver, t.Schema, t.Name)).Scan(&n); errs[i] == nil {
+			t.Rows = n
+		}
+		return nil
+	})
+	if err := ctx.Err(); err != nil {
+		logger.Warn("count rows: %v", err)
+		s.AddWarning("", "row count", err)
+		return
+	}
+	for i, err := range errs {
+		if err != nil {
+			t := s.Tables[i]
+			logger.Warn("count rows of %s.%s: %v", t.Schema, t.Name, err)
+			s.AddWarning(strings.TrimPrefix(t.Schema+"."+t.Name, "."), "row count", err)
+		}
+	}
+}
+
+// qualifiedName returns the quoted schema.name of a table for driver.
+func qualifiedName(driver, schema, name string) string {
+	if schema == "" {
+		return quoteIdent(driver, name)
+	}
+	return quoteIdent(driver, schema) + "." + quoteIdent(driver, name)
+}
+
+// quoteIdent quotes an identifier in the syntax of driver.
+func quoteIdent(driver, ident string) string {
+	switch driver {
+	case "mysql":
+		return "`" + strings.ReplaceAll(ident, "`", "``") + "`"
+	case "sqlserver":
+		return "[" + strings.ReplaceAll(ident, "]", "]]") + "]"
+	default:
+		return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+	}
+}
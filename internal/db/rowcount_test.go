@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"erddiagram/internal/introspect"
+)
+
+func TestQualifiedName(t *testing.T) {
+	var tests = []struct {
+		driver string
+		schema string
+		name   string
+		want   string
+	}{
+		{"postgres", "public", "order", `"public"."order"`},
+		{"sqlite", "", `we"ird`, `"we""ird"`},
+		{"mysql", "shop", "order`s", "`shop`.`order``s`"},
+		{"sqlserver", "dbo", "order]s", "[dbo].[order]]s]"},
+		{"godror", "SHOP", "ORDERS", `"SHOP"."ORDERS"`},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.driver, func(t *testing.T) {
+			if got := qualifiedName(tt.driver, tt.schema, tt.name); got != tt.want {
+				t.Errorf("\ngot %v, wanted %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountRows(t *testing.T) {
+	dbConn, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "count.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbConn.Close()
+	if _, err := dbConn.Exec(`CREATE TABLE items (id INTEGER); INSERT INTO items VALUES (1), (2), (3)`); err != nil {
+		t.Fatal(err)
+	}
+
+	s := introspect.Schema{Tables: []introspect.Table{
+		{Name: "items", Rows: 1},
+		{Name: "missing", Rows: 42},
+	}}
+	countRows(t.Context(), dbConn, "sqlite", &s, 0, 2)
+
+	if s.Tables[0].Rows != 3 {
+		t.Errorf("\ngot %d rows for items, wanted 3", s.Tables[0].Rows)
+	}
+	if s.Tables[1].Rows != 42 {
+		t.Errorf("\ngot %d rows for missing table, wanted the estimate to be kept", s.Tables[1].Rows)
+	}
+	if len(s.Warnings) != 1 || s.Warnings[0].Object != "missing" {
+		t.Errorf("\ngot warnings %+v, wanted one for the missing table", s.Warnings)
+	}
+}
+
+func TestCountRowsCancelled(t *testing.T) {
+	dbConn, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "count.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbConn.Close()
+	if _, err := dbConn.Exec(`CREATE TABLE items (id INTEGER); INSERT INTO items VALUES (1), (2), (3)`); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	s := introspect.Schema{Tables: []introspect.Table{{Name: "items", Rows: 1}, {Name: "items", Rows: 1}}}
+	countRows(ctx, dbConn, "sqlite", &s, 0, 1)
+
+	for _, tab := range s.Tables {
+		if tab.Rows != 1 {
+			t.Errorf("\ngot %d rows after the context was cancelled, wanted the estimate to be kept", tab.Rows)
+		}
+	}
+	if len(s.Warnings) != 1 || s.Warnings[0].Object != "" {
+		t.Errorf("\ngot warnings %+v, wanted one for the cancelled counting", s.Warnings)
+	}
+}
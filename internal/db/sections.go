@@ -0,0 +1,84 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Section is a set of optional parts of a schema that an extractor can
+// leave out. Tables, columns, primary and foreign keys are always extracted.
+type Section uint
+
+const (
+	SectionComments Section = 1 << iota
+	SectionSizes
+	SectionPartitions
+	SectionIndexes
+	SectionConstraints
+	SectionViews
+	SectionTypes
+	SectionSequences
+	SectionTriggers
+	SectionRoutines
+
+	// AllSections is the set of all optional sections.
+	AllSections = SectionRoutines<<1 - 1
+)
+
+// sectionNames are the names of the sections in bit order.
+var sectionNames = []string{
+	"comments", "sizes", "partitions", "indexes", "constraints",
+	"views", "types", "sequences", "triggers", "routines",
+}
+
+// ParseSections returns the set of the named sections, the names are
+// matched case-insensitively.
+func ParseSections(names []string) (Section, error) {
+	var s Section
+	for _, name := range names {
+		i := 0
+		for i < len(sectionNames) && !strings.EqualFold(sectionNames[i], strings.TrimSpace(name)) {
+			i++
+		}
+		if i == len(sectionNames) {
+			return 0, fmt.Errorf("unknown section %q (available: %s)", name, AllSections)
+		}
+		s |= 1 << i
+	}
+	return s, nil
+}
+
+// String returns the names of the sections in s separated by commas.
+func (s Section) String() string {
+	var names []string
+	for i, name := range sectionNames {
+		if s&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Capabilities describes what an extractor supports.
+type Capabilities struct {
+	// Sections are the optional sections the extractor can return.
+	Sections Section
+	// Filter reports whether the extractor pushes Options.Filter into its
+	// catalog queries. The connector prunes the result either way.
+	Filter bool
+	// Concurrency reports whether the extractor honours Options.Concurrency.
+	Concurrency bool
+	// ChangeProbe reports whether the extractor is a ChangeProber.
+	ChangeProbe bool
+}
+
+// Dialect is a registered extractor and its capabilities.
+type Dialect struct {
+	Name         string
+	Capabilities Capabilities
+}
+
+// String returns the name of the dialect.
+func (d Dialect) String() string {
+	return d.Name
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSections(t *testing.T) {
+	var tests = []struct {
+		name     string
+		names    []string
+		sections Section
+		errIsNil bool
+	}{
+		{"none", nil, 0, true},
+		{"single", []string{"indexes"}, SectionIndexes, true},
+		{"mixed case and spaces", []string{" Routines", "TRIGGERS "}, SectionRoutines | SectionTriggers, true},
+		{"unknown", []string{"views", "grants"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseSections(tt.names)
+			if (err == nil) != tt.errIsNil {
+				t.Errorf("\ngot error %v, wanted error: %v", err, !tt.errIsNil)
+			} else if s != tt.sections {
+				t.Errorf("\ngot sections %q, wanted %q", s, tt.sections)
+			}
+		})
+	}
+}
+
+func TestSectionString(t *testing.T) {
+	if s := (SectionComments | SectionViews).String(); s != "comments,views" {
+		t.Errorf("\ngot %q, wanted %q", s, "comments,views")
+	}
+	// every section has a name that parses back to it
+	if s, err := ParseSections(strings.Split(AllSections.String(), ",")); err != nil || s != AllSections {
+		t.Errorf("\ngot %v (%v), wanted all sections", s, err)
+	}
+}
@@ -0,0 +1,269 @@
+package dbml
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"erddiagram/internal/introspect"
+)
+
+func TestParseFile(t *testing.T) {
+	b, err := os.ReadFile("./testdata/shop.dbml")
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	s, err := Parse(string(b))
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+
+	var tables []string
+	for _, tab := range s.Tables {
+		tables = append(tables, qualified(tab.Schema, tab.Name))
+	}
+	if want := []string{"public.customers", "sales.orders", "sales.order_lines", "products"}; !reflect.DeepEqual(tables, want) {
+		t.Errorf("\ngot tables %v, wanted %v", tables, want)
+	}
+	var fks []string
+	for _, fk := range s.ForeignKeys {
+		fks = append(fks, qualified(fk.FromSchema, fk.FromTable)+"("+fk.FromColumn+") -> "+qualified(fk.ToSchema, fk.ToTable)+"("+fk.ToColumn+")")
+	}
+	want := []string{
+		"sales.orders(customer_id) -> public.customers(id)",
+		"sales.order_lines(order_id) -> sales.orders(id)",
+		"sales.order_lines(product) -> products(name)",
+	}
+	if !reflect.DeepEqual(fks, want) {
+		t.Errorf("\ngot foreign keys %v, wanted %v", fks, want)
+	}
+	if fk := s.ForeignKeys[1]; fk.Constraint != "line_order" || fk.OnDelete != "CASCADE" || fk.OnUpdate != "NO ACTION" {
+		t.Errorf("\ngot foreign key %+v, wanted line_order with ON DELETE CASCADE", fk)
+	}
+	if len(s.Types) != 1 || !reflect.DeepEqual(s.Types[0].Labels, []string{"pending", "shipped", "on hold"}) {
+		t.Errorf("\ngot types %+v, wanted enum sales.order_status", s.Types)
+	}
+	if len(s.Groups) != 1 || len(s.Groups[0].Tables) != 2 || s.Groups[0].Comment == nil {
+		t.Errorf("\ngot groups %+v, wanted sales with 2 tables and a note", s.Groups)
+	}
+	if c := s.Tables[0].Comment; c == nil || *c != "People who\nplace orders" {
+		t.Errorf("\ngot table note %v, wanted the dedented multi-line note", c)
+	}
+	if len(s.Warnings) != 1 || !strings.Contains(s.Warnings[0].Message, "many-to-many") {
+		t.Errorf("\ngot warnings %+v, wanted one about the many-to-many ref", s.Warnings)
+	}
+	lines := s.Tables[2]
+	if len(lines.Indexes) != 1 || !lines.Indexes[0].Primary || lines.Indexes[0].Name != "order_lines_pkey" {
+		t.Errorf("\ngot indexes %+v, wanted the composite primary key", lines.Indexes)
+	}
+	if !lines.Columns[0].PK || !lines.Columns[1].PK || lines.Columns[0].Nullable {
+		t.Errorf("\ngot columns %+v, wanted order_id and line_no in the primary key", lines.Columns)
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	var tests = []struct {
+		name   string
+		column string
+		want   introspect.Column
+	}{
+		{"plain", "c int", introspect.Column{Name: "c", Type: "int", Nullable: true}},
+		{"quoted type", `c "timestamp with time zone"`, introspect.Column{Name: "c", Type: "timestamp with time zone", Nullable: true}},
+		{"size", "c varchar(20) [not null]", introspect.Column{Name: "c", Type: "varchar(20)", MaxLength: ptr[int64](20)}},
+		{"pk and increment", "id int [primary key, increment]", introspect.Column{Name: "id", Type: "int", PK: true, Identity: true}},
+		{"string default", `c text [default: 'it\'s']`, introspect.Column{Name: "c", Type: "text", Nullable: true, Default: ptr("'it''s'")}},
+		{"negative default", "c int [default: -1]", introspect.Column{Name: "c", Type: "int", Nullable: true, Default: ptr("-1")}},
+		{"expression default", "c date [default: `now()`]", introspect.Column{Name: "c", Type: "date", Nullable: true, Default: ptr("now()")}},
+		{"note", "c int [note: 'count']", introspect.Column{Name: "c", Type: "int", Nullable: true, Comment: ptr("count")}},
+		{"quoted name", `"Order Id" int`, introspect.Column{Name: "Order Id", Type: "int", Nullable: true}},
+		{"keyword name", "note text", introspect.Column{Name: "note", Type: "text", Nullable: true}},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse("Table t {\n  " + tt.column + "\n}")
+			if err != nil {
+				t.Fatalf("\ngot unexpected error: \"%v\"", err)
+			}
+			if got := s.Tables[0].Columns[0]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\ngot %+v, wanted %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRefs(t *testing.T) {
+	const tables = "Table a {\n id int [pk]\n b_id int\n x int\n y int\n}\nTable b {\n id int [pk]\n x int\n y int\n}\n"
+	var tests = []struct {
+		name string
+		ref  string
+		want string
+	}{
+		{"many to one", "Ref: a.b_id > b.id", "a(b_id) -> b(id)"},
+		{"one to many", "Ref: b.id < a.b_id", "a(b_id) -> b(id)"},
+		{"one to one", "Ref: a.id - b.id", "a(id) -> b(id)"},
+		{"composite", "Ref: a.(x, y) > b.(x, y)", "a(x, y) -> b(x, y)"},
+		{"block", "Ref r {\n a.b_id > b.id\n}", "a(b_id) -> b(id)"},
+		{"schema qualified", "Ref: public.a.b_id > public.b.id", "a(b_id) -> b(id)"},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tables + tt.ref)
+			if err != nil {
+				t.Fatalf("\ngot unexpected error: \"%v\"", err)
+			}
+			if len(s.ForeignKeys) != 1 {
+				t.Fatalf("\ngot foreign keys %+v, wanted one", s.ForeignKeys)
+			}
+			fk := s.ForeignKeys[0]
+			if got := fk.FromTable + "(" + fk.FromColumn + ") -> " + fk.ToTable + "(" + fk.ToColumn + ")"; got != tt.want {
+				t.Errorf("\ngot %q, wanted %q", got, tt.want)
+			}
+			if len(s.Warnings) != 0 {
+				t.Errorf("\ngot unexpected warnings %+v", s.Warnings)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	var tests = []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"no tables", "Enum e {\n a\n}", "no tables found"},
+		{"unterminated string", "Table t {\n c int [note: 'x]\n}", "line 2: unterminated '"},
+		{"missing brace", "Table t {\n c int\n", "missing }"},
+		{"missing type", "Table t {\n c\n}", "line 2: column c has no type"},
+		{"duplicate table", "Table t {\n c int\n}\nTable t {\n c int\n}", "line 4: table t defined twice"},
+		{"bad ref", "Table t {\n c int\n}\nRef: t.c", "line 4: expected <, >, - or <> in ref"},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			if err == nil {
+				t.Fatalf("\nexpected an error, did not receive one")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("\ngot error %q, wanted %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWarnings(t *testing.T) {
+	s, err := Parse("Table t {\n c int [ref: > u.id]\n}\nTableGroup g {\n t\n v\n}")
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	if len(s.ForeignKeys) != 0 {
+		t.Errorf("\ngot foreign keys %+v, wanted none", s.ForeignKeys)
+	}
+	if len(s.Warnings) != 2 {
+		t.Errorf("\ngot warnings %+v, wanted the unknown tables u and v", s.Warnings)
+	}
+	if len(s.Groups[0].Tables) != 1 {
+		t.Errorf("\ngot group tables %+v, wanted only t", s.Groups[0].Tables)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	s := introspect.Schema{
+		Tables: []introspect.Table{{
+			Schema: "public",
+			Name:   "users",
+			Columns: []introspect.Column{
+				{Name: "id", Type: "integer", PK: true, Identity: true},
+				{Name: "email", Type: "character varying", MaxLength: ptr[int64](255), Comment: ptr("login")},
+				{Name: "state", Type: "text", Nullable: true, Default: ptr("'new'::text")},
+				{Name: "created", Type: "timestamp with time zone", Default: ptr("now()")},
+				{Name: "Note", Type: "text", Nullable: true},
+			},
+			Indexes: []introspect.Index{
+				{Name: "users_pkey", Columns: []string{"id"}, Unique: true, Primary: true},
+				{Name: "users_lower", Columns: []string{"lower(email)"}, Method: "btree"},
+			},
+			Constraints: []introspect.Constraint{{Type: introspect.ConstraintUnique, Columns: []string{"email"}}},
+			Comment:     ptr("it's\nall"),
+		}, {
+			Name:    "profiles",
+			Columns: []introspect.Column{{Name: "user_id", Type: "int", PK: true}},
+		}},
+		ForeignKeys: []introspect.ForeignKey{
+			{FromTable: "profiles", ToSchema: "public", ToTable: "users", Constraint: "fk profile",
+				Columns: []introspect.ColumnPair{{From: "user_id", To: "id"}}, OnDelete: "CASCADE", OnUpdate: "NO ACTION"},
+		},
+		Types: []introspect.UserType{{Name: "mood", Kind: introspect.TypeEnum, Labels: []string{"ok", "not ok"}}},
+	}
+	want := `Enum mood {
+  ok
+  "not ok"
+}
+
+Table public.users {
+  id integer [pk, increment]
+  email "character varying(255)" [not null, unique, note: 'login']
+  state text [default: 'new']
+  created "timestamp with time zone" [not null, default: ` + "`now()`" + `]
+  "Note" text
+
+  Note: 'it\'s\nall'
+
+  indexes {
+    ` + "`lower(email)`" + ` [name: 'users_lower', type: btree]
+  }
+}
+
+Table profiles {
+  user_id int [pk]
+}
+
+Ref "fk profile": profiles.user_id - public.users.id [delete: cascade]
+`
+
+	var b strings.Builder
+	if err := Write(&b, s); err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	if b.String() != want {
+		t.Errorf("\ngot:\n%s\nwanted:\n%s", b.String(), want)
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	b, err := os.ReadFile("./testdata/shop.dbml")
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	s, err := Parse(string(b))
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	var out strings.Builder
+	if err := Write(&out, s); err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	again, err := Parse(out.String())
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\" parsing:\n%s", err, out.String())
+	}
+	// the many-to-many ref is not written, and NO ACTION is the default
+	s.Warnings = nil
+	s.ForeignKeys[1].OnUpdate = ""
+	// column checks are written as table checks
+	again.Tables[1].Constraints[0].Columns = []string{"total"}
+	if !reflect.DeepEqual(again, s) {
+		t.Errorf("\ngot:\n%+v\nwanted:\n%+v\nfrom:\n%s", again, s, out.String())
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
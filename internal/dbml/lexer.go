@@ -0,0 +1,154 @@
+package dbml
+
+import (
+	"fmt"
+	"strings"
+)
+
+// kind is the kind of a token.
+type kind int
+
+const (
+	name   kind = iota // keyword, unquoted name, number or color
+	quoted             // quoted name: "x"
+	str                // string: 'x' or '''x'''
+	expr               // expression: `x`
+	punct              // { } [ ] ( ) , : . < > - or <>
+)
+
+// token is a lexical token of a DBML document. The text of quoted names,
+// strings and expressions is their unescaped content.
+type token struct {
+	kind     kind
+	text     string
+	line     int // 1-based line of the start of the token
+	pos, end int // byte offsets of the token in the document
+}
+
+// is reports whether t is the punctuation p.
+func (t token) is(p string) bool {
+	return t.kind == punct && t.text == p
+}
+
+// isKw reports whether t is the unquoted keyword kw, in any case.
+func (t token) isKw(kw string) bool {
+	return t.kind == name && strings.EqualFold(t.text, kw)
+}
+
+// lex splits a DBML document into tokens. Comments are dropped.
+func lex(src string) ([]token, error) {
+	var toks []token
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		start, startLine := i, line
+		add := func(k kind, text string) {
+			toks = append(toks, token{kind: k, text: text, line: startLine, pos: start, end: i})
+		}
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			e := strings.Index(src[i+2:], "*/")
+			if e < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", startLine)
+			}
+			line += strings.Count(src[i:i+2+e], "\n")
+			i += 2 + e + 2
+		case strings.HasPrefix(src[i:], "'''"):
+			e := strings.Index(src[i+3:], "'''")
+			if e < 0 {
+				return nil, fmt.Errorf("line %d: unterminated string", startLine)
+			}
+			text := src[i+3 : i+3+e]
+			line += strings.Count(text, "\n")
+			i += 3 + e + 3
+			add(str, dedent(text))
+		case c == '\'' || c == '"' || c == '`':
+			var b strings.Builder
+			for i++; i < len(src) && src[i] != c; i++ {
+				if src[i] == '\n' {
+					line++
+				}
+				if src[i] == '\\' && c != '`' && i+1 < len(src) {
+					i++
+					b.WriteByte(unescape(src[i]))
+					continue
+				}
+				b.WriteByte(src[i])
+			}
+			if i == len(src) {
+				return nil, fmt.Errorf("line %d: unterminated %c", startLine, c)
+			}
+			i++
+			add(map[byte]kind{'\'': str, '"': quoted, '`': expr}[c], b.String())
+		case strings.HasPrefix(src[i:], "<>"):
+			i += 2
+			add(punct, "<>")
+		case strings.IndexByte("{}[](),:.<>-", c) >= 0:
+			i++
+			add(punct, src[start:i])
+		default:
+			for i < len(src) && !strings.ContainsRune(" \t\r\n\f{}[](),:.<>'\"`", rune(src[i])) && !strings.HasPrefix(src[i:], "//") {
+				i++
+			}
+			// decimals such as 1.5 are one name
+			for i+1 < len(src) && src[i] == '.' && isDigits(src[start:i]) && src[i+1] >= '0' && src[i+1] <= '9' {
+				for i++; i < len(src) && src[i] >= '0' && src[i] <= '9'; i++ {
+				}
+			}
+			if i == start {
+				return nil, fmt.Errorf("line %d: unexpected character %q", startLine, c)
+			}
+			add(name, src[start:i])
+		}
+	}
+	return toks, nil
+}
+
+// unescape returns the character that the escape sequence \c stands for.
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	}
+	return c
+}
+
+// dedent removes the line break that follows the opening quotes of a
+// multi-line string and the indentation its lines have in common.
+func dedent(s string) string {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "\r"), "\n")
+	lines := strings.Split(s, "\n")
+	indent := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		n := len(l) - len(strings.TrimLeft(l, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, l := range lines {
+		if len(l) >= indent && indent > 0 {
+			lines[i] = l[indent:]
+		}
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), " \t\n")
+}
+
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
@@ -0,0 +1,867 @@
+// Package dbml reads and writes schemas in DBML, the Database Markup
+// Language of dbdiagram.io.
+package dbml
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"erddiagram/internal/introspect"
+)
+
+// Parse reads the tables, enums, refs and table groups of a DBML document
+// into a schema. Project blocks, sticky notes and other elements without
+// a counterpart in the schema are skipped. Refs to unknown tables or
+// columns and many-to-many refs, which are not foreign keys, are reported
+// as warnings. It fails on syntax errors or if the document defines no
+// tables.
+func Parse(src string) (introspect.Schema, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return introspect.Schema{}, err
+	}
+	p := &parser{
+		src:     src,
+		toks:    toks,
+		tables:  map[string]int{},
+		aliases: map[string]int{},
+		s:       introspect.Schema{Tables: []introspect.Table{}, ForeignKeys: []introspect.ForeignKey{}},
+	}
+	for p.i < len(p.toks) {
+		if err := p.element(); err != nil {
+			return introspect.Schema{}, err
+		}
+	}
+	p.finish()
+	if len(p.s.Tables) == 0 {
+		return p.s, errors.New("no tables found")
+	}
+	return p.s, nil
+}
+
+// parser collects the schema defined by the elements of a document.
+type parser struct {
+	src  string
+	toks []token
+	i    int // index of the next token
+
+	s       introspect.Schema
+	tables  map[string]int // index in s.Tables by schema and name
+	aliases map[string]int // index in s.Tables by alias
+	refs    []ref
+}
+
+// endpoint is one side of a ref: a table and columns as written.
+type endpoint struct {
+	schema, table string
+	columns       []string
+}
+
+// ref is a relationship that is resolved once all tables are known.
+type ref struct {
+	name               string
+	from, to           endpoint // from references to
+	manyToMany         bool
+	onDelete, onUpdate string
+	line               int
+}
+
+// setting is an item of a [...] settings list, such as pk, not null or
+// note: 'x'. Keys are lower case.
+type setting struct {
+	key string
+	val []token
+}
+
+// syntaxError returns an error at token t, or at the end of the document.
+func (p *parser) syntaxError(t *token, format string, args ...any) error {
+	if t == nil {
+		return fmt.Errorf("end of document: "+format, args...)
+	}
+	return fmt.Errorf("line %d: "+format, append([]any{t.line}, args...)...)
+}
+
+// peek returns the next token, or nil at the end of the document.
+func (p *parser) peek() *token {
+	if p.i >= len(p.toks) {
+		return nil
+	}
+	return &p.toks[p.i]
+}
+
+// accept consumes the next token if it is the punctuation s.
+func (p *parser) accept(s string) bool {
+	if t := p.peek(); t != nil && t.is(s) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// expect consumes the punctuation s or fails.
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		return p.syntaxError(p.peek(), "expected %q", s)
+	}
+	return nil
+}
+
+// ident consumes a quoted or unquoted name.
+func (p *parser) ident() (string, error) {
+	t := p.peek()
+	if t == nil || (t.kind != name && t.kind != quoted) {
+		return "", p.syntaxError(t, "expected a name")
+	}
+	p.i++
+	return t.text, nil
+}
+
+// qualifiedName consumes name or schema.name.
+func (p *parser) qualifiedName() (schema, n string, err error) {
+	if n, err = p.ident(); err != nil {
+		return "", "", err
+	}
+	if p.accept(".") {
+		schema = n
+		n, err = p.ident()
+	}
+	return schema, n, err
+}
+
+// atLineEnd reports whether the next token starts a new line after line,
+// ends a block or ends the document.
+func (p *parser) atLineEnd(line int) bool {
+	t := p.peek()
+	return t == nil || t.line > line || t.is("}")
+}
+
+// atKeyword reports whether the next tokens are the unquoted keyword kw
+// followed by one of the punctuations after, so that columns may be named
+// like keywords.
+func (p *parser) atKeyword(kw string, after ...string) bool {
+	if t := p.peek(); t == nil || !t.isKw(kw) || p.i+1 >= len(p.toks) {
+		return false
+	}
+	for _, a := range after {
+		if p.toks[p.i+1].is(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// element parses a top level element.
+func (p *parser) element() error {
+	t := p.peek()
+	p.i++
+	switch {
+	case t.isKw("Table"):
+		return p.table()
+	case t.isKw("Enum"):
+		return p.enum()
+	case t.isKw("Ref"):
+		return p.ref()
+	case t.isKw("TableGroup"):
+		return p.tableGroup()
+	case t.kind == name:
+		// Project, Note, TablePartial and the like
+		for u := p.peek(); u != nil && !u.is("{") && !u.is(":"); u = p.peek() {
+			p.i++
+		}
+		if p.accept(":") {
+			p.i++
+			return nil
+		}
+		return p.skipBlock()
+	}
+	return p.syntaxError(t, "unexpected %q", t.text)
+}
+
+// skipBlock skips a {...} block.
+func (p *parser) skipBlock() error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for depth := 1; depth > 0; p.i++ {
+		t := p.peek()
+		switch {
+		case t == nil:
+			return p.syntaxError(nil, "missing }")
+		case t.is("{"):
+			depth++
+		case t.is("}"):
+			depth--
+		}
+	}
+	return nil
+}
+
+// settings consumes an optional [...] settings list.
+func (p *parser) settings() ([]setting, error) {
+	if !p.accept("[") {
+		return nil, nil
+	}
+	var out []setting
+	var item []token
+	depth := 0
+	for {
+		t := p.peek()
+		if t == nil {
+			return nil, p.syntaxError(nil, "missing ]")
+		}
+		p.i++
+		switch {
+		case t.is("(") || t.is("["):
+			depth++
+		case t.is(")"):
+			depth--
+		case t.is("]") && depth > 0:
+			depth--
+		case t.is("]") || (t.is(",") && depth == 0):
+			if len(item) > 0 {
+				out = append(out, newSetting(item))
+			}
+			if t.is("]") {
+				return out, nil
+			}
+			item = nil
+			continue
+		}
+		item = append(item, *t)
+	}
+}
+
+// newSetting splits a setting into its key, the words before the first
+// colon, and its value.
+func newSetting(item []token) setting {
+	var key []string
+	for i, t := range item {
+		if t.is(":") {
+			return setting{key: strings.Join(key, " "), val: item[i+1:]}
+		}
+		key = append(key, strings.ToLower(t.text))
+	}
+	return setting{key: strings.Join(key, " ")}
+}
+
+// text returns the value of a setting as written, without quotes.
+func (st setting) text() string {
+	var parts []string
+	for _, t := range st.val {
+		parts = append(parts, t.text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// note returns the value of a string setting, or nil if it has none.
+func (st setting) note() *string {
+	if len(st.val) == 0 {
+		return nil
+	}
+	s := st.val[0].text
+	return &s
+}
+
+// table parses Table name [as alias] [settings] { ... }.
+func (p *parser) table() error {
+	schema, n, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+	key := tableKey(schema, n)
+	if _, ok := p.tables[key]; ok {
+		return p.syntaxError(&p.toks[p.i-1], "table %s defined twice", qualified(schema, n))
+	}
+	ti := len(p.s.Tables)
+	p.tables[key] = ti
+	p.s.Tables = append(p.s.Tables, introspect.Table{Schema: schema, Name: n, Columns: []introspect.Column{}})
+	if t := p.peek(); t != nil && t.isKw("as") {
+		p.i++
+		alias, err := p.ident()
+		if err != nil {
+			return err
+		}
+		p.aliases[alias] = ti
+	}
+	settings, err := p.settings()
+	if err != nil {
+		return err
+	}
+	for _, st := range settings {
+		if st.key == "note" {
+			p.s.Tables[ti].Comment = st.note()
+		}
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for !p.accept("}") {
+		t := p.peek()
+		switch {
+		case t == nil:
+			return p.syntaxError(nil, "missing } of table %s", qualified(schema, n))
+		case p.atKeyword("Note", ":", "{"):
+			p.i++
+			note, err := p.noteBody()
+			if err != nil {
+				return err
+			}
+			p.s.Tables[ti].Comment = &note
+		case p.atKeyword("indexes", "{"):
+			p.i++
+			err = p.indexes(ti)
+		case p.atKeyword("checks", "{"):
+			p.i++
+			err = p.checks(ti)
+		case t.kind == name && strings.HasPrefix(t.text, "~"):
+			p.i++
+			p.s.AddWarning(qualified(schema, n), "dbml", fmt.Errorf("table partial %s is not supported, skipped", t.text[1:]))
+		case t.kind == punct:
+			return p.syntaxError(t, "unexpected %q", t.text)
+		default:
+			err = p.column(ti)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	p.primaryIndex(ti, "")
+	return nil
+}
+
+// noteBody parses the rest of Note: 'x' or Note { 'x' }.
+func (p *parser) noteBody() (string, error) {
+	block := !p.accept(":")
+	if block {
+		if err := p.expect("{"); err != nil {
+			return "", err
+		}
+	}
+	t := p.peek()
+	if t == nil || t.kind != str {
+		return "", p.syntaxError(t, "expected a note string")
+	}
+	p.i++
+	if block {
+		return t.text, p.expect("}")
+	}
+	return t.text, nil
+}
+
+// column parses name type [settings] of table ti.
+func (p *parser) column(ti int) error {
+	line := p.peek().line
+	n, err := p.ident()
+	if err != nil {
+		return err
+	}
+	col := introspect.Column{Name: n, Nullable: true}
+	first := p.i
+	for !p.atLineEnd(line) && !p.peek().is("[") {
+		p.i++
+	}
+	switch {
+	case p.i == first:
+		return p.syntaxError(&p.toks[first-1], "column %s has no type", n)
+	case p.i == first+1 && p.toks[first].kind != name:
+		col.Type = p.toks[first].text
+	default:
+		col.Type = p.src[p.toks[first].pos:p.toks[p.i-1].end]
+	}
+	typeSize(&col)
+	settings, err := p.settings()
+	if err != nil {
+		return err
+	}
+	t := &p.s.Tables[ti]
+	for _, st := range settings {
+		switch st.key {
+		case "pk", "primary key":
+			col.PK = true
+			col.Nullable = false
+		case "not null":
+			col.Nullable = false
+		case "null":
+			col.Nullable = true
+		case "unique":
+			t.Constraints = append(t.Constraints, introspect.Constraint{Type: introspect.ConstraintUnique, Columns: []string{n}})
+		case "increment":
+			col.Identity = true
+		case "default":
+			col.Default = defaultValue(st.val)
+		case "note":
+			col.Comment = st.note()
+		case "check":
+			t.Constraints = append(t.Constraints, introspect.Constraint{Type: introspect.ConstraintCheck, Columns: []string{n}, Expression: st.text()})
+		case "ref":
+			if err := p.inlineRef(t, n, st); err != nil {
+				return err
+			}
+		}
+	}
+	t.Columns = append(t.Columns, col)
+	return nil
+}
+
+// typeSize sets the length, precision and scale of col from its type,
+// such as varchar(255) or decimal(10, 2).
+func typeSize(col *introspect.Column) {
+	base, args, ok := strings.Cut(col.Type, "(")
+	if !ok {
+		return
+	}
+	var nums []*int64
+	for _, a := range strings.Split(strings.TrimSuffix(strings.TrimSpace(args), ")"), ",") {
+		if v, err := strconv.ParseInt(strings.TrimSpace(a), 10, 64); err == nil {
+			nums = append(nums, &v)
+		} else {
+			nums = append(nums, nil)
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(base)) {
+	case "char", "varchar", "nchar", "nvarchar", "character", "character varying", "varchar2", "nvarchar2", "binary", "varbinary", "bit":
+		col.MaxLength = nums[0]
+	case "decimal", "numeric", "dec", "number":
+		col.Precision = nums[0]
+		if len(nums) > 1 {
+			col.Scale = nums[1]
+		}
+	}
+}
+
+// defaultValue converts the value of a default setting to a SQL
+// expression: strings become quoted literals, `expressions` are kept as is.
+func defaultValue(val []token) *string {
+	var s string
+	switch {
+	case len(val) == 0:
+		return nil
+	case val[0].kind == str:
+		s = "'" + strings.ReplaceAll(val[0].text, "'", "''") + "'"
+	case val[0].is("-") && len(val) > 1:
+		s = "-" + val[1].text
+	default:
+		s = val[0].text
+	}
+	return &s
+}
+
+// inlineRef records the ref: setting of column n of table t.
+func (p *parser) inlineRef(t *introspect.Table, n string, st setting) error {
+	if len(st.val) == 0 || st.val[0].kind != punct {
+		return p.syntaxError(p.lastToken(st), "expected <, >, - or <> in ref of column %s", n)
+	}
+	other, err := endpointOf(st.val[1:])
+	if err != nil {
+		return p.syntaxError(&st.val[0], "ref of column %s: %v", n, err)
+	}
+	self := endpoint{schema: t.Schema, table: t.Name, columns: []string{n}}
+	return p.addRef(ref{line: st.val[0].line}, self, st.val[0], other)
+}
+
+// lastToken returns the last token of a setting, for error positions.
+func (p *parser) lastToken(st setting) *token {
+	if len(st.val) > 0 {
+		return &st.val[len(st.val)-1]
+	}
+	return &p.toks[p.i-1]
+}
+
+// endpointOf parses table.column, schema.table.column or a composite
+// table.(a, b) from toks.
+func endpointOf(toks []token) (endpoint, error) {
+	var parts []string
+	var cols []string
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		switch {
+		case t.kind == name || t.kind == quoted:
+			parts = append(parts, t.text)
+		case t.is(".") && i > 0:
+		case t.is("("):
+			for i++; i < len(toks) && !toks[i].is(")"); i++ {
+				if toks[i].kind == name || toks[i].kind == quoted {
+					cols = append(cols, toks[i].text)
+				}
+			}
+		default:
+			return endpoint{}, fmt.Errorf("unexpected %q", t.text)
+		}
+	}
+	if cols == nil && len(parts) > 0 {
+		cols, parts = parts[len(parts)-1:], parts[:len(parts)-1]
+	}
+	switch {
+	case len(parts) == 1 && len(cols) > 0:
+		return endpoint{table: parts[0], columns: cols}, nil
+	case len(parts) == 2 && len(cols) > 0:
+		return endpoint{schema: parts[0], table: parts[1], columns: cols}, nil
+	}
+	return endpoint{}, errors.New("expected table.column")
+}
+
+// addRef records the relationship a op b, where op is one of < > - <>.
+func (p *parser) addRef(r ref, a endpoint, op token, b endpoint) error {
+	switch op.text {
+	case ">", "-":
+		r.from, r.to = a, b
+	case "<":
+		r.from, r.to = b, a
+	case "<>":
+		r.from, r.to, r.manyToMany = a, b, true
+	default:
+		return p.syntaxError(&op, "expected <, >, - or <>, got %q", op.text)
+	}
+	p.refs = append(p.refs, r)
+	return nil
+}
+
+// indexes parses an indexes { ... } block of table ti.
+func (p *parser) indexes(ti int) error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	t := &p.s.Tables[ti]
+	for !p.accept("}") {
+		first := p.peek()
+		if first == nil {
+			return p.syntaxError(nil, "missing } of indexes")
+		}
+		var cols []string
+		if p.accept("(") {
+			for !p.accept(")") {
+				c := p.peek()
+				if c == nil {
+					return p.syntaxError(nil, "missing )")
+				}
+				p.i++
+				if c.kind != punct {
+					cols = append(cols, c.text)
+				}
+			}
+		} else if first.kind != punct {
+			p.i++
+			cols = []string{first.text}
+		} else {
+			return p.syntaxError(first, "unexpected %q in indexes", first.text)
+		}
+		settings, err := p.settings()
+		if err != nil {
+			return err
+		}
+		idx := introspect.Index{Columns: cols}
+		pk := false
+		for _, st := range settings {
+			switch st.key {
+			case "pk", "primary key":
+				pk = true
+			case "unique":
+				idx.Unique = true
+			case "name":
+				idx.Name = st.text()
+			case "type":
+				idx.Method = st.text()
+			}
+		}
+		if pk {
+			for _, c := range cols {
+				if ci, ok := findColumn(t, c); ok {
+					t.Columns[ci].PK = true
+					t.Columns[ci].Nullable = false
+				} else {
+					p.s.AddWarning(qualified(t.Schema, t.Name), "dbml", fmt.Errorf("primary key on unknown column %s", c))
+				}
+			}
+			p.primaryIndex(ti, idx.Name)
+			continue
+		}
+		t.Indexes = append(t.Indexes, idx)
+	}
+	return nil
+}
+
+// primaryIndex adds the index of the primary key of table ti, named name,
+// unless the table has none or already has one.
+func (p *parser) primaryIndex(ti int, n string) {
+	t := &p.s.Tables[ti]
+	var cols []string
+	for _, c := range t.Columns {
+		if c.PK {
+			cols = append(cols, c.Name)
+		}
+	}
+	for _, idx := range t.Indexes {
+		if idx.Primary {
+			return
+		}
+	}
+	if len(cols) > 0 {
+		t.Indexes = append(t.Indexes, introspect.Index{Name: n, Columns: cols, Unique: true, Primary: true})
+	}
+}
+
+// checks parses a checks { `expr` [name: 'x'] ... } block of table ti.
+func (p *parser) checks(ti int) error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	t := &p.s.Tables[ti]
+	for !p.accept("}") {
+		e := p.peek()
+		if e == nil || e.kind != expr {
+			return p.syntaxError(e, "expected a `check expression`")
+		}
+		p.i++
+		c := introspect.Constraint{Type: introspect.ConstraintCheck, Expression: e.text}
+		settings, err := p.settings()
+		if err != nil {
+			return err
+		}
+		for _, st := range settings {
+			if st.key == "name" {
+				c.Name = st.text()
+			}
+		}
+		t.Constraints = append(t.Constraints, c)
+	}
+	return nil
+}
+
+// enum parses Enum name { value [note: 'x'] ... }.
+func (p *parser) enum() error {
+	schema, n, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+	ut := introspect.UserType{Schema: schema, Name: n, Kind: introspect.TypeEnum}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for !p.accept("}") {
+		label, err := p.ident()
+		if err != nil {
+			return err
+		}
+		if _, err := p.settings(); err != nil {
+			return err
+		}
+		ut.Labels = append(ut.Labels, label)
+	}
+	p.s.Types = append(p.s.Types, ut)
+	return nil
+}
+
+// ref parses Ref [name]: a op b [settings] or Ref [name] { a op b [settings] ... }.
+func (p *parser) ref() error {
+	r := ref{line: p.toks[p.i-1].line}
+	if t := p.peek(); t != nil && (t.kind == name || t.kind == quoted) {
+		r.name = t.text
+		p.i++
+	}
+	if p.accept(":") {
+		return p.refBody(r)
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for !p.accept("}") {
+		if p.peek() == nil {
+			return p.syntaxError(nil, "missing } of ref")
+		}
+		if err := p.refBody(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refBody parses a op b [settings] on one line.
+func (p *parser) refBody(r ref) error {
+	start := p.peek()
+	if start == nil {
+		return p.syntaxError(nil, "expected a ref")
+	}
+	var a, b []token
+	var op *token
+	for !p.atLineEnd(start.line) && !p.peek().is("[") {
+		t := p.peek()
+		p.i++
+		switch {
+		case op == nil && (t.is("<") || t.is(">") || t.is("-") || t.is("<>")):
+			op = t
+		case op == nil:
+			a = append(a, *t)
+		default:
+			b = append(b, *t)
+		}
+	}
+	if op == nil {
+		return p.syntaxError(start, "expected <, >, - or <> in ref")
+	}
+	from, err := endpointOf(a)
+	if err != nil {
+		return p.syntaxError(start, "ref: %v", err)
+	}
+	to, err := endpointOf(b)
+	if err != nil {
+		return p.syntaxError(op, "ref: %v", err)
+	}
+	settings, err := p.settings()
+	if err != nil {
+		return err
+	}
+	for _, st := range settings {
+		switch st.key {
+		case "delete":
+			r.onDelete = strings.ToUpper(st.text())
+		case "update":
+			r.onUpdate = strings.ToUpper(st.text())
+		}
+	}
+	r.line = start.line
+	return p.addRef(r, from, *op, to)
+}
+
+// tableGroup parses TableGroup name [settings] { table ... Note: 'x' }.
+func (p *parser) tableGroup() error {
+	n, err := p.ident()
+	if err != nil {
+		return err
+	}
+	g := introspect.TableGroup{Name: n, Tables: []introspect.ObjectRef{}}
+	settings, err := p.settings()
+	if err != nil {
+		return err
+	}
+	for _, st := range settings {
+		if st.key == "note" {
+			g.Comment = st.note()
+		}
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for !p.accept("}") {
+		if p.atKeyword("Note", ":", "{") {
+			p.i++
+			note, err := p.noteBody()
+			if err != nil {
+				return err
+			}
+			g.Comment = &note
+			continue
+		}
+		schema, tn, err := p.qualifiedName()
+		if err != nil {
+			return err
+		}
+		g.Tables = append(g.Tables, introspect.ObjectRef{Schema: schema, Name: tn})
+	}
+	p.s.Groups = append(p.s.Groups, g)
+	return nil
+}
+
+// lookup returns the index of the table schema.n, or of the table aliased
+// n. Unqualified names and names in schema public refer to the same tables.
+func (p *parser) lookup(schema, n string) (int, bool) {
+	if ti, ok := p.tables[tableKey(schema, n)]; ok {
+		return ti, true
+	}
+	switch schema {
+	case "":
+		if ti, ok := p.tables[tableKey("public", n)]; ok {
+			return ti, true
+		}
+		ti, ok := p.aliases[n]
+		return ti, ok
+	case "public":
+		ti, ok := p.tables[tableKey("", n)]
+		return ti, ok
+	}
+	return 0, false
+}
+
+// finish resolves the refs into foreign keys and the tables of groups to
+// their definitions.
+func (p *parser) finish() {
+	for _, r := range p.refs {
+		object := fmt.Sprintf("ref at line %d", r.line)
+		if r.manyToMany {
+			p.s.AddWarning(object, "dbml", errors.New("many-to-many refs are not foreign keys, skipped"))
+			continue
+		}
+		from, ok := p.lookup(r.from.schema, r.from.table)
+		if !ok {
+			p.s.AddWarning(object, "dbml", fmt.Errorf("unknown table %s", qualified(r.from.schema, r.from.table)))
+			continue
+		}
+		to, ok := p.lookup(r.to.schema, r.to.table)
+		if !ok {
+			p.s.AddWarning(object, "dbml", fmt.Errorf("unknown table %s", qualified(r.to.schema, r.to.table)))
+			continue
+		}
+		if len(r.from.columns) != len(r.to.columns) {
+			p.s.AddWarning(object, "dbml", fmt.Errorf("%d columns reference %d columns", len(r.from.columns), len(r.to.columns)))
+			continue
+		}
+		ft, tt := &p.s.Tables[from], &p.s.Tables[to]
+		fk := introspect.ForeignKey{
+			FromSchema: ft.Schema, FromTable: ft.Name,
+			ToSchema: tt.Schema, ToTable: tt.Name,
+			Constraint: r.name,
+			OnDelete:   r.onDelete,
+			OnUpdate:   r.onUpdate,
+		}
+		for i, c := range r.from.columns {
+			p.checkColumn(object, ft, c)
+			p.checkColumn(object, tt, r.to.columns[i])
+			fk.AddColumn(c, r.to.columns[i])
+		}
+		p.s.ForeignKeys = append(p.s.ForeignKeys, fk)
+	}
+	for gi := range p.s.Groups {
+		g := &p.s.Groups[gi]
+		kept := g.Tables[:0]
+		for _, o := range g.Tables {
+			ti, ok := p.lookup(o.Schema, o.Name)
+			if !ok {
+				p.s.AddWarning("table group "+g.Name, "dbml", fmt.Errorf("unknown table %s", qualified(o.Schema, o.Name)))
+				continue
+			}
+			kept = append(kept, introspect.ObjectRef{Schema: p.s.Tables[ti].Schema, Name: p.s.Tables[ti].Name})
+		}
+		g.Tables = kept
+	}
+}
+
+// checkColumn warns about object if table t has no column c.
+func (p *parser) checkColumn(object string, t *introspect.Table, c string) {
+	if _, ok := findColumn(t, c); !ok {
+		p.s.AddWarning(object, "dbml", fmt.Errorf("unknown column %s.%s", qualified(t.Schema, t.Name), c))
+	}
+}
+
+// findColumn returns the index of column n of table t.
+func findColumn(t *introspect.Table, n string) (int, bool) {
+	for i, c := range t.Columns {
+		if c.Name == n {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// tableKey returns the key of a table in parser.tables.
+func tableKey(schema, n string) string {
+	return schema + "\x00" + n
+}
+
+// qualified returns schema.n, or n without a schema.
+func qualified(schema, n string) string {
+	if schema == "" {
+		return n
+	}
+	return schema + "." + n
+}
@@ -0,0 +1,350 @@
+package dbml
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"erddiagram/internal/introspect"
+)
+
+var (
+	// plainName matches names that need no quotes.
+	plainName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// plainType matches column types that need no quotes, such as varchar(255).
+	plainType = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?(\([0-9, ]*\))?$`)
+	// number matches numeric defaults.
+	number = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+	// stringLiteral matches a SQL string literal default with an optional
+	// cast, such as 'new'::character varying.
+	stringLiteral = regexp.MustCompile(`^[NE]?'((?:[^']|'')*)'(::[A-Za-z_][A-Za-z0-9_ ]*)?$`)
+)
+
+// keywords are the names of elements inside a table, which are quoted when
+// used as column names.
+var keywords = []string{"note", "indexes", "checks", "as"}
+
+// Write writes the tables, enums, foreign keys and table groups of s as a
+// DBML document. A foreign key whose columns are unique in the referencing
+// table is written as a one-to-one ref, others as many-to-one. Views,
+// routines and other objects DBML has no notation for are left out.
+func Write(w io.Writer, s introspect.Schema) error {
+	var b strings.Builder
+	for _, ut := range s.Types {
+		if ut.Kind != introspect.TypeEnum {
+			continue
+		}
+		fmt.Fprintf(&b, "Enum %s {\n", qualifiedName(ut.Schema, ut.Name))
+		for _, l := range ut.Labels {
+			fmt.Fprintf(&b, "  %s\n", quote(l))
+		}
+		b.WriteString("}\n\n")
+	}
+	for _, t := range s.Tables {
+		writeTable(&b, t)
+	}
+	for _, fk := range s.ForeignKeys {
+		writeRef(&b, s, fk)
+	}
+	if len(s.ForeignKeys) > 0 {
+		b.WriteString("\n")
+	}
+	for _, g := range s.Groups {
+		fmt.Fprintf(&b, "TableGroup %s {\n", quote(g.Name))
+		for _, t := range g.Tables {
+			fmt.Fprintf(&b, "  %s\n", qualifiedName(t.Schema, t.Name))
+		}
+		if g.Comment != nil {
+			fmt.Fprintf(&b, "  Note: %s\n", stringValue(*g.Comment))
+		}
+		b.WriteString("}\n\n")
+	}
+	_, err := io.WriteString(w, strings.TrimSuffix(b.String(), "\n"))
+	return err
+}
+
+// writeTable writes table t with its columns, indexes and checks.
+func writeTable(b *strings.Builder, t introspect.Table) {
+	fmt.Fprintf(b, "Table %s {\n", qualifiedName(t.Schema, t.Name))
+	pk := primaryKey(t)
+	// unnamed single column unique constraints are column settings
+	uniqueCols := map[string]bool{}
+	for _, c := range t.Constraints {
+		if c.Type == introspect.ConstraintUnique && c.Name == "" && len(c.Columns) == 1 {
+			uniqueCols[c.Columns[0]] = true
+		}
+	}
+	for _, c := range t.Columns {
+		var settings []string
+		if c.PK && len(pk) == 1 {
+			settings = append(settings, "pk")
+		}
+		if c.Identity {
+			settings = append(settings, "increment")
+		}
+		if !c.Nullable && !(c.PK && len(pk) == 1) {
+			settings = append(settings, "not null")
+		}
+		if uniqueCols[c.Name] {
+			settings = append(settings, "unique")
+		}
+		if c.Default != nil {
+			settings = append(settings, "default: "+defaultSetting(*c.Default))
+		}
+		if c.Comment != nil {
+			settings = append(settings, "note: "+stringValue(*c.Comment))
+		}
+		fmt.Fprintf(b, "  %s %s%s\n", columnName(c.Name), columnType(c), settingList(settings))
+	}
+	if t.Comment != nil {
+		fmt.Fprintf(b, "\n  Note: %s\n", stringValue(*t.Comment))
+	}
+
+	var indexes []string
+	if len(pk) > 1 {
+		settings := []string{"pk"}
+		if name := primaryName(t); name != "" {
+			settings = append(settings, "name: "+stringValue(name))
+		}
+		indexes = append(indexes, indexColumns(t, pk)+settingList(settings))
+	}
+	for _, c := range t.Constraints {
+		if c.Type == introspect.ConstraintUnique && !(c.Name == "" && len(c.Columns) == 1) {
+			settings := []string{"unique"}
+			if c.Name != "" {
+				settings = append(settings, "name: "+stringValue(c.Name))
+			}
+			indexes = append(indexes, indexColumns(t, c.Columns)+settingList(settings))
+		}
+	}
+	for _, idx := range t.Indexes {
+		if idx.Primary || len(idx.Columns) == 0 {
+			continue
+		}
+		var settings []string
+		if idx.Unique {
+			settings = append(settings, "unique")
+		}
+		if idx.Name != "" {
+			settings = append(settings, "name: "+stringValue(idx.Name))
+		}
+		if idx.Method != "" {
+			settings = append(settings, "type: "+idx.Method)
+		}
+		indexes = append(indexes, indexColumns(t, idx.Columns)+settingList(settings))
+	}
+	if len(indexes) > 0 {
+		b.WriteString("\n  indexes {\n")
+		for _, idx := range indexes {
+			fmt.Fprintf(b, "    %s\n", idx)
+		}
+		b.WriteString("  }\n")
+	}
+
+	var checks []string
+	for _, c := range t.Constraints {
+		if c.Type == introspect.ConstraintCheck && c.Expression != "" {
+			var settings []string
+			if c.Name != "" {
+				settings = append(settings, "name: "+stringValue(c.Name))
+			}
+			checks = append(checks, "`"+c.Expression+"`"+settingList(settings))
+		}
+	}
+	if len(checks) > 0 {
+		b.WriteString("\n  checks {\n")
+		for _, c := range checks {
+			fmt.Fprintf(b, "    %s\n", c)
+		}
+		b.WriteString("  }\n")
+	}
+	b.WriteString("}\n\n")
+}
+
+// writeRef writes foreign key fk as a ref.
+func writeRef(b *strings.Builder, s introspect.Schema, fk introspect.ForeignKey) {
+	var from, to []string
+	for _, c := range fk.Columns {
+		from = append(from, c.From)
+		to = append(to, c.To)
+	}
+	op := ">"
+	for _, t := range s.Tables {
+		if t.Schema == fk.FromSchema && t.Name == fk.FromTable && isUnique(t, from) {
+			op = "-"
+		}
+	}
+	b.WriteString("Ref")
+	if fk.Constraint != "" {
+		b.WriteString(" " + quote(fk.Constraint))
+	}
+	fmt.Fprintf(b, ": %s %s %s", refEndpoint(fk.FromSchema, fk.FromTable, from), op, refEndpoint(fk.ToSchema, fk.ToTable, to))
+	var settings []string
+	for _, a := range []struct{ key, action string }{{"delete", fk.OnDelete}, {"update", fk.OnUpdate}} {
+		if a.action != "" && !strings.EqualFold(a.action, "NO ACTION") {
+			settings = append(settings, a.key+": "+strings.ToLower(a.action))
+		}
+	}
+	b.WriteString(settingList(settings) + "\n")
+}
+
+// primaryKey returns the primary key columns of t, in key order if t has
+// a primary index.
+func primaryKey(t introspect.Table) []string {
+	for _, idx := range t.Indexes {
+		if idx.Primary {
+			return idx.Columns
+		}
+	}
+	var cols []string
+	for _, c := range t.Columns {
+		if c.PK {
+			cols = append(cols, c.Name)
+		}
+	}
+	return cols
+}
+
+// primaryName returns the name of the primary index of t, if any.
+func primaryName(t introspect.Table) string {
+	for _, idx := range t.Indexes {
+		if idx.Primary {
+			return idx.Name
+		}
+	}
+	return ""
+}
+
+// isUnique reports whether cols, in any order, are the primary key or a
+// unique constraint or index of t.
+func isUnique(t introspect.Table, cols []string) bool {
+	same := func(other []string) bool {
+		return len(other) == len(cols) && !slices.ContainsFunc(cols, func(c string) bool { return !slices.Contains(other, c) })
+	}
+	if same(primaryKey(t)) {
+		return true
+	}
+	for _, c := range t.Constraints {
+		if c.Type == introspect.ConstraintUnique && same(c.Columns) {
+			return true
+		}
+	}
+	for _, idx := range t.Indexes {
+		if idx.Unique && idx.Predicate == "" && same(idx.Columns) {
+			return true
+		}
+	}
+	return false
+}
+
+// refEndpoint returns schema.table.column, or schema.table.(a, b) for
+// several columns.
+func refEndpoint(schema, table string, cols []string) string {
+	if len(cols) == 1 {
+		return qualifiedName(schema, table) + "." + quote(cols[0])
+	}
+	quoted := make([]string, len(cols))
+	for i, c := range cols {
+		quoted[i] = quote(c)
+	}
+	return qualifiedName(schema, table) + ".(" + strings.Join(quoted, ", ") + ")"
+}
+
+// indexColumns returns the columns of an index line: a column name,
+// (a, b) for several, and `expression` for anything that is not a column
+// of t.
+func indexColumns(t introspect.Table, cols []string) string {
+	out := make([]string, len(cols))
+	for i, c := range cols {
+		if slices.ContainsFunc(t.Columns, func(col introspect.Column) bool { return col.Name == c }) {
+			out[i] = quote(c)
+		} else {
+			out[i] = "`" + c + "`"
+		}
+	}
+	if len(out) == 1 {
+		return out[0]
+	}
+	return "(" + strings.Join(out, ", ") + ")"
+}
+
+// columnType returns the type of c with its length, precision and scale
+// if the type does not include them, quoted unless it is a plain name.
+func columnType(c introspect.Column) string {
+	typ := c.Type
+	if !strings.Contains(typ, "(") {
+		switch {
+		case c.MaxLength != nil && *c.MaxLength > 0:
+			typ += fmt.Sprintf("(%d)", *c.MaxLength)
+		case c.Precision != nil && c.Scale != nil:
+			typ += fmt.Sprintf("(%d,%d)", *c.Precision, *c.Scale)
+		case c.Precision != nil:
+			typ += fmt.Sprintf("(%d)", *c.Precision)
+		}
+	}
+	if plainType.MatchString(typ) {
+		return typ
+	}
+	return quoteName(typ)
+}
+
+// defaultSetting returns the DBML value of a default expression: numbers,
+// booleans and null as is, string literals as strings and anything else
+// as an `expression`.
+func defaultSetting(d string) string {
+	switch {
+	case number.MatchString(d):
+		return d
+	case slices.Contains([]string{"true", "false", "null"}, strings.ToLower(d)):
+		return strings.ToLower(d)
+	}
+	if m := stringLiteral.FindStringSubmatch(d); m != nil {
+		return stringValue(strings.ReplaceAll(m[1], "''", "'"))
+	}
+	return "`" + d + "`"
+}
+
+// settingList returns settings as " [a, b]", or "" if there are none.
+func settingList(settings []string) string {
+	if len(settings) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(settings, ", ") + "]"
+}
+
+// stringValue returns s as a single quoted DBML string.
+func stringValue(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return "'" + r.Replace(s) + "'"
+}
+
+// qualifiedName returns schema.name with each part quoted if needed.
+func qualifiedName(schema, n string) string {
+	if schema == "" {
+		return quote(n)
+	}
+	return quote(schema) + "." + quote(n)
+}
+
+// columnName quotes n if it needs quotes or is a keyword inside tables.
+func columnName(n string) string {
+	if slices.Contains(keywords, strings.ToLower(n)) {
+		return quoteName(n)
+	}
+	return quote(n)
+}
+
+// quote returns n, in double quotes unless it is a plain name.
+func quote(n string) string {
+	if plainName.MatchString(n) {
+		return n
+	}
+	return quoteName(n)
+}
+
+// quoteName returns n in double quotes.
+func quoteName(n string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(n) + `"`
+}
@@ -0,0 +1,245 @@
+package ddl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// kind is the kind of a token.
+type kind int
+
+const (
+	word  kind = iota // keyword, unquoted identifier, number or operator
+	ident             // quoted identifier: "x", `x` or [x]
+	str               // string literal: 'x' or $tag$x$tag$
+	group             // parenthesized group, including the parentheses
+	punct             // comma, dot or end of statement (;)
+)
+
+// token is a lexical token of a DDL script. Groups are single tokens, so
+// nested expressions stay intact; lex their inner text to look inside.
+type token struct {
+	kind     kind
+	pos, end int // byte offsets of the token in the script
+	text     string
+}
+
+// dollarTag matches the opening tag of a Postgres dollar-quoted string.
+var dollarTag = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z_0-9]*)?\$`)
+
+// lexer splits a DDL script into tokens.
+type lexer struct {
+	src   string
+	mysql bool  // # starts comments, \ escapes quotes and DELIMITER is honoured
+	err   error // first unterminated group, string or identifier
+}
+
+// tokens returns the tokens of src[start:end] with offsets into src.
+// Comments are dropped. A GO line (SQL Server) and the delimiter set by a
+// MySQL DELIMITER line are returned as ";". A group, string or identifier
+// that is not closed before end runs to end and sets l.err.
+func (l *lexer) tokens(start, end int) []token {
+	var toks []token
+	src := l.src[:end]
+	delim := ";"
+	add := func(k kind, pos, end int) {
+		toks = append(toks, token{kind: k, pos: pos, end: end, text: src[pos:end]})
+	}
+	for i := start; i < end; {
+		c := src[i]
+		switch {
+		case isSpace(c):
+			i++
+		case strings.HasPrefix(src[i:], "--") || (l.mysql && c == '#'):
+			i = lineEnd(src, i)
+		case l.mysql && strings.HasPrefix(src[i:], "/*!"):
+			// MySQL conditional comment: the statement inside is executed
+			i += 3
+			for i < end && src[i] >= '0' && src[i] <= '9' {
+				i++
+			}
+		case l.mysql && strings.HasPrefix(src[i:], "*/"):
+			i += 2
+		case strings.HasPrefix(src[i:], "/*"):
+			i = commentEnd(src, i)
+		case delim != ";" && strings.HasPrefix(src[i:], delim):
+			toks = append(toks, token{kind: punct, pos: i, end: i + len(delim), text: ";"})
+			i += len(delim)
+		case c == ',' || c == ';' || c == '.':
+			add(punct, i, i+1)
+			i++
+		case c == '(':
+			e, ok := l.groupEnd(i)
+			e = l.closed(i, min(e, end), ok, "unclosed parenthesis")
+			add(group, i, e)
+			i = e
+		case c == ')':
+			// unbalanced closing parenthesis, skip it
+			i++
+		case c == '\'':
+			e, ok := l.quoteEnd(i)
+			e = l.closed(i, min(e, end), ok, "unterminated string")
+			add(str, i, e)
+			i = e
+		case c == '"' || c == '`' || c == '[':
+			e, ok := l.quoteEnd(i)
+			e = l.closed(i, min(e, end), ok, "unterminated quoted identifier")
+			add(ident, i, e)
+			i = e
+		case c == '$' && !l.mysql && dollarTag.MatchString(src[i:]):
+			e, ok := dollarEnd(l.src, i)
+			e = l.closed(i, min(e, end), ok, "unterminated string")
+			add(str, i, e)
+			i = e
+		default:
+			s := i
+			for i < end && !isSpace(src[i]) && !strings.ContainsRune(",;.()'\"`[", rune(src[i])) &&
+				(delim == ";" || !strings.HasPrefix(src[i:], delim)) {
+				i++
+			}
+			w := src[s:i]
+			switch {
+			case l.mysql && strings.EqualFold(w, "DELIMITER") && aloneFrom(src, s, s):
+				e := lineEnd(src, i)
+				if d := strings.TrimSpace(src[i:e]); d != "" {
+					delim = d
+				}
+				i = e
+			case strings.EqualFold(w, "GO") && aloneFrom(src, s, i):
+				toks = append(toks, token{kind: punct, pos: s, end: i, text: ";"})
+			default:
+				add(word, s, i)
+			}
+		}
+	}
+	return toks
+}
+
+// inner returns the tokens inside group token g.
+func (l *lexer) inner(g token) []token {
+	if g.kind != group || g.end-g.pos < 2 {
+		return nil
+	}
+	e := g.end
+	if l.src[e-1] == ')' {
+		e--
+	}
+	return l.tokens(g.pos+1, e)
+}
+
+// closed returns end, the end of the token at start. Unless ok, the token
+// is not closed and l.err is set if it is the first one.
+func (l *lexer) closed(start, end int, ok bool, problem string) int {
+	if !ok && l.err == nil {
+		l.err = fmt.Errorf("line %d: %s", l.line(start), problem)
+	}
+	return end
+}
+
+// line returns the line number of offset pos of the script.
+func (l *lexer) line(pos int) int {
+	return strings.Count(l.src[:pos], "\n") + 1
+}
+
+// quoteEnd returns the offset just past the quoted string or identifier
+// starting at src[start]. Doubled quotes are escapes. It reports false if
+// the quote is not closed.
+func (l *lexer) quoteEnd(start int) (int, bool) {
+	src := l.src
+	closer := src[start]
+	if closer == '[' {
+		closer = ']'
+	}
+	for i := start + 1; i < len(src); i++ {
+		switch {
+		case l.mysql && src[i] == '\\' && closer != '`':
+			i++
+		case src[i] != closer:
+		case closer != ']' && i+1 < len(src) && src[i+1] == closer:
+			i++
+		default:
+			return i + 1, true
+		}
+	}
+	return len(src), false
+}
+
+// groupEnd returns the offset just past the parenthesis matching the one
+// at src[start]. It reports false if the parenthesis is not closed.
+func (l *lexer) groupEnd(start int) (int, bool) {
+	src := l.src
+	depth := 0
+	for i := start; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i + 1, true
+			}
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			i, _ = l.quoteEnd(i)
+			continue
+		case strings.HasPrefix(src[i:], "--") || (l.mysql && c == '#'):
+			i = lineEnd(src, i)
+			continue
+		case strings.HasPrefix(src[i:], "/*"):
+			i = commentEnd(src, i)
+			continue
+		case c == '$' && !l.mysql && dollarTag.MatchString(src[i:]):
+			i, _ = dollarEnd(src, i)
+			continue
+		}
+		i++
+	}
+	return len(src), false
+}
+
+// dollarEnd returns the offset just past the dollar-quoted string starting
+// at src[start]. It reports false if the string is not closed.
+func dollarEnd(src string, start int) (int, bool) {
+	tag := dollarTag.FindString(src[start:])
+	if e := strings.Index(src[start+len(tag):], tag); e >= 0 {
+		return start + len(tag) + e + len(tag), true
+	}
+	return len(src), false
+}
+
+// commentEnd returns the offset just past the block comment starting at
+// src[start].
+func commentEnd(src string, start int) int {
+	if e := strings.Index(src[start+2:], "*/"); e >= 0 {
+		return start + 2 + e + 2
+	}
+	return len(src)
+}
+
+// lineEnd returns the offset of the end of the line containing src[i].
+func lineEnd(src string, i int) int {
+	if e := strings.IndexByte(src[i:], '\n'); e >= 0 {
+		return i + e
+	}
+	return len(src)
+}
+
+// aloneFrom reports whether src[start:] begins a line and src[end:] runs
+// to its end with only blanks, so that src[start:end] is alone on its line.
+// end == start only checks the beginning.
+func aloneFrom(src string, start, end int) bool {
+	for i := start - 1; i >= 0 && src[i] != '\n'; i-- {
+		if !isSpace(src[i]) {
+			return false
+		}
+	}
+	if end == start {
+		return true
+	}
+	return strings.TrimSpace(src[end:lineEnd(src, end)]) == ""
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
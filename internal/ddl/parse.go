@@ -0,0 +1,1140 @@
+package ddl
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"erddiagram/internal/introspect"
+	"erddiagram/pkg/config"
+)
+
+// Dialects lists the dialects Parse understands, as normalized driver names.
+var Dialects = []string{"postgres", "mysql", "sqlserver", "sqlite"}
+
+// defaultSchemas holds the schema of unqualified names per dialect.
+var defaultSchemas = map[string]string{"postgres": "public", "sqlserver": "dbo"}
+
+// Parse reads the CREATE TABLE, ALTER TABLE, CREATE INDEX and COMMENT ON
+// statements of a DDL script in dialect, such as the output of
+// pg_dump --schema-only, mysqldump --no-data or a migration, into a schema.
+// An empty dialect is guessed with Detect. Other statements are skipped and
+// statements that cannot be applied are reported as warnings. It fails only
+// if the dialect is unknown, a parenthesis, string or quoted identifier is
+// not closed, as in a truncated script, or the script defines no tables.
+func Parse(script, dialect string) (introspect.Schema, error) {
+	if dialect == "" {
+		dialect = Detect(script)
+	}
+	dialect = config.NormalizeDriver(dialect)
+	if !slices.Contains(Dialects, dialect) {
+		return introspect.Schema{}, fmt.Errorf("unsupported ddl dialect: %q (available: %v)", dialect, Dialects)
+	}
+	p := &parser{
+		lexer:   lexer{src: script, mysql: dialect == "mysql"},
+		dialect: dialect,
+		schema:  defaultSchemas[dialect],
+		tables:  map[string]int{},
+		s:       introspect.Schema{Tables: []introspect.Table{}, ForeignKeys: []introspect.ForeignKey{}},
+	}
+	toks := p.tokens(0, len(script))
+	if p.err != nil {
+		return introspect.Schema{}, p.err
+	}
+	var stmt []token
+	for _, t := range toks {
+		if t.kind == punct && t.text == ";" {
+			p.statement(stmt)
+			stmt = nil
+			continue
+		}
+		stmt = append(stmt, t)
+	}
+	p.statement(stmt)
+	p.finish()
+	if len(p.s.Tables) == 0 {
+		return p.s, errors.New("no CREATE TABLE statements found")
+	}
+	return p.s, nil
+}
+
+// parser collects the schema defined by the statements of a script.
+type parser struct {
+	lexer
+	dialect string
+	schema  string // schema of unqualified names
+
+	s      introspect.Schema
+	tables map[string]int // index in s.Tables by tableKey
+	fks    []pendingFK
+}
+
+// pendingFK is a foreign key whose referenced columns may only be known
+// once the referenced table is defined.
+type pendingFK struct {
+	fk       introspect.ForeignKey
+	from, to []string
+}
+
+// statement applies one statement to the schema.
+func (p *parser) statement(toks []token) {
+	if len(toks) == 0 {
+		return
+	}
+	switch {
+	case isKw(toks, 0, "CREATE"):
+		p.create(toks)
+	case isKw(toks, 0, "ALTER") && isKw(toks, 1, "TABLE"):
+		p.alterTable(toks)
+	case isKw(toks, 0, "COMMENT") && isKw(toks, 1, "ON"):
+		p.comment(toks)
+	case isKw(toks, 0, "SET") && isKw(toks, 1, "search_path"):
+		p.searchPath(toks[2:])
+	case isKw(toks, 0, "EXEC", "EXECUTE"):
+		p.extendedProperty(toks)
+	}
+}
+
+// create dispatches CREATE statements on the kind of object they create.
+func (p *parser) create(toks []token) {
+	for i := 1; i < len(toks); i++ {
+		switch {
+		case isKw(toks, i, "TABLE"):
+			p.createTable(toks, i+1)
+		case isKw(toks, i, "INDEX"):
+			p.createIndex(toks, i+1)
+		case isKw(toks, i, "VIEW"):
+			p.createView(toks, i+1)
+		case isKw(toks, i, "TYPE"):
+			p.createType(toks, i+1)
+		case isKw(toks, i, "FUNCTION", "PROCEDURE", "TRIGGER", "SEQUENCE", "SCHEMA", "DATABASE", "EXTENSION", "DOMAIN", "RULE", "POLICY"):
+		case toks[i].kind != group:
+			// OR REPLACE, TEMPORARY, UNIQUE, DEFINER=... and the like
+			continue
+		}
+		return
+	}
+}
+
+// createTable parses CREATE TABLE name (items) options, or CREATE TABLE
+// name PARTITION OF parent, with toks[i] following TABLE.
+func (p *parser) createTable(toks []token, i int) {
+	i = skipKw(toks, i, "IF", "NOT", "EXISTS")
+	schema, name, i := p.name(toks, i)
+	if name == "" {
+		return
+	}
+	if isKw(toks, i, "PARTITION") && isKw(toks, i+1, "OF") {
+		p.partitionOf(schema, name, toks, i+2)
+		return
+	}
+	if i >= len(toks) || toks[i].kind != group {
+		// CREATE TABLE ... AS SELECT
+		p.warn(qualified(schema, name), errors.New("table has no column list"))
+		return
+	}
+	key := tableKey(schema, name)
+	if _, ok := p.tables[key]; ok {
+		p.warn(qualified(schema, name), errors.New("table defined twice, keeping the first definition"))
+		return
+	}
+	p.tables[key] = len(p.s.Tables)
+	p.s.Tables = append(p.s.Tables, introspect.Table{Schema: schema, Name: name, Columns: []introspect.Column{}})
+	ti := p.tables[key]
+	for _, item := range splitItems(p.inner(toks[i])) {
+		p.tableItem(ti, item)
+	}
+	p.tableOptions(ti, toks[i+1:])
+}
+
+// tableItem applies a column definition or table constraint of table ti.
+func (p *parser) tableItem(ti int, item []token) {
+	if len(item) == 0 {
+		return
+	}
+	switch {
+	case isKw(item, 0, "CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "EXCLUDE"):
+		p.tableConstraint(ti, item, false)
+	case isKw(item, 0, "KEY", "INDEX", "FULLTEXT", "SPATIAL"):
+		p.inlineIndex(ti, item)
+	case isKw(item, 0, "LIKE", "PERIOD"):
+	default:
+		p.column(ti, item)
+	}
+}
+
+// tableOptions applies what follows the column list of CREATE TABLE: the
+// MySQL table comment and the partitioning.
+func (p *parser) tableOptions(ti int, toks []token) {
+	t := &p.s.Tables[ti]
+	for i := 0; i < len(toks); i++ {
+		switch {
+		case isKw(toks, i, "COMMENT", "COMMENT="):
+			j := skipEquals(toks, i+1)
+			if j < len(toks) && toks[j].kind == str {
+				t.Comment = ptr(p.unquote(toks[j]))
+			}
+		case isKw(toks, i, "PARTITION") && isKw(toks, i+1, "BY"):
+			p.partitionBy(ti, toks[i+2:])
+			return
+		}
+	}
+}
+
+// partitionBy parses the strategy, key and, for MySQL, the partitions of
+// PARTITION BY.
+func (p *parser) partitionBy(ti int, toks []token) {
+	i := 0
+	if isKw(toks, i, "LINEAR") {
+		i++
+	}
+	if i >= len(toks) || toks[i].kind != word {
+		return
+	}
+	part := &introspect.Partitioning{Strategy: strings.ToUpper(toks[i].text)}
+	i = skipKw(toks, i+1, "COLUMNS")
+	if i < len(toks) && toks[i].kind == group {
+		for _, item := range splitItems(p.inner(toks[i])) {
+			if len(item) > 0 {
+				part.Columns = append(part.Columns, p.columnOrExpr(item))
+			}
+		}
+		i++
+	}
+	for ; i < len(toks); i++ {
+		if toks[i].kind != group {
+			continue
+		}
+		// MySQL: (PARTITION p0 VALUES LESS THAN (10), ...)
+		for _, item := range splitItems(p.inner(toks[i])) {
+			if isKw(item, 0, "PARTITION") && len(item) > 2 {
+				part.Partitions = append(part.Partitions, introspect.Partition{
+					Name:   p.unquoteIdent(item[1]),
+					Number: len(part.Partitions) + 1,
+					Bound:  p.span(item[2:partitionOptions(item)]),
+				})
+			}
+		}
+		break
+	}
+	p.s.Tables[ti].Partitioning = part
+}
+
+// partitionOptions returns the index of the options that follow the bound
+// of a MySQL partition definition.
+func partitionOptions(item []token) int {
+	for i := range item {
+		if isKw(item, i, "ENGINE", "STORAGE", "COMMENT", "DATA", "INDEX", "TABLESPACE", "MAX_ROWS", "MIN_ROWS") {
+			return i
+		}
+	}
+	return len(item)
+}
+
+// partitionOf adds table name, a Postgres partition created with
+// PARTITION OF parent FOR VALUES ..., to the partitions of its parent.
+func (p *parser) partitionOf(schema, name string, toks []token, i int) {
+	ps, pn, i := p.name(toks, i)
+	ti, ok := p.table(ps, pn)
+	if !ok {
+		p.warn(qualified(schema, name), fmt.Errorf("partition of unknown table %s", qualified(ps, pn)))
+		return
+	}
+	for i < len(toks) && toks[i].kind == group {
+		i++
+	}
+	p.addPartition(ti, schema, name, toks[i:])
+}
+
+// addPartition adds the partition with bound toks to table ti.
+func (p *parser) addPartition(ti int, schema, name string, toks []token) {
+	t := &p.s.Tables[ti]
+	if t.Partitioning == nil {
+		t.Partitioning = &introspect.Partitioning{}
+	}
+	t.Partitioning.Partitions = append(t.Partitioning.Partitions, introspect.Partition{Schema: schema, Name: name, Bound: p.span(toks)})
+}
+
+// column parses a column definition and adds it to table ti.
+func (p *parser) column(ti int, item []token) {
+	t := &p.s.Tables[ti]
+	name := p.unquoteIdent(item[0])
+	if _, ok := findColumn(t, name); ok {
+		p.warn(qualified(t.Schema, t.Name), fmt.Errorf("column %s defined twice", name))
+		return
+	}
+	i := 1
+	for i < len(item) && !columnKeyword(item, i) {
+		i++
+	}
+	col := introspect.Column{Name: name, Nullable: true}
+	if i > 1 {
+		col.Type = p.span(item[1:i])
+		if item[1].kind == ident {
+			// SQL Server: [nvarchar](100)
+			col.Type = p.unquoteIdent(item[1]) + p.src[item[1].end:item[i-1].end]
+		}
+		p.typeSize(&col, item[1:i])
+		if slices.Contains([]string{"serial", "bigserial", "smallserial", "serial2", "serial4", "serial8"}, strings.ToLower(col.Type)) {
+			col.Identity = true
+		}
+	}
+	t.Columns = append(t.Columns, col)
+	p.columnConstraints(ti, len(t.Columns)-1, item[i:])
+}
+
+// columnConstraints applies the constraints that follow the type in the
+// definition of column ci of table ti.
+func (p *parser) columnConstraints(ti, ci int, toks []token) {
+	col := func() *introspect.Column { return &p.s.Tables[ti].Columns[ci] }
+	name := col().Name
+	var constraint string
+	for i := 0; i < len(toks); {
+		switch {
+		case isKw(toks, i, "CONSTRAINT") && i+1 < len(toks):
+			constraint = p.unquoteIdent(toks[i+1])
+			i += 2
+			continue
+		case isKw(toks, i, "NOT") && isKw(toks, i+1, "NULL"):
+			col().Nullable = false
+			i += 2
+		case isKw(toks, i, "DEFAULT"):
+			j := exprEnd(toks, i+1)
+			col().Default = ptr(p.span(toks[i+1 : j]))
+			if strings.HasPrefix(strings.ToLower(*col().Default), "nextval(") {
+				col().Identity = true
+			}
+			i = j
+		case isKw(toks, i, "PRIMARY"):
+			p.primaryKey(ti, constraint, []string{name})
+			i = skipKw(toks, i+1, "KEY", "ASC", "DESC", "CLUSTERED", "NONCLUSTERED")
+		case isKw(toks, i, "UNIQUE"):
+			p.unique(ti, constraint, []string{name})
+			i = skipKw(toks, i+1, "KEY", "CLUSTERED", "NONCLUSTERED")
+		case isKw(toks, i, "CHECK") && i+1 < len(toks) && toks[i+1].kind == group:
+			p.s.Tables[ti].Constraints = append(p.s.Tables[ti].Constraints,
+				introspect.Constraint{Name: constraint, Type: introspect.ConstraintCheck, Columns: []string{name}, Expression: p.innerSpan(toks[i+1])})
+			i += 2
+		case isKw(toks, i, "REFERENCES"):
+			fk := pendingFK{from: []string{name}}
+			fk.fk.Constraint = constraint
+			i = p.references(toks, i, &fk)
+			p.addFK(ti, fk)
+		case isKw(toks, i, "COLLATE") && i+1 < len(toks):
+			col().Collation = ptr(p.unquoteIdent(toks[i+1]))
+			i += 2
+		case isKw(toks, i, "COMMENT") && i+1 < len(toks) && toks[i+1].kind == str:
+			col().Comment = ptr(p.unquote(toks[i+1]))
+			i += 2
+		case isKw(toks, i, "AUTO_INCREMENT", "AUTOINCREMENT", "IDENTITY"):
+			col().Identity = true
+			i++
+			if i < len(toks) && toks[i].kind == group {
+				i++
+			}
+		case isKw(toks, i, "GENERATED", "AS"):
+			// GENERATED {ALWAYS | BY DEFAULT} AS IDENTITY [(options)]
+			// or [GENERATED ALWAYS] AS (expr) [STORED | VIRTUAL | PERSISTED]
+			j := i
+			for j < len(toks) && !isKw(toks, j, "AS") {
+				j++
+			}
+			switch {
+			case isKw(toks, j+1, "IDENTITY"):
+				col().Identity = true
+				i = j + 2
+				if i < len(toks) && toks[i].kind == group {
+					i++
+				}
+			case j+1 < len(toks) && toks[j+1].kind == group:
+				col().Generated = ptr(p.innerSpan(toks[j+1]))
+				i = skipKw(toks, j+2, "STORED", "VIRTUAL", "PERSISTED")
+			default:
+				i = j + 1
+			}
+		default:
+			i++
+		}
+		// a constraint name only applies to the constraint that follows it
+		constraint = ""
+	}
+}
+
+// columnKeywords end the type of a column definition.
+var columnKeywords = []string{
+	"NOT", "NULL", "DEFAULT", "PRIMARY", "UNIQUE", "CHECK", "REFERENCES", "CONSTRAINT",
+	"COLLATE", "COMMENT", "AUTO_INCREMENT", "AUTOINCREMENT", "IDENTITY", "GENERATED", "AS",
+	"ON", "CHARSET", "SPARSE", "ROWGUIDCOL", "FILESTREAM", "MASKED", "VISIBLE", "INVISIBLE",
+	"STORAGE", "COLUMN_FORMAT", "ENCODING",
+}
+
+// columnKeyword reports whether toks[i] starts a column constraint or
+// option. CHARACTER only does as CHARACTER SET, it is also a type.
+func columnKeyword(toks []token, i int) bool {
+	if isKw(toks, i, "CHARACTER") {
+		return isKw(toks, i+1, "SET")
+	}
+	return isKw(toks, i, columnKeywords...)
+}
+
+// exprEnd returns the end of the expression starting at toks[i], at least
+// one token long, such as a default value.
+func exprEnd(toks []token, i int) int {
+	if i >= len(toks) {
+		return i
+	}
+	i++
+	for i < len(toks) && !columnKeyword(toks, i) {
+		i++
+	}
+	return i
+}
+
+// typeSize sets the maximum length or the precision and scale of col
+// from the arguments of its type, such as VARCHAR(20) or DECIMAL(10, 2).
+func (p *parser) typeSize(col *introspect.Column, toks []token) {
+	if len(toks) < 2 || toks[0].kind == group {
+		return
+	}
+	var args []*int64
+	for _, t := range toks[1:] {
+		if t.kind != group {
+			continue
+		}
+		for _, item := range splitItems(p.inner(t)) {
+			var n *int64
+			if len(item) == 1 {
+				if v, err := strconv.ParseInt(item[0].text, 10, 64); err == nil {
+					n = &v
+				}
+			}
+			args = append(args, n)
+		}
+		break
+	}
+	if len(args) == 0 {
+		return
+	}
+	switch strings.ToLower(p.unquoteIdent(toks[0])) {
+	case "char", "varchar", "nchar", "nvarchar", "character", "varchar2", "nvarchar2", "binary", "varbinary", "bit":
+		col.MaxLength = args[0]
+	case "decimal", "numeric", "dec", "number":
+		col.Precision = args[0]
+		if len(args) > 1 {
+			col.Scale = args[1]
+		}
+	}
+}
+
+// tableConstraint parses a table constraint of table ti. notValidated
+// marks foreign keys added WITH NOCHECK.
+func (p *parser) tableConstraint(ti int, item []token, notValidated bool) {
+	t := &p.s.Tables[ti]
+	var name string
+	i := 0
+	if isKw(item, 0, "CONSTRAINT") && len(item) > 1 && !isKw(item, 1, "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "EXCLUDE", "DEFAULT") {
+		name = p.unquoteIdent(item[1])
+		i = 2
+	} else if isKw(item, 0, "CONSTRAINT") {
+		i = 1
+	}
+	g := nextGroup(item, i)
+	switch {
+	case isKw(item, i, "PRIMARY") && g >= 0:
+		p.primaryKey(ti, name, p.columnNames(item[g]))
+	case isKw(item, i, "UNIQUE") && g >= 0:
+		// MySQL: UNIQUE KEY name (cols)
+		for j := i + 1; j < g && name == ""; j++ {
+			if !isKw(item, j, "KEY", "INDEX", "CLUSTERED", "NONCLUSTERED", "NULLS", "DISTINCT") {
+				name = p.unquoteIdent(item[j])
+			}
+		}
+		p.unique(ti, name, p.columnNames(item[g]))
+	case isKw(item, i, "FOREIGN") && g >= 0:
+		// MySQL: FOREIGN KEY name (cols)
+		for j := i + 1; j < g && name == ""; j++ {
+			if !isKw(item, j, "KEY") {
+				name = p.unquoteIdent(item[j])
+			}
+		}
+		r := g + 1
+		for r < len(item) && !isKw(item, r, "REFERENCES") {
+			r++
+		}
+		if r == len(item) {
+			return
+		}
+		fk := pendingFK{from: p.columnNames(item[g])}
+		fk.fk.Constraint = name
+		p.references(item, r, &fk)
+		fk.fk.NotValidated = fk.fk.NotValidated || notValidated
+		p.addFK(ti, fk)
+	case isKw(item, i, "CHECK") && g >= 0:
+		t.Constraints = append(t.Constraints, introspect.Constraint{Name: name, Type: introspect.ConstraintCheck, Expression: p.innerSpan(item[g])})
+	case isKw(item, i, "EXCLUDE"):
+		t.Constraints = append(t.Constraints, introspect.Constraint{Name: name, Type: introspect.ConstraintExclude, Expression: p.span(item[i+1:])})
+	case isKw(item, i, "DEFAULT"):
+		// SQL Server: [CONSTRAINT name] DEFAULT expr FOR col
+		f := i + 1
+		for f < len(item) && !isKw(item, f, "FOR") {
+			f++
+		}
+		if f+1 >= len(item) || f == i+1 {
+			return
+		}
+		col := p.unquoteIdent(item[f+1])
+		ci, ok := findColumn(t, col)
+		if !ok {
+			p.warn(qualified(t.Schema, t.Name), fmt.Errorf("default for unknown column %s", col))
+			return
+		}
+		t.Columns[ci].Default = ptr(p.span(item[i+1 : f]))
+	}
+}
+
+// inlineIndex parses a MySQL KEY or INDEX definition inside CREATE TABLE.
+func (p *parser) inlineIndex(ti int, item []token) {
+	idx := introspect.Index{}
+	g := nextGroup(item, 0)
+	if g < 0 {
+		return
+	}
+	for j := 0; j < g; j++ {
+		switch {
+		case isKw(item, j, "FULLTEXT", "SPATIAL"):
+			idx.Method = strings.ToLower(item[j].text)
+		case isKw(item, j, "KEY", "INDEX"):
+		default:
+			idx.Name = p.unquoteIdent(item[j])
+		}
+	}
+	idx.Columns = p.indexColumns(item[g])
+	if j := skipKw(item, g+1, "USING"); j > g+1 && j < len(item) {
+		idx.Method = strings.ToLower(item[j].text)
+	}
+	t := &p.s.Tables[ti]
+	t.Indexes = append(t.Indexes, idx)
+}
+
+// primaryKey marks cols of table ti as the primary key and adds its index.
+func (p *parser) primaryKey(ti int, name string, cols []string) {
+	t := &p.s.Tables[ti]
+	for _, c := range cols {
+		ci, ok := findColumn(t, c)
+		if !ok {
+			p.warn(qualified(t.Schema, t.Name), fmt.Errorf("primary key on unknown column %s", c))
+			continue
+		}
+		t.Columns[ci].PK = true
+		t.Columns[ci].Nullable = false
+	}
+	for _, idx := range t.Indexes {
+		if idx.Primary {
+			// declared twice, in CREATE TABLE and by ALTER TABLE
+			return
+		}
+	}
+	if name == "" && p.dialect == "mysql" {
+		name = "PRIMARY"
+	}
+	t.Indexes = append(t.Indexes, introspect.Index{Name: name, Columns: cols, Unique: true, Primary: true})
+}
+
+// unique adds a unique constraint on cols to table ti. MySQL has unique
+// keys instead, which are indexes.
+func (p *parser) unique(ti int, name string, cols []string) {
+	t := &p.s.Tables[ti]
+	if len(cols) == 0 {
+		return
+	}
+	if p.dialect == "mysql" {
+		t.Indexes = append(t.Indexes, introspect.Index{Name: cmp.Or(name, cols[0]), Columns: cols, Unique: true})
+		return
+	}
+	t.Constraints = append(t.Constraints, introspect.Constraint{Name: name, Type: introspect.ConstraintUnique, Columns: cols})
+}
+
+// references parses REFERENCES table [(cols)] and the options of the
+// foreign key that follow, with toks[i] being REFERENCES. It returns the
+// index of the first token after them.
+func (p *parser) references(toks []token, i int, fk *pendingFK) int {
+	fk.fk.ToSchema, fk.fk.ToTable, i = p.name(toks, i+1)
+	if i < len(toks) && toks[i].kind == group {
+		fk.to = p.columnNames(toks[i])
+		i++
+	}
+	for i < len(toks) {
+		switch {
+		case isKw(toks, i, "MATCH") && i+1 < len(toks):
+			fk.fk.MatchType = strings.ToUpper(toks[i+1].text)
+			i += 2
+		case isKw(toks, i, "ON") && isKw(toks, i+1, "DELETE", "UPDATE"):
+			action, n := refAction(toks[i+2:])
+			if isKw(toks, i+1, "DELETE") {
+				fk.fk.OnDelete = action
+			} else {
+				fk.fk.OnUpdate = action
+			}
+			i += 2 + n
+		case isKw(toks, i, "DEFERRABLE"):
+			fk.fk.Deferrable = true
+			i++
+		case isKw(toks, i, "NOT") && isKw(toks, i+1, "DEFERRABLE"):
+			i += 2
+		case isKw(toks, i, "INITIALLY") && i+1 < len(toks):
+			fk.fk.InitiallyDeferred = isKw(toks, i+1, "DEFERRED")
+			i += 2
+		case isKw(toks, i, "NOT") && isKw(toks, i+1, "VALID"):
+			fk.fk.NotValidated = true
+			i += 2
+		case isKw(toks, i, "NOT") && isKw(toks, i+1, "FOR") && isKw(toks, i+2, "REPLICATION"):
+			i += 3
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// refAction returns the referential action at the start of toks and the
+// number of tokens it takes.
+func refAction(toks []token) (string, int) {
+	switch {
+	case isKw(toks, 0, "NO", "SET") && len(toks) > 1:
+		return strings.ToUpper(toks[0].text + " " + toks[1].text), 2
+	case len(toks) > 0:
+		return strings.ToUpper(toks[0].text), 1
+	}
+	return "", 0
+}
+
+// addFK adds a foreign key of table ti; its columns are paired in finish.
+func (p *parser) addFK(ti int, fk pendingFK) {
+	t := p.s.Tables[ti]
+	fk.fk.FromSchema, fk.fk.FromTable = t.Schema, t.Name
+	p.fks = append(p.fks, fk)
+}
+
+// finish pairs the columns of the foreign keys, taking the primary key of
+// the referenced table where the key names no columns.
+func (p *parser) finish() {
+	for _, pf := range p.fks {
+		fk := pf.fk
+		to := pf.to
+		if len(to) == 0 {
+			if ti, ok := p.table(fk.ToSchema, fk.ToTable); ok {
+				for _, c := range p.s.Tables[ti].Columns {
+					if c.PK {
+						to = append(to, c.Name)
+					}
+				}
+			}
+		}
+		if len(to) != len(pf.from) {
+			p.warn(qualified(fk.FromSchema, fk.FromTable),
+				fmt.Errorf("foreign key to %s: %d columns reference %d", qualified(fk.ToSchema, fk.ToTable), len(pf.from), len(to)))
+			continue
+		}
+		for k := range to {
+			fk.AddColumn(pf.from[k], to[k])
+		}
+		p.s.ForeignKeys = append(p.s.ForeignKeys, fk)
+	}
+	p.fks = nil
+}
+
+// createIndex parses CREATE [UNIQUE] INDEX [name] ON table [USING method]
+// (columns) [INCLUDE (columns)] [WHERE predicate], with toks[i] following
+// INDEX.
+func (p *parser) createIndex(toks []token, i int) {
+	idx := introspect.Index{Unique: hasKw(toks[:i], "UNIQUE")}
+	for _, m := range []string{"FULLTEXT", "SPATIAL", "CLUSTERED", "NONCLUSTERED"} {
+		if hasKw(toks[:i], m) {
+			idx.Method = strings.ToLower(m)
+		}
+	}
+	i = skipKw(toks, i, "CONCURRENTLY", "IF", "NOT", "EXISTS")
+	if !isKw(toks, i, "ON") {
+		_, idx.Name, i = p.name(toks, i)
+	}
+	if isKw(toks, i, "USING") && i+1 < len(toks) {
+		idx.Method = strings.ToLower(toks[i+1].text)
+		i += 2
+	}
+	if !isKw(toks, i, "ON") {
+		return
+	}
+	schema, name, i := p.name(toks, skipKw(toks, i+1, "ONLY"))
+	ti, ok := p.table(schema, name)
+	if !ok {
+		p.warn(qualified(schema, name), fmt.Errorf("index %s on unknown table", idx.Name))
+		return
+	}
+	for ; i < len(toks); i++ {
+		switch {
+		case isKw(toks, i, "USING") && i+1 < len(toks) && toks[i+1].kind == word:
+			idx.Method = strings.ToLower(toks[i+1].text)
+			i++
+		case toks[i].kind == group && idx.Columns == nil:
+			idx.Columns = p.indexColumns(toks[i])
+		case isKw(toks, i, "INCLUDE") && i+1 < len(toks) && toks[i+1].kind == group:
+			idx.Include = p.columnNames(toks[i+1])
+			i++
+		case isKw(toks, i, "WHERE"):
+			j := i + 1
+			for j < len(toks) && !isKw(toks, j, "WITH", "ON") {
+				j++
+			}
+			idx.Predicate = p.span(toks[i+1 : j])
+			i = j - 1
+		}
+	}
+	t := &p.s.Tables[ti]
+	t.Indexes = append(t.Indexes, idx)
+}
+
+// createView parses CREATE [MATERIALIZED] VIEW name [(columns)] AS query,
+// with toks[i] following VIEW.
+func (p *parser) createView(toks []token, i int) {
+	v := introspect.View{Materialized: hasKw(toks[:i], "MATERIALIZED"), Columns: []introspect.Column{}}
+	v.Schema, v.Name, i = p.name(toks, skipKw(toks, i, "IF", "NOT", "EXISTS"))
+	if i < len(toks) && toks[i].kind == group {
+		for _, c := range p.columnNames(toks[i]) {
+			v.Columns = append(v.Columns, introspect.Column{Name: c, Nullable: true})
+		}
+		i++
+	}
+	for i < len(toks) && !isKw(toks, i, "AS") {
+		i++
+	}
+	v.Definition = p.span(toks[min(i+1, len(toks)):])
+	p.s.Views = append(p.s.Views, v)
+}
+
+// createType parses CREATE TYPE name AS ENUM (labels), with toks[i]
+// following TYPE. Other kinds of types are skipped.
+func (p *parser) createType(toks []token, i int) {
+	schema, name, i := p.name(toks, i)
+	if !isKw(toks, i, "AS") || !isKw(toks, i+1, "ENUM") || i+2 >= len(toks) || toks[i+2].kind != group {
+		return
+	}
+	ut := introspect.UserType{Schema: schema, Name: name, Kind: introspect.TypeEnum}
+	for _, item := range splitItems(p.inner(toks[i+2])) {
+		if len(item) == 1 && item[0].kind == str {
+			ut.Labels = append(ut.Labels, p.unquote(item[0]))
+		}
+	}
+	p.s.Types = append(p.s.Types, ut)
+}
+
+// alterTable applies the actions of ALTER TABLE that add columns and
+// constraints, set defaults and attach partitions.
+func (p *parser) alterTable(toks []token) {
+	i := skipKw(toks, 2, "IF", "EXISTS", "ONLY")
+	schema, name, i := p.name(toks, i)
+	ti, ok := p.table(schema, name)
+	if !ok {
+		p.warn(qualified(schema, name), errors.New("alter of unknown table"))
+		return
+	}
+	adding := false
+	for _, action := range splitItems(toks[i:]) {
+		// SQL Server: WITH CHECK | NOCHECK ADD CONSTRAINT ...
+		notValidated := isKw(action, 0, "WITH") && isKw(action, 1, "NOCHECK")
+		if isKw(action, 0, "WITH") {
+			action = action[min(2, len(action)):]
+		}
+		switch {
+		case isKw(action, 0, "ADD"):
+			adding = true
+			action = action[skipKw(action, 1, "COLUMN", "IF", "NOT", "EXISTS"):]
+		case isKw(action, 0, "ALTER", "MODIFY", "DROP", "ATTACH", "OWNER", "ENABLE", "DISABLE", "SET", "RENAME", "CHECK", "NOCHECK", "CLUSTER", "REPLICA", "INHERIT", "VALIDATE", "FORCE", "NO", "RESET"):
+			adding = false
+		case !adding:
+			continue
+		}
+		switch {
+		case len(action) == 0:
+		case adding && isKw(action, 0, "CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "EXCLUDE", "DEFAULT"):
+			p.tableConstraint(ti, action, notValidated)
+		case adding && isKw(action, 0, "KEY", "INDEX", "FULLTEXT", "SPATIAL"):
+			p.inlineIndex(ti, action)
+		case adding:
+			p.column(ti, action)
+		case isKw(action, 0, "ALTER"):
+			p.alterColumn(ti, action[skipKw(action, 1, "COLUMN"):])
+		case isKw(action, 0, "ATTACH") && isKw(action, 1, "PARTITION"):
+			ps, pn, j := p.name(action, 2)
+			p.addPartition(ti, ps, pn, action[j:])
+		}
+	}
+}
+
+// alterColumn applies ALTER COLUMN col SET DEFAULT expr, SET | DROP NOT
+// NULL and ADD GENERATED ... AS IDENTITY to table ti.
+func (p *parser) alterColumn(ti int, action []token) {
+	if len(action) == 0 {
+		return
+	}
+	t := &p.s.Tables[ti]
+	ci, ok := findColumn(t, p.unquoteIdent(action[0]))
+	if !ok {
+		p.warn(qualified(t.Schema, t.Name), fmt.Errorf("alter of unknown column %s", p.unquoteIdent(action[0])))
+		return
+	}
+	c := &t.Columns[ci]
+	switch {
+	case isKw(action, 1, "SET") && isKw(action, 2, "DEFAULT") && len(action) > 3:
+		c.Default = ptr(p.span(action[3:]))
+		if strings.HasPrefix(strings.ToLower(*c.Default), "nextval(") {
+			c.Identity = true
+		}
+	case isKw(action, 1, "DROP") && isKw(action, 2, "DEFAULT"):
+		c.Default = nil
+	case isKw(action, 1, "SET") && isKw(action, 2, "NOT"):
+		c.Nullable = false
+	case isKw(action, 1, "DROP") && isKw(action, 2, "NOT"):
+		c.Nullable = !c.PK
+	case isKw(action, 1, "ADD") && isKw(action, 2, "GENERATED"):
+		c.Identity = true
+	}
+}
+
+// comment applies COMMENT ON TABLE | VIEW | COLUMN | TYPE name IS 'text'.
+func (p *parser) comment(toks []token) {
+	i := 2
+	if isKw(toks, i, "MATERIALIZED") {
+		i++
+	}
+	if i >= len(toks) {
+		return
+	}
+	objKind := strings.ToUpper(toks[i].text)
+	parts, i := p.nameParts(toks, i+1)
+	if !isKw(toks, i, "IS") || i+1 >= len(toks) || len(parts) == 0 {
+		return
+	}
+	var text *string
+	if toks[i+1].kind == str {
+		text = ptr(p.unquote(toks[i+1]))
+	}
+	switch objKind {
+	case "TABLE", "VIEW", "TYPE":
+		schema, name := p.qualify(parts)
+		p.setComment(objKind, schema, name, "", text)
+	case "COLUMN":
+		if len(parts) < 2 {
+			return
+		}
+		schema, name := p.qualify(parts[:len(parts)-1])
+		p.setComment("COLUMN", schema, name, parts[len(parts)-1], text)
+	}
+}
+
+// extendedProperty applies the MS_Description extended properties SQL
+// Server scripts use for comments:
+// EXEC sp_addextendedproperty @name = N'MS_Description', @value = N'text',
+// @level0type = N'SCHEMA', @level0name = N'dbo', @level1type = N'TABLE', ...
+func (p *parser) extendedProperty(toks []token) {
+	_, proc, i := p.name(toks, 1)
+	if !strings.EqualFold(proc, "sp_addextendedproperty") && !strings.EqualFold(proc, "sp_updateextendedproperty") {
+		return
+	}
+	positional := []string{"name", "value", "level0type", "level0name", "level1type", "level1name", "level2type", "level2name"}
+	args := map[string]string{}
+	for k, item := range splitItems(toks[i:]) {
+		key := ""
+		if k < len(positional) {
+			key = positional[k]
+		}
+		var value string
+		for _, t := range item {
+			switch {
+			case t.kind == word && strings.HasPrefix(t.text, "@"):
+				key = strings.ToLower(strings.TrimPrefix(strings.SplitN(t.text, "=", 2)[0], "@"))
+			case t.kind == str:
+				value = p.unquote(t)
+			case t.kind == ident || t.kind == word && !strings.EqualFold(t.text, "N") && t.text != "=":
+				value = p.unquoteIdent(t)
+			}
+		}
+		args[key] = value
+	}
+	if !strings.EqualFold(args["name"], "MS_Description") || !strings.EqualFold(args["level0type"], "SCHEMA") {
+		return
+	}
+	text := args["value"]
+	column := ""
+	if strings.EqualFold(args["level2type"], "COLUMN") {
+		column = args["level2name"]
+	}
+	p.setComment(strings.ToUpper(args["level1type"]), args["level0name"], args["level1name"], column, &text)
+}
+
+// setComment sets the comment of table, view or type name, or of its
+// column if column is set.
+func (p *parser) setComment(objKind, schema, name, column string, text *string) {
+	switch {
+	case objKind == "VIEW" && column == "":
+		for k := range p.s.Views {
+			if v := &p.s.Views[k]; strings.EqualFold(v.Schema, schema) && strings.EqualFold(v.Name, name) {
+				v.Comment = text
+			}
+		}
+	case objKind == "TYPE":
+		for k := range p.s.Types {
+			if ut := &p.s.Types[k]; strings.EqualFold(ut.Schema, schema) && strings.EqualFold(ut.Name, name) {
+				ut.Comment = text
+			}
+		}
+	default:
+		ti, ok := p.table(schema, name)
+		if !ok {
+			return
+		}
+		t := &p.s.Tables[ti]
+		if column == "" {
+			t.Comment = text
+			return
+		}
+		if ci, ok := findColumn(t, column); ok {
+			t.Columns[ci].Comment = text
+		}
+	}
+}
+
+// searchPath applies SET search_path = schema, ...: unqualified names
+// refer to its first schema.
+func (p *parser) searchPath(toks []token) {
+	toks = toks[skipKw(toks, 0, "TO", "="):]
+	if len(toks) == 0 {
+		return
+	}
+	if schema := p.unquoteIdent(toks[0]); schema != "" {
+		p.schema = schema
+	}
+}
+
+// name parses a possibly qualified name at toks[i] and returns its schema,
+// defaulting to the schema of unqualified names, its name and the index of
+// the next token. A database qualifier is dropped.
+func (p *parser) name(toks []token, i int) (schema, name string, next int) {
+	parts, next := p.nameParts(toks, i)
+	schema, name = p.qualify(parts)
+	return schema, name, next
+}
+
+// nameParts returns the dot separated identifiers at toks[i].
+func (p *parser) nameParts(toks []token, i int) ([]string, int) {
+	var parts []string
+	for i < len(toks) && (toks[i].kind == word || toks[i].kind == ident) {
+		parts = append(parts, p.unquoteIdent(toks[i]))
+		i++
+		if i >= len(toks) || toks[i].text != "." {
+			break
+		}
+		i++
+	}
+	return parts, i
+}
+
+// qualify returns the schema and name of the parts of a qualified name.
+func (p *parser) qualify(parts []string) (schema, name string) {
+	switch len(parts) {
+	case 0:
+		return "", ""
+	case 1:
+		return p.schema, parts[0]
+	}
+	return parts[len(parts)-2], parts[len(parts)-1]
+}
+
+// table returns the index of table schema.name in s.Tables.
+func (p *parser) table(schema, name string) (int, bool) {
+	ti, ok := p.tables[tableKey(schema, name)]
+	return ti, ok
+}
+
+// columnNames returns the column names of a parenthesized column list.
+func (p *parser) columnNames(g token) []string {
+	var cols []string
+	for _, item := range splitItems(p.inner(g)) {
+		if len(item) > 0 {
+			cols = append(cols, p.unquoteIdent(item[0]))
+		}
+	}
+	return cols
+}
+
+// indexColumns returns the key columns of an index, with expressions as
+// written and without sort order, operator classes or MySQL key prefixes.
+func (p *parser) indexColumns(g token) []string {
+	var cols []string
+	for _, item := range splitItems(p.inner(g)) {
+		if len(item) > 0 {
+			cols = append(cols, p.columnOrExpr(item))
+		}
+	}
+	return cols
+}
+
+// columnOrExpr returns the column name of item, or the expression if item
+// is not a plain column. item must not be empty.
+func (p *parser) columnOrExpr(item []token) string {
+	if len(item) == 1 || item[1].kind != group || p.dialect == "mysql" {
+		if item[0].kind == word || item[0].kind == ident {
+			return p.unquoteIdent(item[0])
+		}
+	}
+	return p.span(item)
+}
+
+// span returns the script text covered by toks.
+func (p *parser) span(toks []token) string {
+	if len(toks) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(p.src[toks[0].pos:toks[len(toks)-1].end])
+}
+
+// innerSpan returns the script text inside group g.
+func (p *parser) innerSpan(g token) string {
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(g.text, "("), ")"))
+}
+
+// unquoteIdent returns the identifier t. Unquoted Postgres identifiers are
+// folded to lower case.
+func (p *parser) unquoteIdent(t token) string {
+	s := t.text
+	switch {
+	case t.kind == str:
+		return p.unquote(t)
+	case t.kind != ident || len(s) < 2:
+		if p.dialect == "postgres" {
+			return strings.ToLower(s)
+		}
+		return s
+	case s[0] == '[':
+		return strings.TrimSuffix(s[1:], "]")
+	}
+	q := s[:1]
+	return strings.ReplaceAll(s[1:len(s)-1], q+q, q)
+}
+
+// unquote returns the value of string literal t.
+func (p *parser) unquote(t token) string {
+	s := t.text
+	if t.kind != str || len(s) < 2 {
+		return s
+	}
+	if s[0] == '$' {
+		tag := dollarTag.FindString(s)
+		return strings.TrimSuffix(strings.TrimPrefix(s, tag), tag)
+	}
+	s = s[1 : len(s)-1]
+	if p.mysql {
+		s = strings.NewReplacer(`\'`, `'`, `\"`, `"`, `\\`, `\`, `\n`, "\n", `\t`, "\t", `\r`, "\r").Replace(s)
+	}
+	return strings.ReplaceAll(s, "''", "'")
+}
+
+// warn records that the statement about object could not be applied.
+func (p *parser) warn(object string, err error) {
+	p.s.AddWarning(object, "ddl", err)
+}
+
+// isKw reports whether toks[i] is one of the keywords kws.
+func isKw(toks []token, i int, kws ...string) bool {
+	if i < 0 || i >= len(toks) || toks[i].kind != word {
+		return false
+	}
+	for _, kw := range kws {
+		if strings.EqualFold(toks[i].text, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKw reports whether toks contains keyword kw.
+func hasKw(toks []token, kw string) bool {
+	for i := range toks {
+		if isKw(toks, i, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipKw returns the index of the first token from toks[i] on that is not
+// one of the keywords kws.
+func skipKw(toks []token, i int, kws ...string) int {
+	for isKw(toks, i, kws...) {
+		i++
+	}
+	return i
+}
+
+// skipEquals skips an optional = at toks[i], as in COMMENT = 'text'.
+func skipEquals(toks []token, i int) int {
+	if i < len(toks) && toks[i].kind == word && toks[i].text == "=" {
+		return i + 1
+	}
+	return i
+}
+
+// nextGroup returns the index of the first group from toks[i] on, or -1.
+func nextGroup(toks []token, i int) int {
+	for ; i < len(toks); i++ {
+		if toks[i].kind == group {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitItems splits a token list at commas.
+func splitItems(toks []token) [][]token {
+	var items [][]token
+	var cur []token
+	for _, t := range toks {
+		if t.kind == punct && t.text == "," {
+			items = append(items, cur)
+			cur = nil
+			continue
+		}
+		cur = append(cur, t)
+	}
+	return append(items, cur)
+}
+
+// findColumn returns the index of column name in t, ignoring case.
+func findColumn(t *introspect.Table, name string) (int, bool) {
+	for ci, c := range t.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return ci, true
+		}
+	}
+	return 0, false
+}
+
+// tableKey is the key of table schema.name in parser.tables. Names are
+// compared ignoring case.
+func tableKey(schema, name string) string {
+	return strings.ToLower(schema) + "\x00" + strings.ToLower(name)
+}
+
+// qualified returns schema.name, or name without a schema.
+func qualified(schema, name string) string {
+	if schema == "" {
+		return name
+	}
+	return schema + "." + name
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
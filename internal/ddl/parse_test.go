@@ -0,0 +1,273 @@
+package ddl
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"erddiagram/internal/introspect"
+)
+
+func TestParseDumps(t *testing.T) {
+	var tests = []struct {
+		name    string
+		file    string
+		dialect string
+		tables  []string
+		fks     []string
+		indexes int
+	}{
+		{"pg_dump", "./testdata/pg_dump.sql", "postgres",
+			[]string{"public.customers", "public.orders", "public.orders_2024"},
+			[]string{"public.orders(customer_id) -> public.customers(id)"}, 4},
+		{"mysqldump", "./testdata/mysqldump.sql", "mysql",
+			[]string{"customers", "orders"},
+			[]string{"orders(customer_id) -> customers(id)"}, 6},
+		{"sql server script", "./testdata/mssql.sql", "sqlserver",
+			[]string{"dbo.Customers", "sales.Orders"},
+			[]string{"sales.Orders(CustomerId) -> dbo.Customers(Id)"}, 3},
+		{"sqlite schema", "./testdata/sqlite.sql", "sqlite",
+			[]string{"customers", "orders"},
+			[]string{"orders(customer_id) -> customers(id)"}, 3},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatalf("\ngot unexpected error: \"%v\"", err)
+			}
+			if d := Detect(string(b)); d != tt.dialect {
+				t.Errorf("\ngot dialect %q, wanted %q", d, tt.dialect)
+			}
+			s, err := Parse(string(b), "")
+			if err != nil {
+				t.Fatalf("\ngot unexpected error: \"%v\"", err)
+			}
+			var tables []string
+			indexes := 0
+			for _, tab := range s.Tables {
+				tables = append(tables, qualified(tab.Schema, tab.Name))
+				indexes += len(tab.Indexes)
+			}
+			if !reflect.DeepEqual(tables, tt.tables) {
+				t.Errorf("\ngot tables %v, wanted %v", tables, tt.tables)
+			}
+			var fks []string
+			for _, fk := range s.ForeignKeys {
+				fks = append(fks, qualified(fk.FromSchema, fk.FromTable)+"("+fk.FromColumn+") -> "+qualified(fk.ToSchema, fk.ToTable)+"("+fk.ToColumn+")")
+			}
+			if !reflect.DeepEqual(fks, tt.fks) {
+				t.Errorf("\ngot foreign keys %v, wanted %v", fks, tt.fks)
+			}
+			if indexes != tt.indexes {
+				t.Errorf("\ngot %d indexes, wanted %d", indexes, tt.indexes)
+			}
+			if len(s.Warnings) > 0 {
+				t.Errorf("\ngot warnings %v, wanted none", s.Warnings)
+			}
+		})
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	var tests = []struct {
+		name    string
+		dialect string
+		ddl     string
+		columns []introspect.Column
+	}{
+		{"postgres", "postgres",
+			`CREATE TABLE t (Id serial PRIMARY KEY, "Name" varchar(20) NOT NULL DEFAULT 'x, y', amount numeric(10, 2) COLLATE "C");
+			 COMMENT ON COLUMN t."Name" IS 'the name';`,
+			[]introspect.Column{
+				{Name: "id", Type: "serial", PK: true, Identity: true},
+				{Name: "Name", Type: "varchar(20)", Default: ptr("'x, y'"), MaxLength: ptr[int64](20), Comment: ptr("the name")},
+				{Name: "amount", Type: "numeric(10, 2)", Nullable: true, Precision: ptr[int64](10), Scale: ptr[int64](2), Collation: ptr("C")},
+			}},
+		{"mysql", "mysql",
+			"CREATE TABLE `t` (`id` int NOT NULL AUTO_INCREMENT, `total` int AS (`id` * 2) STORED, `note` text COMMENT 'it\\'s', PRIMARY KEY (`id`))",
+			[]introspect.Column{
+				{Name: "id", Type: "int", PK: true, Identity: true},
+				{Name: "total", Type: "int", Nullable: true, Generated: ptr("`id` * 2")},
+				{Name: "note", Type: "text", Nullable: true, Comment: ptr("it's")},
+			}},
+		{"sql server", "sqlserver",
+			"CREATE TABLE [t] ([id] [int] IDENTITY(1,1) NOT NULL, [code] [nchar](3) NULL)\nGO\nALTER TABLE [t] ADD CONSTRAINT [DF_code] DEFAULT (N'abc') FOR [code]\nGO",
+			[]introspect.Column{
+				{Name: "id", Type: "int", Identity: true},
+				{Name: "code", Type: "nchar(3)", Nullable: true, Default: ptr("(N'abc')"), MaxLength: ptr[int64](3)},
+			}},
+		{"sqlite", "sqlite",
+			`CREATE TABLE t (a INTEGER PRIMARY KEY AUTOINCREMENT, b, c TEXT GENERATED ALWAYS AS (upper(b)) VIRTUAL)`,
+			[]introspect.Column{
+				{Name: "a", Type: "INTEGER", PK: true, Identity: true},
+				{Name: "b", Nullable: true},
+				{Name: "c", Type: "TEXT", Nullable: true, Generated: ptr("upper(b)")},
+			}},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.ddl, tt.dialect)
+			if err != nil {
+				t.Fatalf("\ngot unexpected error: \"%v\"", err)
+			}
+			if !reflect.DeepEqual(s.Tables[0].Columns, tt.columns) {
+				t.Errorf("\ngot columns %+v, wanted %+v", s.Tables[0].Columns, tt.columns)
+			}
+		})
+	}
+}
+
+func TestParseForeignKeys(t *testing.T) {
+	var tests = []struct {
+		name string
+		ddl  string
+		fks  []introspect.ForeignKey
+	}{
+		{"composite key to primary key",
+			`CREATE TABLE a (x int, y int, PRIMARY KEY (x, y));
+			 CREATE TABLE b (x int, y int, CONSTRAINT fk_b FOREIGN KEY (x, y) REFERENCES a ON UPDATE CASCADE MATCH FULL);`,
+			[]introspect.ForeignKey{{
+				FromSchema: "public", FromTable: "b", FromColumn: "x, y",
+				ToSchema: "public", ToTable: "a", ToColumn: "x, y",
+				Columns:    []introspect.ColumnPair{{From: "x", To: "x"}, {From: "y", To: "y"}},
+				Constraint: "fk_b", OnUpdate: "CASCADE", MatchType: "FULL",
+			}}},
+		{"key declared before the referenced table",
+			`CREATE TABLE b (a_id int REFERENCES a (id) NOT VALID);
+			 CREATE TABLE a (id int PRIMARY KEY);`,
+			[]introspect.ForeignKey{{
+				FromSchema: "public", FromTable: "b", FromColumn: "a_id",
+				ToSchema: "public", ToTable: "a", ToColumn: "id",
+				Columns:      []introspect.ColumnPair{{From: "a_id", To: "id"}},
+				NotValidated: true,
+			}}},
+		{"unknown referenced table without columns",
+			`CREATE TABLE b (a_id int REFERENCES a)`,
+			[]introspect.ForeignKey{}},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.ddl, "postgres")
+			if err != nil {
+				t.Fatalf("\ngot unexpected error: \"%v\"", err)
+			}
+			if !reflect.DeepEqual(s.ForeignKeys, tt.fks) {
+				t.Errorf("\ngot foreign keys %+v, wanted %+v", s.ForeignKeys, tt.fks)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	var tests = []struct {
+		name    string
+		ddl     string
+		dialect string
+	}{
+		{"no tables", `CREATE INDEX ix ON t (a); SELECT 1;`, "postgres"},
+		{"unknown dialect", `CREATE TABLE t (a int)`, "cobol"},
+		{"empty", ``, ""},
+		{"truncated constraint", `CREATE TABLE t (CONSTRAINT`, "postgres"},
+		{"truncated column list", `CREATE TABLE t (a varchar(10)`, "postgres"},
+		{"truncated partition key", `CREATE TABLE t (a int) PARTITION BY RANGE (`, "postgres"},
+		{"unterminated string", `CREATE TABLE t (a int DEFAULT 'x);`, "postgres"},
+		{"unterminated identifier", "CREATE TABLE `t (a int);", "mysql"},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.ddl, tt.dialect); err == nil {
+				t.Errorf("\nexpected an error, did not receive one")
+			}
+		})
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	var tests = []struct {
+		name     string
+		dsn      string
+		tables   int
+		errIsNil bool
+	}{
+		{"path", "./testdata/sqlite.sql", 2, true},
+		{"file and dialect", "file:./testdata/sqlite.sql?dialect=sqlite", 2, true},
+		{"wrong dialect", "file:./testdata/sqlite.sql?dialect=oracle", 0, false},
+		{"missing file", "file:./testdata/no_such_file.sql", 0, false},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := fileSource{}.Load(t.Context(), tt.dsn)
+			if (err == nil) != tt.errIsNil {
+				if tt.errIsNil {
+					t.Errorf("\ngot unexpected error: \"%v\"", err)
+				} else {
+					t.Errorf("\nexpected an error, did not receive one")
+				}
+			} else if len(s.Tables) != tt.tables {
+				t.Errorf("\ngot %d tables, wanted %d", len(s.Tables), tt.tables)
+			}
+		})
+	}
+
+	v1, err := fileSource{}.Version("file:./testdata/sqlite.sql")
+	if err != nil || v1 == "" {
+		t.Errorf("\ngot version %q, %v, wanted a version", v1, err)
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	var tests = []struct {
+		name    string
+		file    string
+		dialect string
+	}{
+		{"pg_dump", "./testdata/pg_dump.sql", "postgres"},
+		{"mysqldump", "./testdata/mysqldump.sql", "mysql"},
+		{"sql server script", "./testdata/mssql.sql", "sqlserver"},
+		{"sqlite schema", "./testdata/sqlite.sql", "sqlite"},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatalf("\ngot unexpected error: \"%v\"", err)
+			}
+			// every prefix must parse or fail without panicking, also with
+			// its open parentheses closed, so that the statements are cut
+			// at every token
+			for i := range len(b) {
+				prefix := string(b[:i])
+				Parse(prefix, tt.dialect)
+				Parse(prefix+strings.Repeat(")", 4), tt.dialect)
+			}
+		})
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, file := range []string{"pg_dump.sql", "mysqldump.sql", "mssql.sql", "sqlite.sql"} {
+		b, err := os.ReadFile("./testdata/" + file)
+		if err != nil {
+			f.Fatalf("\ngot unexpected error: \"%v\"", err)
+		}
+		f.Add(string(b), "")
+	}
+	f.Add("CREATE TABLE t (CONSTRAINT)", "postgres")
+	f.Fuzz(func(t *testing.T, script, dialect string) {
+		Parse(script, dialect)
+	})
+}
@@ -0,0 +1,79 @@
+package ddl
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"erddiagram/internal/db"
+	"erddiagram/internal/introspect"
+)
+
+// detectRules guess the dialect of a script from constructs only one
+// dialect uses, checked in order.
+var detectRules = []struct {
+	dialect string
+	re      *regexp.Regexp
+}{
+	{"sqlserver", regexp.MustCompile(`(?im)^\s*GO\s*$|\[dbo\]|\bIDENTITY\s*\(|\bNVARCHAR\b|sp_addextendedproperty`)},
+	{"mysql", regexp.MustCompile("(?i)`|\\bENGINE\\s*=|\\bAUTO_INCREMENT\\b|^\\s*DELIMITER\\b")},
+	{"sqlite", regexp.MustCompile(`(?i)\bAUTOINCREMENT\b|\bWITHOUT\s+ROWID\b|sqlite_sequence`)},
+}
+
+// Detect guesses the dialect of a DDL script. It defaults to postgres.
+func Detect(script string) string {
+	for _, r := range detectRules {
+		if r.re.MatchString(script) {
+			return r.dialect
+		}
+	}
+	return "postgres"
+}
+
+// fileSource reads schemas from DDL files. Its dsn is the path of the
+// file, optionally prefixed with file: and followed by ?dialect=name.
+type fileSource struct{}
+
+func (fileSource) Load(ctx context.Context, dsn string) (introspect.Schema, error) {
+	path, dialect, err := parseDSN(dsn)
+	if err != nil {
+		return introspect.Schema{}, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return introspect.Schema{}, err
+	}
+	return Parse(string(b), dialect)
+}
+
+func (fileSource) Version(dsn string) (string, error) {
+	path, _, err := parseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", fi.ModTime().UnixNano(), fi.Size()), nil
+}
+
+// parseDSN returns the path and dialect of a file source dsn.
+func parseDSN(dsn string) (path, dialect string, err error) {
+	path, query, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	if path == "" {
+		return "", "", fmt.Errorf("ddl dsn %q has no file path", dsn)
+	}
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return "", "", fmt.Errorf("ddl dsn %q: %w", dsn, err)
+	}
+	return path, q.Get("dialect"), nil
+}
+
+func init() {
+	db.RegisterSource("ddl", fileSource{})
+}
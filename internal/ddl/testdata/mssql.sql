@@ -0,0 +1,37 @@
+SET ANSI_NULLS ON
+GO
+CREATE TABLE [dbo].[Customers](
+	[Id] [int] IDENTITY(1,1) NOT NULL,
+	[Name] [nvarchar](100) NOT NULL,
+	[Notes] [nvarchar](max) NULL,
+	[Total] AS ([Id]*(2)) PERSISTED,
+ CONSTRAINT [PK_Customers] PRIMARY KEY CLUSTERED 
+(
+	[Id] ASC
+)WITH (PAD_INDEX = OFF) ON [PRIMARY]
+) ON [PRIMARY] TEXTIMAGE_ON [PRIMARY]
+GO
+CREATE TABLE [sales].[Orders](
+	[Id] [bigint] NOT NULL PRIMARY KEY,
+	[CustomerId] [int] NULL,
+	[Amount] [decimal](12, 2) NULL
+)
+GO
+CREATE NONCLUSTERED INDEX [IX_Orders_Customer] ON [sales].[Orders]
+(
+	[CustomerId] ASC
+)
+INCLUDE([Amount]) WHERE ([CustomerId] IS NOT NULL) WITH (PAD_INDEX = OFF) ON [PRIMARY]
+GO
+ALTER TABLE [sales].[Orders] ADD  CONSTRAINT [DF_Orders_Amount]  DEFAULT ((0)) FOR [Amount]
+GO
+ALTER TABLE [sales].[Orders]  WITH NOCHECK ADD  CONSTRAINT [FK_Orders_Customers] FOREIGN KEY([CustomerId])
+REFERENCES [dbo].[Customers] ([Id])
+ON DELETE CASCADE
+GO
+ALTER TABLE [sales].[Orders] CHECK CONSTRAINT [FK_Orders_Customers]
+GO
+EXEC sys.sp_addextendedproperty @name=N'MS_Description', @value=N'People who buy things' , @level0type=N'SCHEMA',@level0name=N'dbo', @level1type=N'TABLE',@level1name=N'Customers'
+GO
+EXEC sys.sp_addextendedproperty @name=N'MS_Description', @value=N'Full name' , @level0type=N'SCHEMA',@level0name=N'dbo', @level1type=N'TABLE',@level1name=N'Customers', @level2type=N'COLUMN',@level2name=N'Name'
+GO
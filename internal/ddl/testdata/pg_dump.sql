@@ -0,0 +1,83 @@
+--
+-- PostgreSQL database dump
+--
+
+SET statement_timeout = 0;
+SELECT pg_catalog.set_config('search_path', '', false);
+
+CREATE TYPE public.status AS ENUM (
+    'active',
+    'it''s closed'
+);
+
+CREATE FUNCTION public.touch() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+  NEW.updated := now(); -- a ; inside the body
+  RETURN NEW;
+END;
+$$;
+
+CREATE TABLE public.customers (
+    id integer NOT NULL,
+    name character varying(100) NOT NULL,
+    balance numeric(10,2) DEFAULT 0.00,
+    status public.status DEFAULT 'active'::public.status,
+    created timestamp with time zone DEFAULT now() NOT NULL
+);
+
+COMMENT ON TABLE public.customers IS 'People who buy things';
+COMMENT ON COLUMN public.customers.name IS 'Full name';
+
+CREATE SEQUENCE public.customers_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1;
+
+ALTER SEQUENCE public.customers_id_seq OWNED BY public.customers.id;
+
+CREATE TABLE public.orders (
+    id bigint NOT NULL,
+    customer_id integer,
+    total numeric(12,2),
+    placed date NOT NULL
+)
+PARTITION BY RANGE (placed);
+
+CREATE TABLE public.orders_2024 (
+    id bigint NOT NULL,
+    customer_id integer,
+    total numeric(12,2),
+    placed date NOT NULL
+);
+
+ALTER TABLE ONLY public.orders ATTACH PARTITION public.orders_2024 FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+
+CREATE VIEW public.big_customers AS
+ SELECT c.id,
+    c.name
+   FROM public.customers c
+  WHERE (c.balance > (1000)::numeric);
+
+ALTER TABLE ONLY public.customers ALTER COLUMN id SET DEFAULT nextval('public.customers_id_seq'::regclass);
+
+ALTER TABLE ONLY public.customers
+    ADD CONSTRAINT customers_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_pkey PRIMARY KEY (id, placed);
+
+ALTER TABLE ONLY public.customers
+    ADD CONSTRAINT customers_name_key UNIQUE (name);
+
+CREATE INDEX orders_customer_idx ON public.orders USING btree (customer_id) INCLUDE (total) WHERE (total > (0)::numeric);
+
+CREATE UNIQUE INDEX customers_lower_name_idx ON public.customers USING btree (lower((name)::text));
+
+ALTER TABLE public.orders
+    ADD CONSTRAINT orders_customer_fk FOREIGN KEY (customer_id) REFERENCES public.customers(id) ON DELETE SET NULL DEFERRABLE INITIALLY DEFERRED;
+
+CREATE TRIGGER touch BEFORE UPDATE ON public.customers FOR EACH ROW EXECUTE FUNCTION public.touch();
+
+GRANT ALL ON TABLE public.customers TO app;
@@ -0,0 +1,11 @@
+CREATE TABLE customers (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+  balance REAL CONSTRAINT positive CHECK (balance >= 0)
+);
+CREATE TABLE orders (
+  id INTEGER PRIMARY KEY,
+  customer_id INTEGER REFERENCES customers ON DELETE CASCADE,
+  total REAL GENERATED ALWAYS AS (id * 2) STORED
+) WITHOUT ROWID;
+CREATE INDEX ix_orders_customer ON orders(customer_id) WHERE customer_id IS NOT NULL;
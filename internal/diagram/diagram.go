@@ -0,0 +1,206 @@
+// Package diagram renders schemas as entity relationship diagrams in the
+// text formats of Mermaid, PlantUML and Graphviz. The diagrams show what
+// the web UI shows: tables colored by size class, views and the tables
+// they read from, and foreign keys labeled with their constraint names.
+package diagram
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"erddiagram/internal/introspect"
+)
+
+// Options selects the objects of a diagram and how tables are colored,
+// like the filters of the web UI.
+type Options struct {
+	Schema  string // only objects of this schema, and foreign keys touching it; empty for all
+	Search  string // only objects whose qualified name contains this text, ignoring case
+	ColorBy string // "rows" colors tables by row count, anything else by size in 8k pages
+}
+
+// Writer writes schema s as a diagram to w.
+type Writer func(w io.Writer, s introspect.Schema, o Options) error
+
+// Formats maps the names of the diagram formats to their writers.
+var Formats = map[string]Writer{
+	"mermaid":  Mermaid,
+	"plantuml": PlantUML,
+	"dot":      DOT,
+}
+
+// FormatNames returns the names of Formats in order.
+func FormatNames() []string {
+	names := make([]string, 0, len(Formats))
+	for name := range Formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// spaces matches runs of white space.
+var spaces = regexp.MustCompile(`\s+`)
+
+// sizeColors are the fill colors of the size classes 1 to 10.
+var sizeColors = []string{
+	"#FFFF66", "#FFD744", "#FFAF22", "#96B6F5", "#70A0F0",
+	"#4A8AEC", "#769A62", "#60804E", "#4B663B", "#CB4040",
+}
+
+// viewColor is the fill color of views.
+const viewColor = "#E8E8E8"
+
+// SizeClass returns the size class of t, 1 to 10, the order of magnitude
+// of its row count or size in 8k pages plus one.
+func SizeClass(t introspect.Table, colorBy string) int {
+	magnitude := t.Size8kPages
+	if colorBy == "rows" {
+		magnitude = t.Rows
+	}
+	if magnitude <= 0 {
+		return 1
+	}
+	return min(int(math.Log10(float64(magnitude))), 9) + 1
+}
+
+// selection is the part of a schema a diagram shows.
+type selection struct {
+	tables []introspect.Table
+	fks    []introspect.ForeignKey
+	views  []introspect.View
+}
+
+// selectObjects returns the objects of s that match the schema and search
+// filters of o. A foreign key is kept if either of its tables matches, a
+// view dependency only if the table or view it points to is selected too.
+func selectObjects(s introspect.Schema, o Options) selection {
+	schema := strings.ToLower(o.Schema)
+	q := strings.ToLower(strings.TrimSpace(o.Search))
+	matches := func(objSchema, name string) (bool, bool) {
+		objSchema = strings.ToLower(objSchema)
+		return schema == "" || objSchema == schema, q == "" || strings.Contains(strings.ToLower(qualified(objSchema, name)), q)
+	}
+	var sel selection
+	for _, t := range s.Tables {
+		if inSchema, found := matches(t.Schema, t.Name); inSchema && found {
+			sel.tables = append(sel.tables, t)
+		}
+	}
+	for _, fk := range s.ForeignKeys {
+		fromSchema, fromFound := matches(fk.FromSchema, fk.FromTable)
+		toSchema, toFound := matches(fk.ToSchema, fk.ToTable)
+		if (fromSchema || toSchema) && (fromFound || toFound) {
+			sel.fks = append(sel.fks, fk)
+		}
+	}
+	for _, v := range s.Views {
+		if inSchema, found := matches(v.Schema, v.Name); inSchema && found {
+			sel.views = append(sel.views, v)
+		}
+	}
+	selected := map[string]bool{}
+	for _, t := range sel.tables {
+		selected[qualified(t.Schema, t.Name)] = true
+	}
+	for _, v := range sel.views {
+		selected[qualified(v.Schema, v.Name)] = true
+	}
+	for i, v := range sel.views {
+		var deps []introspect.ObjectRef
+		for _, dep := range v.DependsOn {
+			if selected[qualified(dep.Schema, dep.Name)] {
+				deps = append(deps, dep)
+			}
+		}
+		sel.views[i].DependsOn = deps
+	}
+	return sel
+}
+
+// missingEntities returns the qualified names of the tables that foreign
+// keys of sel point to but sel does not define.
+func (sel selection) missingEntities() []string {
+	defined := map[string]bool{}
+	for _, t := range sel.tables {
+		defined[qualified(t.Schema, t.Name)] = true
+	}
+	for _, v := range sel.views {
+		defined[qualified(v.Schema, v.Name)] = true
+	}
+	var missing []string
+	add := func(name string) {
+		if !defined[name] {
+			defined[name] = true
+			missing = append(missing, name)
+		}
+	}
+	for _, fk := range sel.fks {
+		add(qualified(fk.FromSchema, fk.FromTable))
+		add(qualified(fk.ToSchema, fk.ToTable))
+	}
+	return missing
+}
+
+// fkLabel returns the label of a foreign key: its constraint name, or FK.
+func fkLabel(fk introspect.ForeignKey) string {
+	if fk.Constraint != "" {
+		return fk.Constraint
+	}
+	return "FK"
+}
+
+// columnType returns a column type as a single word of letters, digits
+// and en spaces (U+2002), which Mermaid accepts as an attribute type.
+func columnType(typ string) string {
+	typ = spaces.ReplaceAllString(typ, "\u2002")
+	typ = strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) || unicode.IsSpace(r) {
+			return r
+		}
+		return -1
+	}, typ)
+	return strings.TrimSpace(typ)
+}
+
+// qualified returns schema.name, or name without a schema.
+func qualified(schema, name string) string {
+	if schema == "" {
+		return name
+	}
+	return schema + "." + name
+}
+
+// ids assigns identifiers made of letters, digits and underscores to
+// qualified names, for formats whose references cannot be quoted.
+type ids map[string]string
+
+// id returns the identifier of name, assigning a new one on first use.
+func (m ids) id(name string) string {
+	if id, ok := m[name]; ok {
+		return id
+	}
+	id := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			return r
+		}
+		return '_'
+	}, name)
+	if id == "" || unicode.IsDigit(rune(id[0])) {
+		id = "_" + id
+	}
+	taken := map[string]bool{}
+	for _, v := range m {
+		taken[v] = true
+	}
+	for base, n := id, 2; taken[id]; n++ {
+		id = fmt.Sprintf("%s_%d", base, n)
+	}
+	m[name] = id
+	return id
+}
@@ -0,0 +1,175 @@
+package diagram
+
+import (
+	"strings"
+	"testing"
+
+	"erddiagram/internal/introspect"
+)
+
+// testSchema has two schemas, a foreign key across them and a view.
+var testSchema = introspect.Schema{
+	Tables: []introspect.Table{
+		{Schema: "crm", Name: "customers", Rows: 5000, Size8kPages: 40, Columns: []introspect.Column{
+			{Name: "id", Type: "integer", PK: true},
+			{Name: "name", Type: "character varying(100)", Nullable: true, Comment: ptr(`the "full"  name`)},
+		}},
+		{Schema: "sales", Name: "orders", Columns: []introspect.Column{
+			{Name: "id", Type: "bigint", PK: true},
+			{Name: "customer_id", Type: "integer"},
+		}},
+	},
+	ForeignKeys: []introspect.ForeignKey{
+		{FromSchema: "sales", FromTable: "orders", FromColumn: "customer_id", ToSchema: "crm", ToTable: "customers", ToColumn: "id", Constraint: "orders_customer_fk"},
+	},
+	Views: []introspect.View{
+		{Schema: "sales", Name: "big_orders", Columns: []introspect.Column{{Name: "id", Type: "bigint"}, {Name: "x"}},
+			DependsOn: []introspect.ObjectRef{{Schema: "sales", Name: "orders"}}},
+	},
+}
+
+func TestSizeClass(t *testing.T) {
+	var tests = []struct {
+		name    string
+		table   introspect.Table
+		colorBy string
+		want    int
+	}{
+		{"empty", introspect.Table{}, "size", 1},
+		{"9 pages", introspect.Table{Size8kPages: 9}, "size", 1},
+		{"10 pages", introspect.Table{Size8kPages: 10}, "size", 2},
+		{"rows", introspect.Table{Rows: 12345, Size8kPages: 1}, "rows", 5},
+		{"capped", introspect.Table{Size8kPages: 1e12}, "", 10},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SizeClass(tt.table, tt.colorBy); got != tt.want {
+				t.Errorf("\ngot %d, wanted %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColumnType(t *testing.T) {
+	var tests = []struct {
+		typ  string
+		want string
+	}{
+		{"integer", "integer"},
+		{"character varying(100)", "character\u2002varying100"},
+		{"timestamp  with time zone", "timestamp\u2002with\u2002time\u2002zone"},
+		{"decimal(10, 2)", "decimal10\u20022"},
+		{" int[] ", "int"},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.typ, func(t *testing.T) {
+			if got := columnType(tt.typ); got != tt.want {
+				t.Errorf("\ngot %q, wanted %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	want := "erDiagram\ndirection BT\n\n" +
+		"  \"crm.customers\":::tabsiz_2 {\n" +
+		"    integer id PK\n" +
+		"    character\u2002varying100 name  \"the 'full' name\"\n" +
+		"  }\n" +
+		"  \"sales.orders\":::tabsiz_1 {\n" +
+		"    bigint id PK\n" +
+		"    integer customer_id \n" +
+		"  }\n" +
+		"  \"sales.orders\" }|--|| \"crm.customers\" : \"orders_customer_fk\"\n" +
+		"  \"sales.big_orders\":::view {\n" +
+		"    bigint id\n" +
+		"    any x\n" +
+		"  }\n" +
+		"  \"sales.big_orders\" }o..o{ \"sales.orders\" : \"uses\"\n" +
+		mermaidClassDefs
+
+	var b strings.Builder
+	if err := Mermaid(&b, testSchema, Options{}); err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	if b.String() != want {
+		t.Errorf("\ngot:\n%s\nwanted:\n%s", b.String(), want)
+	}
+	if !strings.Contains(mermaidClassDefs, "classDef tabsiz_10 fill:#CB4040\n") {
+		t.Errorf("\ngot class definitions:\n%s", mermaidClassDefs)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	var tests = []struct {
+		name    string
+		format  string
+		opts    Options
+		want    []string
+		notWant []string
+	}{
+		{"mermaid colored by rows", "mermaid", Options{ColorBy: "rows"},
+			[]string{`"crm.customers":::tabsiz_4 {`}, nil},
+		{"mermaid schema filter", "mermaid", Options{Schema: "CRM"},
+			[]string{`"crm.customers"`, `"sales.orders" }|--|| "crm.customers"`}, []string{`"sales.orders":::`, "big_orders"}},
+		{"mermaid search", "mermaid", Options{Search: "big"},
+			[]string{`"sales.big_orders":::view`}, []string{"customers"}},
+		{"mermaid drops dependencies on filtered out tables", "mermaid", Options{Search: "big"},
+			nil, []string{`"sales.orders"`, "uses"}},
+		{"dot drops dependencies on filtered out tables", "dot", Options{Search: "big"},
+			[]string{`"sales.big_orders" [label=<`}, []string{`"sales.orders"`, "uses"}},
+		{"plantuml", "plantuml", Options{},
+			[]string{"@startuml\n", `entity "crm.customers" as crm_customers #FFD744 {`, "  * id : integer <<PK>>\n  --\n  name : character varying(100)\n",
+				`entity "sales.big_orders" as sales_big_orders <<view>> #E8E8E8`, "sales_orders }|--|| crm_customers : orders_customer_fk",
+				"sales_big_orders }o..o{ sales_orders : uses", "@enduml\n"}, nil},
+		{"plantuml declares filtered out tables", "plantuml", Options{Schema: "crm"},
+			[]string{`entity "sales.orders" as sales_orders` + "\n"}, nil},
+		{"dot", "dot", Options{},
+			[]string{"digraph erd {", `"crm.customers" [label=<`, `<td bgcolor="#FFD744"><b>crm.customers</b></td>`,
+				`"sales.orders" -> "crm.customers" [label="orders_customer_fk"`, `"sales.big_orders" -> "sales.orders" [label="uses", style=dashed`}, nil},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := Formats[tt.format](&b, testSchema, tt.opts); err != nil {
+				t.Fatalf("\ngot unexpected error: \"%v\"", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(b.String(), w) {
+					t.Errorf("\ngot:\n%s\nwanted it to contain %q", b.String(), w)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(b.String(), w) {
+					t.Errorf("\ngot:\n%s\nwanted it not to contain %q", b.String(), w)
+				}
+			}
+		})
+	}
+}
+
+func TestIDs(t *testing.T) {
+	m := ids{}
+	if id := m.id("a.b"); id != "a_b" {
+		t.Errorf("\ngot %q, wanted a_b", id)
+	}
+	if id := m.id("a-b"); id != "a_b_2" {
+		t.Errorf("\ngot %q, wanted a_b_2 for a clashing name", id)
+	}
+	if id := m.id("a.b"); id != "a_b" {
+		t.Errorf("\ngot %q, wanted the id assigned before", id)
+	}
+	if id := m.id("1st"); id != "_1st" {
+		t.Errorf("\ngot %q, wanted _1st", id)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,78 @@
+package diagram
+
+import (
+	"cmp"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+
+	"erddiagram/internal/introspect"
+)
+
+// DOT writes s as a Graphviz digraph. Tables and views are HTML-like
+// tables with a colored header, foreign keys are crow's foot edges and
+// the dependencies of views are dashed edges.
+func DOT(w io.Writer, s introspect.Schema, o Options) error {
+	sel := selectObjects(s, o)
+	var b strings.Builder
+	b.WriteString("digraph erd {\n  rankdir=BT;\n  node [shape=plaintext, fontname=\"Helvetica\"];\n  edge [fontname=\"Helvetica\", fontsize=10];\n\n")
+
+	for _, t := range sel.tables {
+		var rows []string
+		for _, c := range t.Columns {
+			key := ""
+			if c.PK {
+				key = " <b>PK</b>"
+			}
+			rows = append(rows, html.EscapeString(c.Name)+" : "+html.EscapeString(c.Type)+key)
+		}
+		writeDOTNode(&b, qualified(t.Schema, t.Name), sizeColors[SizeClass(t, o.ColorBy)-1], false, rows)
+	}
+	for _, v := range sel.views {
+		var rows []string
+		for _, c := range v.Columns {
+			rows = append(rows, html.EscapeString(c.Name)+" : "+html.EscapeString(cmp.Or(c.Type, "any")))
+		}
+		writeDOTNode(&b, qualified(v.Schema, v.Name), viewColor, true, rows)
+	}
+	for _, name := range sel.missingEntities() {
+		writeDOTNode(&b, name, "", false, nil)
+	}
+	b.WriteString("\n")
+
+	for _, fk := range sel.fks {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s, dir=both, arrowtail=crowtee, arrowhead=teetee];\n",
+			strconv.Quote(qualified(fk.FromSchema, fk.FromTable)), strconv.Quote(qualified(fk.ToSchema, fk.ToTable)), strconv.Quote(fkLabel(fk)))
+	}
+	for _, v := range sel.views {
+		for _, dep := range v.DependsOn {
+			fmt.Fprintf(&b, "  %s -> %s [label=\"uses\", style=dashed, dir=both, arrowtail=crowodot, arrowhead=crowodot];\n",
+				strconv.Quote(qualified(v.Schema, v.Name)), strconv.Quote(qualified(dep.Schema, dep.Name)))
+		}
+	}
+
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeDOTNode writes the node of a table or view named name with a
+// header in color and rows, which are HTML-escaped already.
+func writeDOTNode(b *strings.Builder, name, color string, dashed bool, rows []string) {
+	style := ""
+	if dashed {
+		style = ` style="dashed"`
+	}
+	bg := ""
+	if color != "" {
+		bg = ` bgcolor="` + color + `"`
+	}
+	fmt.Fprintf(b, "  %s [label=<<table border=\"1\" cellborder=\"0\" cellspacing=\"0\" cellpadding=\"4\"%s>\n", strconv.Quote(name), style)
+	fmt.Fprintf(b, "    <tr><td%s><b>%s</b></td></tr>\n", bg, html.EscapeString(name))
+	for _, r := range rows {
+		fmt.Fprintf(b, "    <tr><td align=\"left\">%s</td></tr>\n", r)
+	}
+	b.WriteString("  </table>>];\n")
+}
@@ -0,0 +1,65 @@
+package diagram
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"strings"
+
+	"erddiagram/internal/introspect"
+)
+
+// mermaidClassDefs styles the size classes and views of Mermaid diagrams.
+var mermaidClassDefs = func() string {
+	var b strings.Builder
+	b.WriteString("classDef default font-size:16pt\n")
+	for i, c := range sizeColors {
+		fmt.Fprintf(&b, "classDef tabsiz_%d fill:%s\n", i+1, c)
+	}
+	fmt.Fprintf(&b, "classDef view fill:%s,stroke-dasharray:5 5\n", viewColor)
+	return b.String()
+}()
+
+// Mermaid writes s as a Mermaid erDiagram, the diagram the web UI renders.
+func Mermaid(w io.Writer, s introspect.Schema, o Options) error {
+	sel := selectObjects(s, o)
+	var b strings.Builder
+	b.WriteString("erDiagram\ndirection BT\n\n")
+
+	for _, t := range sel.tables {
+		fmt.Fprintf(&b, "  \"%s\":::tabsiz_%d {\n", qualified(t.Schema, t.Name), SizeClass(t, o.ColorBy))
+		for _, c := range t.Columns {
+			key := ""
+			if c.PK {
+				key = "PK"
+			}
+			comment := ""
+			if c.Comment != nil {
+				// mermaid attribute comments are double quoted and cannot contain double quotes
+				comment = ` "` + spaces.ReplaceAllString(strings.ReplaceAll(*c.Comment, `"`, "'"), " ") + `"`
+			}
+			fmt.Fprintf(&b, "    %s %s %s%s\n", columnType(c.Type), c.Name, key, comment)
+		}
+		b.WriteString("  }\n")
+	}
+
+	for _, fk := range sel.fks {
+		fmt.Fprintf(&b, "  \"%s\" }|--|| \"%s\" : \"%s\"\n", qualified(fk.FromSchema, fk.FromTable), qualified(fk.ToSchema, fk.ToTable), fkLabel(fk))
+	}
+
+	for _, v := range sel.views {
+		name := qualified(v.Schema, v.Name)
+		fmt.Fprintf(&b, "  \"%s\":::view {\n", name)
+		for _, c := range v.Columns {
+			fmt.Fprintf(&b, "    %s %s\n", columnType(cmp.Or(c.Type, "any")), c.Name)
+		}
+		b.WriteString("  }\n")
+		for _, dep := range v.DependsOn {
+			fmt.Fprintf(&b, "  \"%s\" }o..o{ \"%s\" : \"uses\"\n", name, qualified(dep.Schema, dep.Name))
+		}
+	}
+
+	b.WriteString(mermaidClassDefs)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,81 @@
+package diagram
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"strings"
+
+	"erddiagram/internal/introspect"
+)
+
+// PlantUML writes s as a PlantUML diagram in IE (crow's foot) notation.
+// Primary key columns are listed above the separator line and mandatory
+// columns are marked with *.
+func PlantUML(w io.Writer, s introspect.Schema, o Options) error {
+	sel := selectObjects(s, o)
+	names := ids{}
+	var b strings.Builder
+	b.WriteString("@startuml\nhide circle\nskinparam linetype ortho\n\n")
+
+	for _, t := range sel.tables {
+		name := qualified(t.Schema, t.Name)
+		fmt.Fprintf(&b, "entity %s as %s %s {\n", plantString(name), names.id(name), sizeColors[SizeClass(t, o.ColorBy)-1])
+		var keys, others []string
+		for _, c := range t.Columns {
+			line := "  "
+			if !c.Nullable || c.PK {
+				line += "* "
+			}
+			line += c.Name + " : " + c.Type
+			if c.PK {
+				keys = append(keys, line+" <<PK>>")
+				continue
+			}
+			others = append(others, line)
+		}
+		for _, l := range keys {
+			b.WriteString(l + "\n")
+		}
+		if len(keys) > 0 {
+			b.WriteString("  --\n")
+		}
+		for _, l := range others {
+			b.WriteString(l + "\n")
+		}
+		b.WriteString("}\n")
+	}
+
+	for _, v := range sel.views {
+		name := qualified(v.Schema, v.Name)
+		fmt.Fprintf(&b, "entity %s as %s <<view>> %s ##[dashed] {\n", plantString(name), names.id(name), viewColor)
+		for _, c := range v.Columns {
+			fmt.Fprintf(&b, "  %s : %s\n", c.Name, cmp.Or(c.Type, "any"))
+		}
+		b.WriteString("}\n")
+	}
+
+	for _, name := range sel.missingEntities() {
+		fmt.Fprintf(&b, "entity %s as %s\n", plantString(name), names.id(name))
+	}
+	b.WriteString("\n")
+
+	for _, fk := range sel.fks {
+		fmt.Fprintf(&b, "%s }|--|| %s : %s\n",
+			names.id(qualified(fk.FromSchema, fk.FromTable)), names.id(qualified(fk.ToSchema, fk.ToTable)), fkLabel(fk))
+	}
+	for _, v := range sel.views {
+		for _, dep := range v.DependsOn {
+			fmt.Fprintf(&b, "%s }o..o{ %s : uses\n", names.id(qualified(v.Schema, v.Name)), names.id(qualified(dep.Schema, dep.Name)))
+		}
+	}
+
+	b.WriteString("@enduml\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// plantString returns s as a double quoted PlantUML string.
+func plantString(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `'`) + `"`
+}
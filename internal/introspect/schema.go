@@ -1,36 +1,245 @@
 ﻿package introspect
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
 // Column represents a table column.
 type Column struct {
-	Name     string `json:"name"`
-	Type     string `json:"type"`
-	Nullable bool   `json:"nullable"`
-	PK       bool   `json:"pk"`
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Nullable  bool    `json:"nullable"`
+	PK        bool    `json:"pk"`
+	Default   *string `json:"default,omitempty"`    // optional default expression
+	Identity  bool    `json:"identity,omitempty"`   // identity, serial or auto-increment column
+	Generated *string `json:"generated,omitempty"`  // optional generated/computed column expression
+	MaxLength *int64  `json:"max_length,omitempty"` // optional maximum character length
+	Precision *int64  `json:"precision,omitempty"`  // optional precision of exact numeric types
+	Scale     *int64  `json:"scale,omitempty"`      // optional scale of exact numeric types
+	Collation *string `json:"collation,omitempty"`  // optional collation
+	Comment   *string `json:"comment,omitempty"`    // optional column comment
+}
+
+// ColumnPair maps a referencing column to the column it references.
+type ColumnPair struct {
+	From string `json:"from"`
+	To   string `json:"to"`
 }
 
 // ForeignKey represents a foreign key relationship.
+// FromColumn and ToColumn hold the comma-joined column names of Columns
+// for clients that do not handle composite keys; use AddColumn to keep
+// them in sync.
 type ForeignKey struct {
-	FromSchema string `json:"from_schema,omitempty"`
-	FromTable  string `json:"from_table"`
-	FromColumn string `json:"from_column"`
-	ToSchema   string `json:"to_schema,omitempty"`
-	ToTable    string `json:"to_table"`
-	ToColumn   string `json:"to_column"`
-	Constraint string `json:"constraint,omitempty"`
+	FromSchema string       `json:"from_schema,omitempty"`
+	FromTable  string       `json:"from_table"`
+	FromColumn string       `json:"from_column"`
+	ToSchema   string       `json:"to_schema,omitempty"`
+	ToTable    string       `json:"to_table"`
+	ToColumn   string       `json:"to_column"`
+	Columns    []ColumnPair `json:"columns"` // column pairs in key order
+	Constraint string       `json:"constraint,omitempty"`
+
+	OnDelete          string `json:"on_delete,omitempty"`          // optional referential action, e.g. CASCADE, SET NULL
+	OnUpdate          string `json:"on_update,omitempty"`          // optional referential action, e.g. CASCADE, NO ACTION
+	MatchType         string `json:"match_type,omitempty"`         // optional match type: SIMPLE, FULL or PARTIAL
+	Deferrable        bool   `json:"deferrable,omitempty"`         // constraint check can be deferred
+	InitiallyDeferred bool   `json:"initially_deferred,omitempty"` // constraint check is deferred by default
+	Disabled          bool   `json:"disabled,omitempty"`           // constraint is not enforced
+	NotValidated      bool   `json:"not_validated,omitempty"`      // existing rows were not checked (NOT VALID, NOVALIDATE, not trusted)
+}
+
+// Index represents a table index.
+type Index struct {
+	Name      string   `json:"name"`
+	Columns   []string `json:"columns"`           // key columns (or expressions) in index order
+	Include   []string `json:"include,omitempty"` // optional non-key (covering) columns
+	Unique    bool     `json:"unique"`
+	Primary   bool     `json:"primary"`
+	Predicate string   `json:"predicate,omitempty"` // optional partial index predicate
+	Method    string   `json:"method,omitempty"`    // optional access method, e.g. btree, gin, hash
+}
+
+// AddColumn appends the column pair from/to to the foreign key.
+func (fk *ForeignKey) AddColumn(from, to string) {
+	fk.Columns = append(fk.Columns, ColumnPair{From: from, To: to})
+	if len(fk.Columns) > 1 {
+		fk.FromColumn += ", "
+		fk.ToColumn += ", "
+	}
+	fk.FromColumn += from
+	fk.ToColumn += to
+}
+
+// Constraint types used in Constraint.Type.
+const (
+	ConstraintUnique  = "UNIQUE"
+	ConstraintCheck   = "CHECK"
+	ConstraintExclude = "EXCLUDE"
+)
+
+// Constraint represents a unique, check or exclusion constraint.
+type Constraint struct {
+	Name       string   `json:"name,omitempty"`
+	Type       string   `json:"type"`
+	Columns    []string `json:"columns,omitempty"`
+	Expression string   `json:"expression,omitempty"` // optional check expression or exclusion definition
 }
 
 // Table represents a database table and its columns.
 type Table struct {
-	Schema      string   `json:"schema,omitempty"`
-	Name        string   `json:"name"`
-	Columns     []Column `json:"columns"`
-	Rows        int64    `json:"rows,omitempty"`        // optional row estimate/counted value
-	Comment     *string  `json:"comment,omitempty"`     // optional table comment
-	Size8kPages int64    `json:"size8kPages,omitempty"` // optional size in 8k pages
+	Schema       string        `json:"schema,omitempty"`
+	Name         string        `json:"name"`
+	Columns      []Column      `json:"columns"`
+	Indexes      []Index       `json:"indexes,omitempty"`
+	Constraints  []Constraint  `json:"constraints,omitempty"`
+	Rows         int64         `json:"rows,omitempty"`         // optional row estimate/counted value
+	Comment      *string       `json:"comment,omitempty"`      // optional table comment
+	Size8kPages  int64         `json:"size8kPages,omitempty"`  // optional size in 8k pages
+	Partitioning *Partitioning `json:"partitioning,omitempty"` // optional partitioning of the table
+}
+
+// Partitioning describes how a partitioned table is split into partitions.
+type Partitioning struct {
+	Strategy   string      `json:"strategy"`          // RANGE, LIST, HASH, ...
+	Columns    []string    `json:"columns,omitempty"` // partition key columns or expressions
+	Partitions []Partition `json:"partitions,omitempty"`
+}
+
+// Partition is one partition of a partitioned table.
+type Partition struct {
+	Schema      string `json:"schema,omitempty"`
+	Name        string `json:"name,omitempty"`        // optional, SQL Server partitions have no name
+	Number      int    `json:"number,omitempty"`      // optional partition number
+	Bound       string `json:"bound,omitempty"`       // optional partition bound, e.g. FOR VALUES IN (1, 2)
+	Rows        int64  `json:"rows,omitempty"`        // optional row estimate
+	Size8kPages int64  `json:"size8kPages,omitempty"` // optional size in 8k pages
+}
+
+// ObjectRef references a table or view by schema and name.
+type ObjectRef struct {
+	Schema string `json:"schema,omitempty"`
+	Name   string `json:"name"`
+}
+
+// View represents a view or materialized view and the objects it reads from.
+type View struct {
+	Schema       string      `json:"schema,omitempty"`
+	Name         string      `json:"name"`
+	Columns      []Column    `json:"columns"`
+	Definition   string      `json:"definition,omitempty"`   // optional view definition SQL
+	Materialized bool        `json:"materialized,omitempty"` // materialized view (Postgres, Oracle)
+	Indexed      bool        `json:"indexed,omitempty"`      // indexed view (SQL Server)
+	Comment      *string     `json:"comment,omitempty"`      // optional view comment
+	DependsOn    []ObjectRef `json:"depends_on,omitempty"`   // optional tables and views the view reads from
+}
+
+// Kinds of user-defined types used in UserType.Kind.
+const (
+	TypeEnum      = "enum"
+	TypeSet       = "set" // MySQL SET
+	TypeDomain    = "domain"
+	TypeComposite = "composite"
+	TypeAlias     = "alias" // SQL Server alias type
+	TypeTable     = "table" // SQL Server table type
+)
+
+// UserType represents a user-defined type such as an enum, domain or
+// composite type. MySQL ENUM and SET columns are reported as inline types
+// named after their table and column.
+type UserType struct {
+	Schema     string   `json:"schema,omitempty"`
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind"`
+	Labels     []string `json:"labels,omitempty"`     // optional enum or set values in order
+	BaseType   string   `json:"base_type,omitempty"`  // optional underlying type of domains and alias types
+	Check      string   `json:"check,omitempty"`      // optional domain check constraints
+	Attributes []Column `json:"attributes,omitempty"` // optional columns of composite and table types
+	Comment    *string  `json:"comment,omitempty"`    // optional type comment
+}
+
+// ColumnRef references a column of a table by schema, table and name.
+type ColumnRef struct {
+	Schema string `json:"schema,omitempty"`
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
+
+// Sequence represents a sequence generator.
+type Sequence struct {
+	Schema    string     `json:"schema,omitempty"`
+	Name      string     `json:"name"`
+	DataType  string     `json:"data_type,omitempty"`
+	Start     *int64     `json:"start,omitempty"`     // optional start value
+	Increment *int64     `json:"increment,omitempty"` // optional increment
+	Cycle     bool       `json:"cycle,omitempty"`
+	OwnedBy   *ColumnRef `json:"owned_by,omitempty"` // optional serial or identity column the sequence belongs to
+}
+
+// Trigger represents a trigger on a table or view.
+type Trigger struct {
+	Schema     string    `json:"schema,omitempty"`
+	Name       string    `json:"name"`
+	Table      ObjectRef `json:"table"`
+	Timing     string    `json:"timing,omitempty"` // BEFORE, AFTER or INSTEAD OF
+	Events     []string  `json:"events,omitempty"` // INSERT, UPDATE, DELETE, TRUNCATE
+	Level      string    `json:"level,omitempty"`  // ROW or STATEMENT
+	Disabled   bool      `json:"disabled,omitempty"`
+	Definition string    `json:"definition,omitempty"` // optional trigger definition SQL
+}
+
+// Routine represents a stored function, procedure or package.
+type Routine struct {
+	Schema     string      `json:"schema,omitempty"`
+	Name       string      `json:"name"`
+	Kind       string      `json:"kind"`                  // FUNCTION, PROCEDURE or PACKAGE
+	Arguments  string      `json:"arguments,omitempty"`   // optional argument list
+	ReturnType string      `json:"return_type,omitempty"` // optional result type of functions
+	Language   string      `json:"language,omitempty"`
+	Definition string      `json:"definition,omitempty"` // optional routine body
+	References []ObjectRef `json:"references,omitempty"` // optional tables and views the routine uses, where the catalog tracks them
+}
+
+// TableGroup is a named group of tables, such as a DBML TableGroup.
+type TableGroup struct {
+	Name    string      `json:"name"`
+	Tables  []ObjectRef `json:"tables"`
+	Comment *string     `json:"comment,omitempty"` // optional group note
+}
+
+// Warning records an extraction step that failed, so that a missing part of
+// the schema can be told apart from one that is empty in the database.
+type Warning struct {
+	Object  string `json:"object,omitempty"` // optional table or other object the step was about
+	Phase   string `json:"phase"`            // extraction step, e.g. "foreign keys"
+	Message string `json:"message"`
 }
 
 // Schema is the full DB schema extracted for visualization.
 type Schema struct {
 	Tables      []Table      `json:"tables"`
+	Views       []View       `json:"views,omitempty"`
+	Types       []UserType   `json:"types,omitempty"`
+	Sequences   []Sequence   `json:"sequences,omitempty"`
+	Triggers    []Trigger    `json:"triggers,omitempty"`
+	Routines    []Routine    `json:"routines,omitempty"`
 	ForeignKeys []ForeignKey `json:"foreign_keys"`
+	Groups      []TableGroup `json:"groups,omitempty"` // optional table groups of imported models
+	Warnings    []Warning    `json:"warnings,omitempty"`
+}
+
+// AddWarning records that the extraction step phase failed for object with err.
+func (s *Schema) AddWarning(object, phase string, err error) {
+	s.Warnings = append(s.Warnings, Warning{Object: object, Phase: phase, Message: err.Error()})
+}
+
+// Hash returns a hex encoded SHA-256 of the JSON encoding of s. Schemas
+// with the same content have the same hash, so it serves as an ETag.
+func (s *Schema) Hash() string {
+	// a schema holds only plain data, so encoding cannot fail
+	b, _ := json.Marshal(s)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
 }
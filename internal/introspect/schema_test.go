@@ -0,0 +1,39 @@
+package introspect
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestForeignKeyAddColumn(t *testing.T) {
+	var fk ForeignKey
+	fk.AddColumn("order_id", "id")
+	fk.AddColumn("order_version", "version")
+
+	if fk.FromColumn != "order_id, order_version" {
+		t.Errorf("\ngot from column %q", fk.FromColumn)
+	}
+	if fk.ToColumn != "id, version" {
+		t.Errorf("\ngot to column %q", fk.ToColumn)
+	}
+	want := []ColumnPair{{"order_id", "id"}, {"order_version", "version"}}
+	if !reflect.DeepEqual(fk.Columns, want) {
+		t.Errorf("\ngot columns %v, wanted %v", fk.Columns, want)
+	}
+}
+
+func TestSchemaHash(t *testing.T) {
+	a := Schema{Tables: []Table{{Schema: "s", Name: "t", Columns: []Column{{Name: "id", Type: "int"}}}}}
+	b := Schema{Tables: []Table{{Schema: "s", Name: "t", Columns: []Column{{Name: "id", Type: "int"}}}}}
+
+	if a.Hash() != b.Hash() {
+		t.Errorf("\ngot different hashes for equal schemas")
+	}
+	if len(a.Hash()) != 64 {
+		t.Errorf("\ngot hash %q, wanted 64 hex digits", a.Hash())
+	}
+	b.Tables[0].Columns[0].Nullable = true
+	if a.Hash() == b.Hash() {
+		t.Errorf("\ngot the same hash after a change")
+	}
+}
@@ -0,0 +1,197 @@
+// Package lint checks schemas for common design problems, such as tables
+// without a primary key or foreign keys without an index.
+package lint
+
+import (
+	"slices"
+	"strings"
+
+	"erddiagram/internal/introspect"
+)
+
+// Finding is a problem found in a schema.
+type Finding struct {
+	Rule    string `json:"rule"`
+	Object  string `json:"object"`
+	Message string `json:"message"`
+}
+
+// String returns the finding as a line of text.
+func (f Finding) String() string {
+	return f.Rule + ": " + f.Object + ": " + f.Message
+}
+
+// Rule is a check of a schema.
+type Rule struct {
+	Name        string
+	Description string
+	check       func(s introspect.Schema, tables map[string]introspect.Table) []Finding
+}
+
+// Rules lists the rules Check applies, in the order of their findings.
+var Rules = []Rule{
+	{"no-primary-key", "tables without a primary key", noPrimaryKey},
+	{"fk-unknown-table", "foreign keys referencing a table that is not in the schema", fkUnknownTable},
+	{"fk-type-mismatch", "foreign key columns whose type differs from the referenced column", fkTypeMismatch},
+	{"fk-without-index", "foreign keys whose columns are not the leading columns of an index", fkWithoutIndex},
+	{"duplicate-index", "indexes with the same columns as another index of the table", duplicateIndex},
+	{"extraction-warning", "parts of the schema that could not be extracted", extractionWarning},
+}
+
+// Check applies Rules to s, except for the rules named in disabled.
+func Check(s introspect.Schema, disabled ...string) []Finding {
+	tables := map[string]introspect.Table{}
+	for _, t := range s.Tables {
+		tables[qualified(t.Schema, t.Name)] = t
+	}
+	var findings []Finding
+	for _, r := range Rules {
+		if slices.Contains(disabled, r.Name) {
+			continue
+		}
+		for _, f := range r.check(s, tables) {
+			f.Rule = r.Name
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+func noPrimaryKey(s introspect.Schema, _ map[string]introspect.Table) []Finding {
+	var findings []Finding
+	for _, t := range s.Tables {
+		if !slices.ContainsFunc(t.Columns, func(c introspect.Column) bool { return c.PK }) {
+			findings = append(findings, Finding{Object: qualified(t.Schema, t.Name), Message: "table has no primary key"})
+		}
+	}
+	return findings
+}
+
+func fkUnknownTable(s introspect.Schema, tables map[string]introspect.Table) []Finding {
+	var findings []Finding
+	for _, fk := range s.ForeignKeys {
+		if _, ok := tables[qualified(fk.ToSchema, fk.ToTable)]; !ok {
+			findings = append(findings, Finding{Object: fkName(fk), Message: "references " + qualified(fk.ToSchema, fk.ToTable) + ", which is not in the schema"})
+		}
+	}
+	return findings
+}
+
+func fkTypeMismatch(s introspect.Schema, tables map[string]introspect.Table) []Finding {
+	var findings []Finding
+	for _, fk := range s.ForeignKeys {
+		from, ok := tables[qualified(fk.FromSchema, fk.FromTable)]
+		to, ok2 := tables[qualified(fk.ToSchema, fk.ToTable)]
+		if !ok || !ok2 {
+			continue
+		}
+		for _, p := range pairs(fk) {
+			fc, tc := column(from, p.From), column(to, p.To)
+			if fc == nil || tc == nil || strings.EqualFold(fc.Type, tc.Type) {
+				continue
+			}
+			findings = append(findings, Finding{Object: fkName(fk),
+				Message: "column " + p.From + " is " + fc.Type + ", but the referenced column " + p.To + " is " + tc.Type})
+		}
+	}
+	return findings
+}
+
+func fkWithoutIndex(s introspect.Schema, tables map[string]introspect.Table) []Finding {
+	var findings []Finding
+	for _, fk := range s.ForeignKeys {
+		from, ok := tables[qualified(fk.FromSchema, fk.FromTable)]
+		if !ok {
+			continue
+		}
+		var cols []string
+		for _, p := range pairs(fk) {
+			cols = append(cols, p.From)
+		}
+		if !slices.ContainsFunc(from.Indexes, func(idx introspect.Index) bool { return leading(idx.Columns, cols) }) {
+			findings = append(findings, Finding{Object: fkName(fk), Message: "no index starts with (" + strings.Join(cols, ", ") + ")"})
+		}
+	}
+	return findings
+}
+
+func duplicateIndex(s introspect.Schema, _ map[string]introspect.Table) []Finding {
+	var findings []Finding
+	for _, t := range s.Tables {
+		for i, idx := range t.Indexes {
+			for _, prev := range t.Indexes[:i] {
+				if slices.Equal(idx.Columns, prev.Columns) && idx.Predicate == prev.Predicate && idx.Method == prev.Method {
+					findings = append(findings, Finding{Object: qualified(t.Schema, t.Name) + "." + idx.Name,
+						Message: "has the same columns as index " + prev.Name})
+					break
+				}
+			}
+		}
+	}
+	return findings
+}
+
+func extractionWarning(s introspect.Schema, _ map[string]introspect.Table) []Finding {
+	var findings []Finding
+	for _, w := range s.Warnings {
+		findings = append(findings, Finding{Object: w.Object, Message: w.Phase + ": " + w.Message})
+	}
+	return findings
+}
+
+// pairs returns the column pairs of fk, falling back to FromColumn and
+// ToColumn for foreign keys decoded from JSON without columns.
+func pairs(fk introspect.ForeignKey) []introspect.ColumnPair {
+	if len(fk.Columns) > 0 {
+		return fk.Columns
+	}
+	from, to := strings.Split(fk.FromColumn, ","), strings.Split(fk.ToColumn, ",")
+	var ps []introspect.ColumnPair
+	for i := range from {
+		p := introspect.ColumnPair{From: strings.TrimSpace(from[i])}
+		if i < len(to) {
+			p.To = strings.TrimSpace(to[i])
+		}
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+// leading reports whether cols are the leading columns of index, in any order.
+func leading(index, cols []string) bool {
+	if len(index) < len(cols) {
+		return false
+	}
+	for _, c := range index[:len(cols)] {
+		if !slices.Contains(cols, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// column returns the column of t named name, or nil.
+func column(t introspect.Table, name string) *introspect.Column {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// fkName names a foreign key by its table and constraint or columns.
+func fkName(fk introspect.ForeignKey) string {
+	if fk.Constraint != "" {
+		return qualified(fk.FromSchema, fk.FromTable) + "." + fk.Constraint
+	}
+	return qualified(fk.FromSchema, fk.FromTable) + "(" + fk.FromColumn + ")"
+}
+
+// qualified returns schema.name, or name without a schema.
+func qualified(schema, name string) string {
+	if schema == "" {
+		return name
+	}
+	return schema + "." + name
+}
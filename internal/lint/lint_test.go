@@ -0,0 +1,82 @@
+package lint
+
+import (
+	"reflect"
+	"testing"
+
+	"erddiagram/internal/introspect"
+)
+
+func TestCheck(t *testing.T) {
+	s := introspect.Schema{
+		Tables: []introspect.Table{
+			{Name: "customers", Columns: []introspect.Column{{Name: "id", Type: "integer", PK: true}}},
+			{Name: "orders", Columns: []introspect.Column{
+				{Name: "id", Type: "bigint", PK: true},
+				{Name: "customer_id", Type: "bigint"},
+				{Name: "product_id", Type: "integer"},
+			}, Indexes: []introspect.Index{
+				{Name: "orders_customer_idx", Columns: []string{"customer_id", "id"}},
+				{Name: "orders_customer_idx2", Columns: []string{"customer_id", "id"}},
+			}},
+			{Name: "log", Columns: []introspect.Column{{Name: "line", Type: "text"}}},
+		},
+		ForeignKeys: []introspect.ForeignKey{
+			{FromTable: "orders", FromColumn: "customer_id", ToTable: "customers", ToColumn: "id", Constraint: "orders_customer_fk"},
+			{FromTable: "orders", FromColumn: "product_id", ToTable: "products", ToColumn: "id",
+				Columns: []introspect.ColumnPair{{From: "product_id", To: "id"}}},
+		},
+		Warnings: []introspect.Warning{{Object: "log", Phase: "indexes", Message: "permission denied"}},
+	}
+
+	var tests = []struct {
+		name     string
+		disabled []string
+		want     []Finding
+	}{
+		{"all rules", nil, []Finding{
+			{"no-primary-key", "log", "table has no primary key"},
+			{"fk-unknown-table", "orders(product_id)", "references products, which is not in the schema"},
+			{"fk-type-mismatch", "orders.orders_customer_fk", "column customer_id is bigint, but the referenced column id is integer"},
+			{"fk-without-index", "orders(product_id)", "no index starts with (product_id)"},
+			{"duplicate-index", "orders.orders_customer_idx2", "has the same columns as index orders_customer_idx"},
+			{"extraction-warning", "log", "indexes: permission denied"},
+		}},
+		{"disabled rules", []string{"no-primary-key", "fk-unknown-table", "fk-without-index", "duplicate-index", "extraction-warning"}, []Finding{
+			{"fk-type-mismatch", "orders.orders_customer_fk", "column customer_id is bigint, but the referenced column id is integer"},
+		}},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Check(s, tt.disabled...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\ngot %v, wanted %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeading(t *testing.T) {
+	var tests = []struct {
+		name  string
+		index []string
+		cols  []string
+		want  bool
+	}{
+		{"same", []string{"a"}, []string{"a"}, true},
+		{"prefix", []string{"a", "b"}, []string{"a"}, true},
+		{"other order", []string{"b", "a", "c"}, []string{"a", "b"}, true},
+		{"not leading", []string{"b", "a"}, []string{"a"}, false},
+		{"too short", []string{"a"}, []string{"a", "b"}, false},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			if got := leading(tt.index, tt.cols); got != tt.want {
+				t.Errorf("\ngot %v, wanted %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+// Package markdown writes schemas as Markdown documentation.
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"erddiagram/internal/introspect"
+)
+
+// Write writes s to w as a Markdown document with a section per table and
+// view, listing columns, indexes, constraints and foreign keys, followed
+// by the user-defined types and the warnings of the extraction.
+func Write(w io.Writer, s introspect.Schema) error {
+	var b strings.Builder
+	b.WriteString("# Schema\n\n")
+	fmt.Fprintf(&b, "%d tables, %d views, %d foreign keys\n", len(s.Tables), len(s.Views), len(s.ForeignKeys))
+
+	if len(s.Warnings) > 0 {
+		b.WriteString("\n> **The schema may be incomplete:**\n")
+		for _, wn := range s.Warnings {
+			fmt.Fprintf(&b, "> - %s\n", warning(wn))
+		}
+	}
+
+	if len(s.Tables) > 0 {
+		b.WriteString("\n## Tables\n")
+	}
+	for _, t := range s.Tables {
+		writeTable(&b, s, t)
+	}
+
+	if len(s.Views) > 0 {
+		b.WriteString("\n## Views\n")
+	}
+	for _, v := range s.Views {
+		kind := "View"
+		if v.Materialized {
+			kind = "Materialized view"
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n%s", code(qualified(v.Schema, v.Name)), kind)
+		if len(v.DependsOn) > 0 {
+			var deps []string
+			for _, d := range v.DependsOn {
+				deps = append(deps, code(qualified(d.Schema, d.Name)))
+			}
+			fmt.Fprintf(&b, " of %s", strings.Join(deps, ", "))
+		}
+		b.WriteString("\n")
+		if v.Comment != nil {
+			fmt.Fprintf(&b, "\n%s\n", *v.Comment)
+		}
+		if len(v.Columns) > 0 {
+			b.WriteString("\n| Column | Type |\n| --- | --- |\n")
+			for _, c := range v.Columns {
+				fmt.Fprintf(&b, "| %s | %s |\n", cell(c.Name), cell(c.Type))
+			}
+		}
+		if v.Definition != "" {
+			fmt.Fprintf(&b, "\n```sql\n%s\n```\n", strings.TrimSpace(v.Definition))
+		}
+	}
+
+	if len(s.Types) > 0 {
+		b.WriteString("\n## Types\n\n")
+	}
+	for _, ut := range s.Types {
+		fmt.Fprintf(&b, "- %s %s", code(qualified(ut.Schema, ut.Name)), ut.Kind)
+		switch {
+		case len(ut.Labels) > 0:
+			fmt.Fprintf(&b, ": %s", strings.Join(ut.Labels, ", "))
+		case ut.BaseType != "":
+			fmt.Fprintf(&b, " of %s", ut.BaseType)
+		}
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeTable writes the section of table t.
+func writeTable(b *strings.Builder, s introspect.Schema, t introspect.Table) {
+	fmt.Fprintf(b, "\n### %s\n", code(qualified(t.Schema, t.Name)))
+	if t.Comment != nil {
+		fmt.Fprintf(b, "\n%s\n", *t.Comment)
+	}
+	if t.Rows > 0 {
+		fmt.Fprintf(b, "\n%d rows\n", t.Rows)
+	}
+
+	b.WriteString("\n| Column | Type | Nullable | Default | Key | Comment |\n| --- | --- | --- | --- | --- | --- |\n")
+	for _, c := range t.Columns {
+		nullable := "no"
+		if c.Nullable {
+			nullable = "yes"
+		}
+		var keys []string
+		if c.PK {
+			keys = append(keys, "PK")
+		}
+		for _, fk := range s.ForeignKeys {
+			if fk.FromSchema == t.Schema && fk.FromTable == t.Name && hasFrom(fk, c.Name) {
+				keys = append(keys, "FK")
+				break
+			}
+		}
+		if c.Identity {
+			keys = append(keys, "identity")
+		}
+		def := ""
+		switch {
+		case c.Generated != nil:
+			def = "generated: " + code(*c.Generated)
+		case c.Default != nil:
+			def = code(*c.Default)
+		}
+		fmt.Fprintf(b, "| %s | %s | %s | %s | %s | %s |\n",
+			cell(c.Name), cell(c.Type), nullable, cell(def), strings.Join(keys, ", "), cell(deref(c.Comment)))
+	}
+
+	if len(t.Indexes) > 0 {
+		b.WriteString("\nIndexes:\n\n")
+	}
+	for _, idx := range t.Indexes {
+		var attrs []string
+		switch {
+		case idx.Primary:
+			attrs = append(attrs, "primary key")
+		case idx.Unique:
+			attrs = append(attrs, "unique")
+		}
+		if idx.Method != "" {
+			attrs = append(attrs, idx.Method)
+		}
+		if idx.Predicate != "" {
+			attrs = append(attrs, "where "+code(idx.Predicate))
+		}
+		fmt.Fprintf(b, "- %s(%s)%s\n", named(idx.Name), strings.Join(idx.Columns, ", "), list(attrs))
+	}
+
+	if len(t.Constraints) > 0 {
+		b.WriteString("\nConstraints:\n\n")
+	}
+	for _, c := range t.Constraints {
+		fmt.Fprintf(b, "- %s%s", named(c.Name), c.Type)
+		if len(c.Columns) > 0 {
+			fmt.Fprintf(b, " (%s)", strings.Join(c.Columns, ", "))
+		}
+		if c.Expression != "" {
+			fmt.Fprintf(b, " %s", code(c.Expression))
+		}
+		b.WriteString("\n")
+	}
+
+	first := true
+	for _, fk := range s.ForeignKeys {
+		if fk.FromSchema != t.Schema || fk.FromTable != t.Name {
+			continue
+		}
+		if first {
+			b.WriteString("\nForeign keys:\n\n")
+			first = false
+		}
+		var attrs []string
+		if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+			attrs = append(attrs, "on delete "+strings.ToLower(fk.OnDelete))
+		}
+		if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+			attrs = append(attrs, "on update "+strings.ToLower(fk.OnUpdate))
+		}
+		fmt.Fprintf(b, "- %s(%s) → %s (%s)%s\n", named(fk.Constraint), fk.FromColumn, code(qualified(fk.ToSchema, fk.ToTable)), fk.ToColumn, list(attrs))
+	}
+}
+
+// hasFrom reports whether column c is a referencing column of fk.
+func hasFrom(fk introspect.ForeignKey, c string) bool {
+	for _, p := range fk.Columns {
+		if p.From == c {
+			return true
+		}
+	}
+	return false
+}
+
+// warning returns a warning as a line of text.
+func warning(w introspect.Warning) string {
+	if w.Object == "" {
+		return w.Phase + ": " + w.Message
+	}
+	return w.Phase + " of " + w.Object + ": " + w.Message
+}
+
+// list returns attrs as ", a, b", or "" if there are none.
+func list(attrs []string) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(attrs, ", ")
+}
+
+// code returns s as inline code, or "" for an empty s.
+func code(s string) string {
+	if s == "" {
+		return ""
+	}
+	s = strings.Join(strings.Fields(s), " ")
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
+
+// named returns the name of an index or constraint as code followed by a
+// space, or "" if it has none.
+func named(name string) string {
+	if name == "" {
+		return ""
+	}
+	return code(name) + " "
+}
+
+// cell escapes s for a table cell, which must be a single line without
+// unescaped pipes.
+func cell(s string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(s), " "), "|", `\|`)
+}
+
+// deref returns *s, or "" if s is nil.
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// qualified returns schema.name, or name without a schema.
+func qualified(schema, name string) string {
+	if schema == "" {
+		return name
+	}
+	return schema + "." + name
+}
@@ -0,0 +1,78 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"erddiagram/internal/introspect"
+)
+
+func TestWrite(t *testing.T) {
+	comment := "orders | invoices"
+	def := "now()"
+	s := introspect.Schema{
+		Tables: []introspect.Table{
+			{Schema: "sales", Name: "orders", Rows: 42, Comment: &comment, Columns: []introspect.Column{
+				{Name: "id", Type: "bigint", PK: true, Identity: true},
+				{Name: "customer_id", Type: "integer"},
+				{Name: "created", Type: "timestamp", Nullable: true, Default: &def},
+			},
+				Indexes:     []introspect.Index{{Name: "orders_created_idx", Columns: []string{"created"}, Method: "btree"}},
+				Constraints: []introspect.Constraint{{Name: "orders_id_check", Type: introspect.ConstraintCheck, Expression: "id > 0"}},
+			},
+		},
+		ForeignKeys: []introspect.ForeignKey{
+			{FromSchema: "sales", FromTable: "orders", FromColumn: "customer_id", ToSchema: "crm", ToTable: "customers", ToColumn: "id",
+				Columns: []introspect.ColumnPair{{From: "customer_id", To: "id"}}, Constraint: "orders_customer_fk", OnDelete: "CASCADE", OnUpdate: "NO ACTION"},
+		},
+		Views: []introspect.View{
+			{Schema: "sales", Name: "recent", Materialized: true, Definition: "SELECT * FROM orders\n",
+				Columns: []introspect.Column{{Name: "id", Type: "bigint"}}, DependsOn: []introspect.ObjectRef{{Schema: "sales", Name: "orders"}}},
+		},
+		Types:    []introspect.UserType{{Name: "status", Kind: introspect.TypeEnum, Labels: []string{"new", "paid"}}},
+		Warnings: []introspect.Warning{{Object: "sales.orders", Phase: "triggers", Message: "permission denied"}},
+	}
+
+	var b strings.Builder
+	if err := Write(&b, s); err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	for _, want := range []string{
+		"# Schema\n\n1 tables, 1 views, 1 foreign keys\n",
+		"> - triggers of sales.orders: permission denied\n",
+		"\n### `sales.orders`\n\norders | invoices\n\n42 rows\n",
+		"| id | bigint | no |  | PK, identity |  |\n",
+		"| customer_id | integer | no |  | FK |  |\n",
+		"| created | timestamp | yes | `now()` |  |  |\n",
+		"- `orders_created_idx` (created), btree\n",
+		"- `orders_id_check` CHECK `id > 0`\n",
+		"- `orders_customer_fk` (customer_id) → `crm.customers` (id), on delete cascade\n",
+		"\n### `sales.recent`\n\nMaterialized view of `sales.orders`\n",
+		"```sql\nSELECT * FROM orders\n```\n",
+		"- `status` enum: new, paid\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("\ngot:\n%s\nwanted it to contain %q", b.String(), want)
+		}
+	}
+}
+
+func TestCell(t *testing.T) {
+	var tests = []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{"a | b", `a \| b`},
+		{"two\n  lines", "two lines"},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.in, func(t *testing.T) {
+			if got := cell(tt.in); got != tt.want {
+				t.Errorf("\ngot %q, wanted %q", got, tt.want)
+			}
+		})
+	}
+}
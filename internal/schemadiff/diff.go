@@ -0,0 +1,268 @@
+// Package schemadiff compares two schemas and lists the objects that were
+// added, removed or changed between them.
+package schemadiff
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"erddiagram/internal/introspect"
+)
+
+// Kinds of changes used in Change.Kind.
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change is a difference between two schemas.
+type Change struct {
+	Kind   string `json:"kind"`
+	Object string `json:"object"`           // e.g. "column sales.orders.total"
+	Detail string `json:"detail,omitempty"` // optional description of a change, e.g. "type: integer -> bigint"
+}
+
+// String returns the change as a line of text.
+func (c Change) String() string {
+	if c.Detail == "" {
+		return c.Kind + " " + c.Object
+	}
+	return c.Kind + " " + c.Object + ": " + c.Detail
+}
+
+// Diff returns the changes that turn old into new: tables, columns,
+// indexes, constraints, foreign keys, views, types, sequences, triggers
+// and routines. Row counts, sizes and extraction warnings are ignored, as
+// they change without a change of the schema. Changes are listed in the
+// order of old, followed by what new adds.
+func Diff(old, new introspect.Schema) []Change {
+	var d differ
+	diffObjects(&d, "table", old.Tables, new.Tables,
+		func(t introspect.Table) string { return qualified(t.Schema, t.Name) },
+		d.table)
+	diffObjects(&d, "foreign key", old.ForeignKeys, new.ForeignKeys, fkKey,
+		func(name string, a, b introspect.ForeignKey) {
+			d.field("foreign key "+name, "references", fkTarget(a), fkTarget(b))
+			d.field("foreign key "+name, "on delete", a.OnDelete, b.OnDelete)
+			d.field("foreign key "+name, "on update", a.OnUpdate, b.OnUpdate)
+			d.field("foreign key "+name, "deferrable", a.Deferrable, b.Deferrable)
+			d.field("foreign key "+name, "disabled", a.Disabled, b.Disabled)
+		})
+	diffObjects(&d, "view", old.Views, new.Views,
+		func(v introspect.View) string { return qualified(v.Schema, v.Name) },
+		func(name string, a, b introspect.View) {
+			d.field("view "+name, "materialized", a.Materialized, b.Materialized)
+			d.field("view "+name, "columns", columnNames(a.Columns), columnNames(b.Columns))
+			d.definition("view "+name, a.Definition, b.Definition)
+			d.field("view "+name, "comment", deref(a.Comment), deref(b.Comment))
+		})
+	diffObjects(&d, "type", old.Types, new.Types,
+		func(t introspect.UserType) string { return qualified(t.Schema, t.Name) },
+		func(name string, a, b introspect.UserType) {
+			d.field("type "+name, "kind", a.Kind, b.Kind)
+			d.field("type "+name, "labels", strings.Join(a.Labels, ", "), strings.Join(b.Labels, ", "))
+			d.field("type "+name, "base type", a.BaseType, b.BaseType)
+			d.field("type "+name, "check", a.Check, b.Check)
+			d.field("type "+name, "attributes", columnNames(a.Attributes), columnNames(b.Attributes))
+		})
+	diffObjects(&d, "sequence", old.Sequences, new.Sequences,
+		func(s introspect.Sequence) string { return qualified(s.Schema, s.Name) },
+		func(name string, a, b introspect.Sequence) {
+			d.field("sequence "+name, "data type", a.DataType, b.DataType)
+			d.field("sequence "+name, "increment", deref(a.Increment), deref(b.Increment))
+			d.field("sequence "+name, "cycle", a.Cycle, b.Cycle)
+		})
+	diffObjects(&d, "trigger", old.Triggers, new.Triggers,
+		func(t introspect.Trigger) string {
+			return qualified(t.Schema, t.Name) + " on " + qualified(t.Table.Schema, t.Table.Name)
+		},
+		func(name string, a, b introspect.Trigger) {
+			d.field("trigger "+name, "timing", a.Timing, b.Timing)
+			d.field("trigger "+name, "events", strings.Join(a.Events, ", "), strings.Join(b.Events, ", "))
+			d.field("trigger "+name, "disabled", a.Disabled, b.Disabled)
+			d.definition("trigger "+name, a.Definition, b.Definition)
+		})
+	diffObjects(&d, "routine", old.Routines, new.Routines,
+		func(r introspect.Routine) string {
+			return strings.ToLower(r.Kind) + " " + qualified(r.Schema, r.Name) + "(" + r.Arguments + ")"
+		},
+		func(name string, a, b introspect.Routine) {
+			d.field("routine "+name, "return type", a.ReturnType, b.ReturnType)
+			d.definition("routine "+name, a.Definition, b.Definition)
+		})
+	return d.changes
+}
+
+// differ collects changes.
+type differ struct {
+	changes []Change
+}
+
+// add records a change of object.
+func (d *differ) add(kind, object, detail string) {
+	d.changes = append(d.changes, Change{Kind: kind, Object: object, Detail: detail})
+}
+
+// field records a change of the attribute name of object from a to b,
+// which are values of the same comparable type.
+func (d *differ) field(object, name string, a, b any) {
+	if a != b {
+		d.add(Changed, object, fmt.Sprintf("%s: %v -> %v", name, display(a), display(b)))
+	}
+}
+
+// definition records a change of the definition of object, ignoring
+// differences in white space.
+func (d *differ) definition(object, a, b string) {
+	if strings.Join(strings.Fields(a), " ") != strings.Join(strings.Fields(b), " ") {
+		d.add(Changed, object, "definition")
+	}
+}
+
+// table records the changes of table name from a to b.
+func (d *differ) table(name string, a, b introspect.Table) {
+	diffObjects(d, "column "+name+".", a.Columns, b.Columns,
+		func(c introspect.Column) string { return c.Name },
+		func(col string, x, y introspect.Column) {
+			object := "column " + name + "." + col
+			d.field(object, "type", x.Type, y.Type)
+			d.field(object, "nullable", x.Nullable, y.Nullable)
+			d.field(object, "primary key", x.PK, y.PK)
+			d.field(object, "default", deref(x.Default), deref(y.Default))
+			d.field(object, "identity", x.Identity, y.Identity)
+			d.field(object, "generated", deref(x.Generated), deref(y.Generated))
+			d.field(object, "max length", deref(x.MaxLength), deref(y.MaxLength))
+			d.field(object, "precision", deref(x.Precision), deref(y.Precision))
+			d.field(object, "scale", deref(x.Scale), deref(y.Scale))
+			d.field(object, "collation", deref(x.Collation), deref(y.Collation))
+			d.field(object, "comment", deref(x.Comment), deref(y.Comment))
+		})
+	diffObjects(d, "index "+name+".", a.Indexes, b.Indexes, indexKey,
+		func(idx string, x, y introspect.Index) {
+			object := "index " + name + "." + idx
+			d.field(object, "columns", strings.Join(x.Columns, ", "), strings.Join(y.Columns, ", "))
+			d.field(object, "include", strings.Join(x.Include, ", "), strings.Join(y.Include, ", "))
+			d.field(object, "unique", x.Unique, y.Unique)
+			d.field(object, "primary", x.Primary, y.Primary)
+			d.field(object, "predicate", x.Predicate, y.Predicate)
+			d.field(object, "method", x.Method, y.Method)
+		})
+	diffObjects(d, "constraint "+name+".", a.Constraints, b.Constraints, constraintKey,
+		func(c string, x, y introspect.Constraint) {
+			object := "constraint " + name + "." + c
+			d.field(object, "columns", strings.Join(x.Columns, ", "), strings.Join(y.Columns, ", "))
+			d.definition(object, x.Expression, y.Expression)
+		})
+	d.field("table "+name, "comment", deref(a.Comment), deref(b.Comment))
+	d.field("table "+name, "partitioning", partitioning(a.Partitioning), partitioning(b.Partitioning))
+}
+
+// diffObjects records the objects of old that are missing in new as
+// removed, those only new has as added, and calls changed for the ones
+// both have, matched by key. Objects are named kind followed by their key,
+// or kind directly followed by it if kind ends in a dot.
+func diffObjects[T any](d *differ, kind string, old, new []T, key func(T) string, changed func(name string, a, b T)) {
+	object := func(k string) string {
+		if strings.HasSuffix(kind, ".") {
+			return kind + k
+		}
+		return kind + " " + k
+	}
+	byKey := make(map[string]T, len(new))
+	for _, n := range new {
+		byKey[key(n)] = n
+	}
+	seen := map[string]bool{}
+	for _, o := range old {
+		k := key(o)
+		seen[k] = true
+		n, ok := byKey[k]
+		if !ok {
+			d.add(Removed, object(k), "")
+			continue
+		}
+		changed(k, o, n)
+	}
+	for _, n := range new {
+		if k := key(n); !seen[k] {
+			seen[k] = true
+			d.add(Added, object(k), "")
+		}
+	}
+}
+
+// fkKey identifies a foreign key by its table and constraint name, or by
+// its columns if it has no name.
+func fkKey(fk introspect.ForeignKey) string {
+	if fk.Constraint != "" {
+		return qualified(fk.FromSchema, fk.FromTable) + "." + fk.Constraint
+	}
+	return qualified(fk.FromSchema, fk.FromTable) + "(" + fk.FromColumn + ")"
+}
+
+// fkTarget describes the columns a foreign key references.
+func fkTarget(fk introspect.ForeignKey) string {
+	return "(" + fk.FromColumn + ") " + qualified(fk.ToSchema, fk.ToTable) + "(" + fk.ToColumn + ")"
+}
+
+// indexKey identifies an index by its name, or by its columns if it has none.
+func indexKey(idx introspect.Index) string {
+	if idx.Name != "" {
+		return idx.Name
+	}
+	return "(" + strings.Join(idx.Columns, ", ") + ")"
+}
+
+// constraintKey identifies a constraint by its name, or by its type and
+// columns or expression if it has none.
+func constraintKey(c introspect.Constraint) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return strings.ToLower(c.Type) + "(" + cmp.Or(strings.Join(c.Columns, ", "), c.Expression) + ")"
+}
+
+// partitioning describes the partitioning of a table, without the
+// partitions, which come and go with the data.
+func partitioning(p *introspect.Partitioning) string {
+	if p == nil {
+		return ""
+	}
+	return p.Strategy + " (" + strings.Join(p.Columns, ", ") + ")"
+}
+
+// columnNames returns the names and types of columns as one string.
+func columnNames(cols []introspect.Column) string {
+	var parts []string
+	for _, c := range cols {
+		parts = append(parts, strings.TrimSpace(c.Name+" "+c.Type))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// display returns v for a change detail, with empty strings shown as none.
+func display(v any) any {
+	if s, ok := v.(string); ok && s == "" {
+		return "none"
+	}
+	return v
+}
+
+// deref returns *p, or the zero value if p is nil.
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+// qualified returns schema.name, or name without a schema.
+func qualified(schema, name string) string {
+	if schema == "" {
+		return name
+	}
+	return schema + "." + name
+}
@@ -0,0 +1,140 @@
+package schemadiff
+
+import (
+	"reflect"
+	"testing"
+
+	"erddiagram/internal/introspect"
+)
+
+func TestDiff(t *testing.T) {
+	base := introspect.Schema{
+		Tables: []introspect.Table{
+			{Schema: "sales", Name: "orders", Rows: 10, Columns: []introspect.Column{
+				{Name: "id", Type: "integer", PK: true},
+				{Name: "total", Type: "integer", Nullable: true},
+			}, Indexes: []introspect.Index{{Name: "orders_pkey", Columns: []string{"id"}, Unique: true, Primary: true}}},
+			{Schema: "sales", Name: "customers", Columns: []introspect.Column{{Name: "id", Type: "integer", PK: true}}},
+		},
+		ForeignKeys: []introspect.ForeignKey{
+			{FromSchema: "sales", FromTable: "orders", FromColumn: "customer_id", ToSchema: "sales", ToTable: "customers", ToColumn: "id", Constraint: "orders_customer_fk"},
+		},
+		Views: []introspect.View{{Schema: "sales", Name: "big_orders", Definition: "SELECT *\n  FROM orders"}},
+		Types: []introspect.UserType{{Name: "status", Kind: introspect.TypeEnum, Labels: []string{"new", "paid"}}},
+	}
+
+	var tests = []struct {
+		name   string
+		change func(s *introspect.Schema)
+		want   []Change
+	}{
+		{"unchanged", func(s *introspect.Schema) {}, nil},
+		{"rows and warnings are ignored", func(s *introspect.Schema) {
+			s.Tables[0].Rows = 20
+			s.Tables[0].Size8kPages = 3
+			s.Warnings = []introspect.Warning{{Phase: "indexes", Message: "denied"}}
+		}, nil},
+		{"view definition white space is ignored", func(s *introspect.Schema) {
+			s.Views[0].Definition = "SELECT * FROM orders"
+		}, nil},
+		{"column type and nullability", func(s *introspect.Schema) {
+			s.Tables[0].Columns[1].Type = "bigint"
+			s.Tables[0].Columns[1].Nullable = false
+		}, []Change{
+			{Changed, "column sales.orders.total", "type: integer -> bigint"},
+			{Changed, "column sales.orders.total", "nullable: true -> false"},
+		}},
+		{"added and removed columns", func(s *introspect.Schema) {
+			s.Tables[0].Columns = []introspect.Column{s.Tables[0].Columns[0], {Name: "note", Type: "text"}}
+		}, []Change{
+			{Removed, "column sales.orders.total", ""},
+			{Added, "column sales.orders.note", ""},
+		}},
+		{"added and removed tables", func(s *introspect.Schema) {
+			s.Tables = []introspect.Table{s.Tables[0], {Name: "audit"}}
+		}, []Change{
+			{Removed, "table sales.customers", ""},
+			{Added, "table audit", ""},
+		}},
+		{"default and comment", func(s *introspect.Schema) {
+			def := "0"
+			s.Tables[0].Columns[1].Default = &def
+			comment := "all orders"
+			s.Tables[0].Comment = &comment
+		}, []Change{
+			{Changed, "column sales.orders.total", "default: none -> 0"},
+			{Changed, "table sales.orders", "comment: none -> all orders"},
+		}},
+		{"index", func(s *introspect.Schema) {
+			s.Tables[0].Indexes = append(s.Tables[0].Indexes, introspect.Index{Name: "orders_total_idx", Columns: []string{"total"}})
+			s.Tables[0].Indexes[0].Columns = []string{"id", "total"}
+		}, []Change{
+			{Changed, "index sales.orders.orders_pkey", "columns: id -> id, total"},
+			{Added, "index sales.orders.orders_total_idx", ""},
+		}},
+		{"foreign key", func(s *introspect.Schema) {
+			s.ForeignKeys[0].OnDelete = "CASCADE"
+			s.ForeignKeys = append(s.ForeignKeys, introspect.ForeignKey{FromTable: "audit", FromColumn: "order_id", ToSchema: "sales", ToTable: "orders", ToColumn: "id"})
+		}, []Change{
+			{Changed, "foreign key sales.orders.orders_customer_fk", "on delete: none -> CASCADE"},
+			{Added, "foreign key audit(order_id)", ""},
+		}},
+		{"view and type", func(s *introspect.Schema) {
+			s.Views[0].Definition = "SELECT * FROM orders WHERE total > 100"
+			s.Types[0].Labels = append(s.Types[0].Labels, "shipped")
+		}, []Change{
+			{Changed, "view sales.big_orders", "definition"},
+			{Changed, "type status", "labels: new, paid -> new, paid, shipped"},
+		}},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			changed := clone(t, base)
+			tt.change(&changed)
+			if got := Diff(base, changed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\ngot %v, wanted %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	var tests = []struct {
+		change Change
+		want   string
+	}{
+		{Change{Added, "table audit", ""}, "added table audit"},
+		{Change{Changed, "column orders.total", "type: integer -> bigint"}, "changed column orders.total: type: integer -> bigint"},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.change.String(); got != tt.want {
+				t.Errorf("\ngot %q, wanted %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// clone returns a deep copy of s, so that test cases can change it.
+func clone(t *testing.T, s introspect.Schema) introspect.Schema {
+	t.Helper()
+	c := s
+	c.Tables = nil
+	for _, tb := range s.Tables {
+		tb.Columns = append([]introspect.Column(nil), tb.Columns...)
+		tb.Indexes = append([]introspect.Index(nil), tb.Indexes...)
+		c.Tables = append(c.Tables, tb)
+	}
+	c.ForeignKeys = append([]introspect.ForeignKey(nil), s.ForeignKeys...)
+	c.Views = append([]introspect.View(nil), s.Views...)
+	c.Types = nil
+	for _, ut := range s.Types {
+		ut.Labels = append([]string(nil), ut.Labels...)
+		c.Types = append(c.Types, ut)
+	}
+	return c
+}
@@ -0,0 +1,323 @@
+package session
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"erddiagram/pkg/config"
+)
+
+// CookieName is the name of the cookie that carries the session id.
+const CookieName = "erd_session"
+
+// DefaultName is the connection used by sessions that did not select one.
+const DefaultName = "default"
+
+// DefaultTTL is the idle time after which a session expires when the store
+// has no other limit.
+const DefaultTTL = 24 * time.Hour
+
+var (
+	ErrNotFound = errors.New("connection not found")
+	ErrShared   = errors.New("connection is defined in the config file")
+)
+
+// Profile is a named database connection.
+type Profile struct {
+	Name   string
+	Config config.DBConfig
+	Shared bool // defined in the config file and visible in every session
+}
+
+// session holds the connections one browser created and selected.
+type session struct {
+	profiles map[string]Profile
+	selected string
+	lastSeen time.Time
+}
+
+// Store keeps the shared connection profiles and the sessions of the users
+// of the server. It is safe for concurrent use.
+type Store struct {
+	ttl     time.Duration
+	acquire func(driver, dsn string)
+	release func(driver, dsn string)
+	now     func() time.Time
+
+	mu       sync.Mutex
+	shared   map[string]Profile
+	sessions map[string]*session
+}
+
+// NewStore returns a Store with the shared profiles. Sessions expire after
+// ttl without a request, or DefaultTTL if ttl is 0. acquire is called with
+// the driver and dsn of a connection when a profile starts using it, and
+// release when no profile uses it anymore; either may be nil.
+func NewStore(shared []Profile, ttl time.Duration, acquire, release func(driver, dsn string)) *Store {
+	s := &Store{
+		ttl:      cmp.Or(ttl, DefaultTTL),
+		acquire:  acquire,
+		release:  release,
+		now:      time.Now,
+		shared:   map[string]Profile{},
+		sessions: map[string]*session{},
+	}
+	for _, p := range shared {
+		p.Shared = true
+		s.acquireUnused(p)
+		s.shared[p.Name] = p
+	}
+	return s
+}
+
+// ID returns the session id of the request, or "" if it has no session
+// or an expired one. It does not start a session, so that requests that
+// only read keep no state; see Start.
+func (s *Store) ID(r *http.Request) string {
+	s.mu.Lock()
+	unused := s.expire()
+	id := s.find(r)
+	s.mu.Unlock()
+	s.releaseAll(unused)
+	return id
+}
+
+// Start returns the session id of the request, starting a session and
+// setting its cookie on w if the request has none or an expired one. Call
+// it before storing state in the session.
+func (s *Store) Start(w http.ResponseWriter, r *http.Request) string {
+	s.mu.Lock()
+	unused := s.expire()
+	id := s.find(r)
+	if id == "" {
+		b := make([]byte, 16)
+		rand.Read(b)
+		id = hex.EncodeToString(b)
+		s.sessions[id] = &session{profiles: map[string]Profile{}, lastSeen: s.now()}
+		http.SetCookie(w, &http.Cookie{
+			Name:     CookieName,
+			Value:    id,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	s.mu.Unlock()
+	s.releaseAll(unused)
+	return id
+}
+
+// find returns the id of the session of the cookie of r and marks it as
+// seen, or "" if there is none. s.mu must be held.
+func (s *Store) find(r *http.Request) string {
+	c, err := r.Cookie(CookieName)
+	if err != nil {
+		return ""
+	}
+	sess, ok := s.sessions[c.Value]
+	if !ok {
+		return ""
+	}
+	sess.lastSeen = s.now()
+	return c.Value
+}
+
+// expire drops the sessions idle for longer than the ttl and returns the
+// connections to release. s.mu must be held.
+func (s *Store) expire() [][2]string {
+	var dropped []Profile
+	for id, sess := range s.sessions {
+		if s.now().Sub(sess.lastSeen) > s.ttl {
+			delete(s.sessions, id)
+			for _, p := range sess.profiles {
+				dropped = append(dropped, p)
+			}
+		}
+	}
+	return s.unused(dropped...)
+}
+
+// List returns the connections visible in session id sorted by name, and
+// the name of the selected one. A session connection hides a shared one of
+// the same name.
+func (s *Store) List(id string) ([]Profile, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	visible := map[string]Profile{}
+	for name, p := range s.shared {
+		visible[name] = p
+	}
+	selected := DefaultName
+	if sess, ok := s.sessions[id]; ok {
+		for name, p := range sess.profiles {
+			visible[name] = p
+		}
+		if sess.selected != "" {
+			selected = sess.selected
+		}
+	}
+	out := make([]Profile, 0, len(visible))
+	for _, p := range visible {
+		out = append(out, p)
+	}
+	slices.SortFunc(out, func(a, b Profile) int { return strings.Compare(a.Name, b.Name) })
+	return out, selected
+}
+
+// Get returns the connection name of session id, or the selected one if
+// name is empty.
+func (s *Store) Get(id, name string) (Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.sessions[id]
+	if name == "" {
+		name = DefaultName
+		if sess != nil && sess.selected != "" {
+			name = sess.selected
+		}
+	}
+	if sess != nil {
+		if p, ok := sess.profiles[name]; ok {
+			return p, nil
+		}
+	}
+	if p, ok := s.shared[name]; ok {
+		return p, nil
+	}
+	return Profile{}, fmt.Errorf("%w: %q", ErrNotFound, name)
+}
+
+// Put adds connection p to session id, or replaces the one of the same
+// name, and selects it if selectIt is set.
+func (s *Store) Put(id string, p Profile, selectIt bool) error {
+	if p.Name == "" {
+		return errors.New("connection name is empty")
+	}
+	p.Shared = false
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		s.mu.Unlock()
+		return errors.New("session expired")
+	}
+	old, replaced := sess.profiles[p.Name]
+	s.acquireUnused(p)
+	sess.profiles[p.Name] = p
+	if selectIt {
+		sess.selected = p.Name
+	}
+	var unused [][2]string
+	if replaced {
+		unused = s.unused(old)
+	}
+	s.mu.Unlock()
+	s.releaseAll(unused)
+	return nil
+}
+
+// Select makes connection name the one session id uses by default.
+func (s *Store) Select(id, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return errors.New("session expired")
+	}
+	if _, ok := sess.profiles[name]; !ok {
+		if _, ok := s.shared[name]; !ok {
+			return fmt.Errorf("%w: %q", ErrNotFound, name)
+		}
+	}
+	sess.selected = name
+	return nil
+}
+
+// Delete removes connection name from session id. Shared connections
+// cannot be deleted.
+func (s *Store) Delete(id, name string) error {
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	if ok {
+		if p, ok := sess.profiles[name]; ok {
+			delete(sess.profiles, name)
+			if sess.selected == name {
+				sess.selected = ""
+			}
+			unused := s.unused(p)
+			s.mu.Unlock()
+			s.releaseAll(unused)
+			return nil
+		}
+	}
+	defer s.mu.Unlock()
+	if _, ok := s.shared[name]; ok {
+		return fmt.Errorf("%w: %q", ErrShared, name)
+	}
+	return fmt.Errorf("%w: %q", ErrNotFound, name)
+}
+
+// acquireUnused calls acquire for the connection of p, a profile about to
+// be stored, unless a stored profile uses it. s.mu must be held.
+func (s *Store) acquireUnused(p Profile) {
+	if s.acquire == nil {
+		return
+	}
+	driver, dsn, err := config.BuildDriverAndDSN(p.Config)
+	if err == nil && !s.inUse()[[2]string{driver, dsn}] {
+		s.acquire(driver, dsn)
+	}
+}
+
+// unused returns the drivers and dsns of the connections of the dropped
+// profiles that no remaining profile uses. s.mu must be held.
+func (s *Store) unused(dropped ...Profile) [][2]string {
+	if s.release == nil || len(dropped) == 0 {
+		return nil
+	}
+	var unused [][2]string
+	inUse := s.inUse()
+	for _, p := range dropped {
+		driver, dsn, err := config.BuildDriverAndDSN(p.Config)
+		if err == nil && !inUse[[2]string{driver, dsn}] {
+			inUse[[2]string{driver, dsn}] = true // release once
+			unused = append(unused, [2]string{driver, dsn})
+		}
+	}
+	return unused
+}
+
+// releaseAll calls release for the connections returned by unused. s.mu
+// must not be held, closing a pool may wait for its connections.
+func (s *Store) releaseAll(unused [][2]string) {
+	for _, c := range unused {
+		s.release(c[0], c[1])
+	}
+}
+
+// inUse returns the drivers and dsns of the connections of the stored
+// profiles. s.mu must be held.
+func (s *Store) inUse() map[[2]string]bool {
+	inUse := map[[2]string]bool{}
+	use := func(p Profile) {
+		if driver, dsn, err := config.BuildDriverAndDSN(p.Config); err == nil {
+			inUse[[2]string{driver, dsn}] = true
+		}
+	}
+	for _, p := range s.shared {
+		use(p)
+	}
+	for _, sess := range s.sessions {
+		for _, p := range sess.profiles {
+			use(p)
+		}
+	}
+	return inUse
+}
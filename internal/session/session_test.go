@@ -0,0 +1,150 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"erddiagram/pkg/config"
+)
+
+func sqliteProfile(name, file string) Profile {
+	return Profile{Name: name, Config: config.DBConfig{Type: "sqlite", DatabaseName: file}}
+}
+
+// newSession starts a session in s and returns its id.
+func newSession(s *Store) string {
+	return s.Start(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestStoreID(t *testing.T) {
+	s := NewStore(nil, time.Hour, nil, nil)
+
+	// reading requests start no session
+	for range 3 {
+		if id := s.ID(httptest.NewRequest(http.MethodGet, "/", nil)); id != "" {
+			t.Errorf("\ngot session %s for a request without a cookie, wanted none", id)
+		}
+	}
+	if len(s.sessions) != 0 {
+		t.Errorf("\ngot %d sessions, wanted none", len(s.sessions))
+	}
+
+	w := httptest.NewRecorder()
+	id := s.Start(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != CookieName || cookies[0].Value != id || !cookies[0].HttpOnly {
+		t.Fatalf("\ngot cookies %v, wanted an http only session cookie %s", cookies, id)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookies[0])
+	if again := s.ID(r); again != id {
+		t.Errorf("\ngot session %s for the cookie, wanted %s", again, id)
+	}
+	w = httptest.NewRecorder()
+	if again := s.Start(w, r); again != id || len(w.Result().Cookies()) != 0 {
+		t.Errorf("\ngot session %s and cookies %v starting the session of the cookie, wanted %s", again, w.Result().Cookies(), id)
+	}
+
+	// an expired session starts a new one
+	s.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if again := s.ID(r); again != "" {
+		t.Errorf("\ngot the expired session %s, wanted none", again)
+	}
+	if again := s.Start(httptest.NewRecorder(), r); again == id {
+		t.Errorf("\ngot the expired session %s, wanted a new one", id)
+	}
+}
+
+func TestStoreConnections(t *testing.T) {
+	var acquired, released []string
+	s := NewStore([]Profile{sqliteProfile(DefaultName, "shared.db")}, time.Hour, func(driver, dsn string) {
+		acquired = append(acquired, dsn)
+	}, func(driver, dsn string) {
+		released = append(released, dsn)
+	})
+	alice, bob := newSession(s), newSession(s)
+
+	if err := s.Put(alice, sqliteProfile("mine", "alice.db"), true); err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+
+	var tests = []struct {
+		name    string
+		session string
+		conn    string
+		file    string
+		err     error
+	}{
+		{"selected", alice, "", "alice.db", nil},
+		{"shared by name", alice, DefaultName, "shared.db", nil},
+		{"default of another session", bob, "", "shared.db", nil},
+		{"private to another session", bob, "mine", "", ErrNotFound},
+		{"unknown session", "nosuchsession", "", "shared.db", nil},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := s.Get(tt.session, tt.conn)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("\ngot error %v, wanted %v", err, tt.err)
+			} else if p.Config.DatabaseName != tt.file {
+				t.Errorf("\ngot connection to %q, wanted %q", p.Config.DatabaseName, tt.file)
+			}
+		})
+	}
+
+	list, selected := s.List(alice)
+	if len(list) != 2 || list[0].Name != DefaultName || list[1].Name != "mine" || selected != "mine" {
+		t.Errorf("\ngot connections %v selected %q, wanted default and mine selected", list, selected)
+	}
+
+	if err := s.Select(bob, "mine"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("\ngot error %v selecting another session's connection, wanted %v", err, ErrNotFound)
+	}
+	if err := s.Delete(alice, DefaultName); !errors.Is(err, ErrShared) {
+		t.Errorf("\ngot error %v deleting a shared connection, wanted %v", err, ErrShared)
+	}
+
+	// bob uses the same database, so it stays open until both are gone
+	if err := s.Put(bob, sqliteProfile("same", "alice.db"), false); err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	if err := s.Delete(alice, "mine"); err != nil || len(released) != 0 {
+		t.Errorf("\ngot error %v and released %v, wanted nothing released", err, released)
+	}
+	if p, _ := s.Get(alice, ""); p.Name != DefaultName {
+		t.Errorf("\ngot %q after deleting the selected connection, wanted %q", p.Name, DefaultName)
+	}
+	if err := s.Delete(bob, "same"); err != nil || !reflect.DeepEqual(released, []string{"file:alice.db?mode=ro"}) {
+		t.Errorf("\ngot error %v and released %v, wanted alice.db released", err, released)
+	}
+	if want := []string{"file:shared.db?mode=ro", "file:alice.db?mode=ro"}; !reflect.DeepEqual(acquired, want) {
+		t.Errorf("\ngot acquired %v, wanted %v", acquired, want)
+	}
+}
+
+func TestStoreExpire(t *testing.T) {
+	var s *Store
+	var released []string
+	s = NewStore(nil, time.Hour, nil, func(driver, dsn string) {
+		// release runs without the lock, so it may use the store
+		s.List("")
+		released = append(released, dsn)
+	})
+	id := newSession(s)
+	if err := s.Put(id, sqliteProfile("mine", "mine.db"), true); err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+
+	s.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	s.ID(httptest.NewRequest(http.MethodGet, "/", nil))
+	if !reflect.DeepEqual(released, []string{"file:mine.db?mode=ro"}) || len(s.sessions) != 0 {
+		t.Errorf("\ngot released %v with %d sessions left, wanted mine.db released with the session", released, len(s.sessions))
+	}
+}
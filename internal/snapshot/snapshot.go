@@ -0,0 +1,228 @@
+// Package snapshot keeps the history of the schemas extracted from each
+// connection in a directory, so that earlier versions can be shown again.
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"erddiagram/internal/introspect"
+)
+
+// ErrNotFound is returned for a snapshot that does not exist.
+var ErrNotFound = errors.New("snapshot not found")
+
+// timeLayout formats the time a snapshot was taken in its id, so that ids
+// sort by time.
+const timeLayout = "20060102T150405.000Z"
+
+// idPattern matches snapshot ids: the time taken and the schema hash.
+var idPattern = regexp.MustCompile(`^\d{8}T\d{6}\.\d{3}Z-[0-9a-f]{64}$`)
+
+// Snapshot describes a saved schema.
+type Snapshot struct {
+	ID         string    `json:"id"`
+	Connection string    `json:"connection"`
+	Taken      time.Time `json:"taken"`
+	Hash       string    `json:"hash"` // see Hash
+}
+
+// file is the content of a snapshot file.
+type file struct {
+	Snapshot
+	Schema introspect.Schema `json:"schema"`
+}
+
+// Store saves snapshots as JSON files in a directory per connection. A
+// schema is saved only if its Hash differs from the last snapshot of its
+// connection, so the history lists the changes of the schema, and a
+// schema that changes back is saved again. It is safe for concurrent use.
+type Store struct {
+	dir string
+
+	mu     sync.Mutex
+	latest map[string]string // hash of the last snapshot per connection, once read
+}
+
+// Open returns a Store that keeps its snapshots in dir, creating it if
+// necessary.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir, latest: map[string]string{}}, nil
+}
+
+// Hash returns the hash of the structure of s: the hash of s without the
+// row counts and sizes of its tables, which change without a change of
+// the schema.
+func Hash(s introspect.Schema) string {
+	tables := make([]introspect.Table, len(s.Tables))
+	for i, t := range s.Tables {
+		t.Rows, t.Size8kPages = 0, 0
+		if t.Partitioning != nil {
+			p := *t.Partitioning
+			p.Partitions = slices.Clone(p.Partitions)
+			for j := range p.Partitions {
+				p.Partitions[j].Rows, p.Partitions[j].Size8kPages = 0, 0
+			}
+			t.Partitioning = &p
+		}
+		tables[i] = t
+	}
+	s.Tables = tables
+	return s.Hash()
+}
+
+// Save saves s as a snapshot of connection conn taken at taken, unless the
+// last snapshot of conn has the same Hash. It reports whether a snapshot
+// was saved. The row counts and sizes of a snapshot are those of the first
+// extraction of its version of the schema.
+func (st *Store) Save(conn string, s introspect.Schema, taken time.Time) (Snapshot, bool, error) {
+	hash := Hash(s)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	last, ok := st.latest[conn]
+	if !ok {
+		list, err := st.list(conn)
+		if err != nil {
+			return Snapshot{}, false, err
+		}
+		if len(list) > 0 {
+			last = list[0].Hash
+		}
+	}
+	if last == hash {
+		st.latest[conn] = hash
+		return Snapshot{}, false, nil
+	}
+
+	taken = taken.UTC()
+	snap := Snapshot{ID: taken.Format(timeLayout) + "-" + hash, Connection: conn, Taken: taken, Hash: hash}
+	b, err := json.Marshal(file{Snapshot: snap, Schema: s})
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+	dir := filepath.Join(st.dir, dirName(conn))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return Snapshot{}, false, err
+	}
+	// write to a temporary file first, so that a failed write leaves no
+	// partial snapshot behind
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dir, snap.ID+".json"))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return Snapshot{}, false, err
+	}
+	st.latest[conn] = hash
+	return snap, true, nil
+}
+
+// List returns the snapshots of connection conn, newest first.
+func (st *Store) List(conn string) ([]Snapshot, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.list(conn)
+}
+
+func (st *Store) list(conn string) ([]Snapshot, error) {
+	entries, err := os.ReadDir(filepath.Join(st.dir, dirName(conn)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var list []Snapshot
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || !idPattern.MatchString(id) {
+			continue
+		}
+		stamp, hash, _ := strings.Cut(id, "-")
+		taken, err := time.Parse(timeLayout, stamp)
+		if err != nil {
+			continue
+		}
+		list = append(list, Snapshot{ID: id, Connection: conn, Taken: taken, Hash: hash})
+	}
+	// the ids sort by time
+	slices.Reverse(list)
+	return list, nil
+}
+
+// Connections returns the names of the connections with snapshots, sorted.
+func (st *Store) Connections() ([]string, error) {
+	entries, err := os.ReadDir(st.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if name, ok := connName(e.Name()); ok && e.IsDir() {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// Load returns the snapshot id of connection conn and its schema.
+func (st *Store) Load(conn, id string) (Snapshot, introspect.Schema, error) {
+	if !idPattern.MatchString(id) {
+		return Snapshot{}, introspect.Schema{}, ErrNotFound
+	}
+	b, err := os.ReadFile(filepath.Join(st.dir, dirName(conn), id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Snapshot{}, introspect.Schema{}, ErrNotFound
+	} else if err != nil {
+		return Snapshot{}, introspect.Schema{}, err
+	}
+	var f file
+	if err := json.Unmarshal(b, &f); err != nil {
+		return Snapshot{}, introspect.Schema{}, fmt.Errorf("snapshot %s: %w", id, err)
+	}
+	return f.Snapshot, f.Schema, nil
+}
+
+// dirName returns the directory of the snapshots of connection conn. Bytes
+// other than ASCII letters, digits, - and _ are escaped as %XX, so that any
+// name is a single path element.
+func dirName(conn string) string {
+	var b strings.Builder
+	for i := 0; i < len(conn); i++ {
+		c := conn[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// connName reverses dirName. It reports false for names dirName does not
+// return.
+func connName(dir string) (string, bool) {
+	name, err := url.PathUnescape(dir)
+	return name, err == nil && name != "" && dirName(name) == dir
+}
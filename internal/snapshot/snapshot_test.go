@@ -0,0 +1,137 @@
+package snapshot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"erddiagram/internal/introspect"
+)
+
+func TestStore(t *testing.T) {
+	st, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	v1 := introspect.Schema{Tables: []introspect.Table{{Name: "orders", Rows: 10, Columns: []introspect.Column{{Name: "id", Type: "integer"}}}}}
+	v1grown := introspect.Schema{Tables: []introspect.Table{{Name: "orders", Rows: 99, Size8kPages: 5, Columns: v1.Tables[0].Columns}}}
+	v2 := introspect.Schema{Tables: []introspect.Table{{Name: "orders", Columns: []introspect.Column{{Name: "id", Type: "bigint"}}}}}
+	start := time.Date(2026, 10, 17, 6, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		name   string
+		conn   string
+		schema introspect.Schema
+		want   bool
+	}{
+		{"first", "prod", v1, true},
+		{"identical", "prod", v1, false},
+		{"only row counts changed", "prod", v1grown, false},
+		{"changed", "prod", v2, true},
+		{"changed back", "prod", v1, true},
+		{"other connection", "a/../b", v1, true},
+	}
+
+	var saved []Snapshot
+	for i, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			snap, ok, err := st.Save(tt.conn, tt.schema, start.Add(time.Duration(i)*time.Minute))
+			if err != nil {
+				t.Fatalf("\ngot unexpected error: \"%v\"", err)
+			}
+			if ok != tt.want {
+				t.Errorf("\ngot saved %v, wanted %v", ok, tt.want)
+			}
+			if ok && tt.conn == "prod" {
+				saved = append(saved, snap)
+			}
+		})
+	}
+
+	list, err := st.List("prod")
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	if len(list) != 3 || list[0] != saved[2] || list[2] != saved[0] {
+		t.Errorf("\ngot %v, wanted the saved snapshots newest first: %v", list, saved)
+	}
+	if list[0].Hash != list[2].Hash || list[0].Hash == list[1].Hash {
+		t.Errorf("\ngot hashes %s, %s and %s, wanted the first and last to be equal", list[0].Hash, list[1].Hash, list[2].Hash)
+	}
+
+	snap, s, err := st.Load("prod", saved[1].ID)
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	if snap != saved[1] || !reflect.DeepEqual(s, v2) {
+		t.Errorf("\ngot %v with %v, wanted %v with %v", snap, s, saved[1], v2)
+	}
+
+	conns, err := st.Connections()
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	if want := []string{"a/../b", "prod"}; !reflect.DeepEqual(conns, want) {
+		t.Errorf("\ngot connections %q, wanted %q", conns, want)
+	}
+
+	// a new store reads the last hash from the directory
+	st2, err := Open(st.dir)
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	if _, ok, err := st2.Save("prod", v1grown, start.Add(time.Hour)); ok || err != nil {
+		t.Errorf("\ngot saved %v with error %v, wanted the snapshot to be skipped", ok, err)
+	}
+}
+
+func TestLoadNotFound(t *testing.T) {
+	st, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	var tests = []struct {
+		name string
+		id   string
+	}{
+		{"missing", "20261017T060000.000Z-" + Hash(introspect.Schema{})},
+		{"path", "../../etc/passwd"},
+		{"empty", ""},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := st.Load("prod", tt.id); !errors.Is(err, ErrNotFound) {
+				t.Errorf("\ngot error %v, wanted %v", err, ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestDirName(t *testing.T) {
+	var tests = []struct {
+		conn string
+		want string
+	}{
+		{"prod_db-1", "prod_db-1"},
+		{"..", "%2E%2E"},
+		{"a/b c", "a%2Fb%20c"},
+		{"ü", "%C3%BC"},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.conn, func(t *testing.T) {
+			got := dirName(tt.conn)
+			if got != tt.want {
+				t.Errorf("\ngot %q, wanted %q", got, tt.want)
+			}
+			if name, ok := connName(got); !ok || name != tt.conn {
+				t.Errorf("\ngot %q, %v back, wanted %q", name, ok, tt.conn)
+			}
+		})
+	}
+}
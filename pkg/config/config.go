@@ -3,28 +3,72 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 type DBConfig struct {
-	Type         string `yaml:"type" json:"type"`
-	Host         string `yaml:"host" json:"host"`
-	Port         int    `yaml:"port" json:"port"`
-	Username     string `yaml:"username" json:"username"`
-	Password     string `yaml:"password" json:"password"`
-	DatabaseName string `yaml:"database_name" json:"database_name"`
-	DSN          string `yaml:"dsn" json:"dsn"` // optional explicit DSN
+	Type         string       `yaml:"type" json:"type"`
+	Host         string       `yaml:"host" json:"host"`
+	Port         int          `yaml:"port" json:"port"`
+	Username     string       `yaml:"username" json:"username"`
+	Password     string       `yaml:"password" json:"password"` // may reference ${ENV} variables in the config file
+	DatabaseName string       `yaml:"database_name" json:"database_name"`
+	DSN          string       `yaml:"dsn" json:"dsn"` // optional explicit DSN, may reference ${ENV} variables in the config file
+	Filter       FilterConfig `yaml:"filter" json:"filter"`
+	PasswordFile string       `yaml:"password_file" json:"-"` // file holding the password, config file only
+}
+
+// FilterConfig limits extraction to matching schemas and tables. Rules are
+// case-insensitive globs using * and ?, or regular expressions when wrapped
+// in slashes. Table rules containing a dot match against schema.table.
+type FilterConfig struct {
+	IncludeSchemas []string `yaml:"include_schemas" json:"include_schemas,omitempty"`
+	ExcludeSchemas []string `yaml:"exclude_schemas" json:"exclude_schemas,omitempty"`
+	IncludeTables  []string `yaml:"include_tables" json:"include_tables,omitempty"`
+	ExcludeTables  []string `yaml:"exclude_tables" json:"exclude_tables,omitempty"`
 }
 
 type ServerConfig struct {
-	Port int `yaml:"port" json:"port"`
+	Port       int `yaml:"port" json:"port"`
+	SessionTTL int `yaml:"session_ttl" json:"session_ttl"` // minutes a browser session is kept without requests, 0 for a day
+}
+
+type ExtractConfig struct {
+	ExactRowCounts  bool     `yaml:"exact_row_counts" json:"exact_row_counts"`   // count rows instead of using catalog estimates
+	RowCountTimeout int      `yaml:"row_count_timeout" json:"row_count_timeout"` // seconds per table, 0 for the default
+	Concurrency     int      `yaml:"concurrency" json:"concurrency"`             // tables extracted in parallel, 0 or 1 for sequential
+	Skip            []string `yaml:"skip" json:"skip"`                           // optional sections not to extract, e.g. routines or sizes
+}
+
+// PoolConfig bounds the connection pool kept for each database.
+type PoolConfig struct {
+	MaxOpen     int `yaml:"max_open" json:"max_open"`           // open connections, 0 for unlimited, at least max(2, extract.concurrency)
+	MaxIdle     int `yaml:"max_idle" json:"max_idle"`           // idle connections kept, 0 for extract.concurrency
+	MaxLifetime int `yaml:"max_lifetime" json:"max_lifetime"`   // seconds before a connection is replaced, 0 for no limit
+	MaxIdleTime int `yaml:"max_idle_time" json:"max_idle_time"` // seconds before an idle connection is closed, 0 for no limit
+}
+
+// CacheConfig controls how long an extracted schema is served from the cache.
+type CacheConfig struct {
+	MaxAge int `yaml:"max_age" json:"max_age"` // seconds before a schema is extracted again even if unchanged, 0 for no limit
+}
+
+// SnapshotConfig enables the history of the schemas of the shared connections.
+type SnapshotConfig struct {
+	Dir string `yaml:"dir" json:"dir"` // directory the snapshots are saved in, empty to keep no history
 }
 
 type AppConfig struct {
-	Database DBConfig     `yaml:"database" json:"database"`
-	Server   ServerConfig `yaml:"server" json:"server"`
+	Database    DBConfig            `yaml:"database" json:"database"`       // the connection named "default"
+	Connections map[string]DBConfig `yaml:"connections" json:"connections"` // further named connections, shared by all users
+	Server      ServerConfig        `yaml:"server" json:"server"`
+	Extract     ExtractConfig       `yaml:"extract" json:"extract"`
+	Pool        PoolConfig          `yaml:"pool" json:"pool"`
+	Cache       CacheConfig         `yaml:"cache" json:"cache"`
+	Snapshots   SnapshotConfig      `yaml:"snapshots" json:"snapshots"`
 }
 
 // LoadFile loads YAML config from path.
@@ -37,6 +81,16 @@ func LoadFile(path string) (AppConfig, error) {
 	if err := yaml.Unmarshal(f, &cfg); err != nil {
 		return cfg, err
 	}
+	dir := filepath.Dir(path)
+	if err := cfg.Database.resolveSecrets(dir); err != nil {
+		return cfg, fmt.Errorf("database: %w", err)
+	}
+	for name, c := range cfg.Connections {
+		if err := c.resolveSecrets(dir); err != nil {
+			return cfg, fmt.Errorf("connection %s: %w", name, err)
+		}
+		cfg.Connections[name] = c
+	}
 	return cfg, nil
 }
 
@@ -83,6 +137,13 @@ func BuildDriverAndDSN(db DBConfig) (driver string, dsn string, err error) {
 			return "", "", fmt.Errorf("sqlite needs a file path in database_name")
 		}
 		dsn = fmt.Sprintf("file:%s?mode=ro", db.DatabaseName)
+	case "ddl":
+		// a DDL script instead of a database, see internal/ddl
+		driver = "ddl"
+		if db.DatabaseName == "" {
+			return "", "", fmt.Errorf("ddl needs a file path in database_name")
+		}
+		dsn = "file:" + db.DatabaseName
 	case "sqlserver":
 		driver = "sqlserver"
 		dsn = fmt.Sprintf("sqlserver://%s:%s@%s:%d?database=%s",
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadFileSecrets(t *testing.T) {
+	t.Setenv("ERD_TEST_PASSWORD", "envSecret")
+	var tests = []struct {
+		name     string
+		filename string
+		password string
+		dsn      string
+		errIsNil bool
+	}{
+		{"Password File And Env", "./testdata/secret_config.yaml", "fileSecret", "report:envSecret@tcp(testHost:3306)/reports", true},
+		{"Missing Env", "./testdata/missing_env_config.yaml", "", "", false},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := LoadFile(tt.filename)
+			if (err == nil) != tt.errIsNil {
+				if tt.errIsNil {
+					t.Errorf("\ngot unexpected error: \"%v\"", err)
+				} else {
+					t.Errorf("\nexpected an error, did not receive one")
+				}
+				return
+			}
+			if !tt.errIsNil {
+				return
+			}
+			if c.Database.Password != tt.password {
+				t.Errorf("\ngot password %q, wanted %q", c.Database.Password, tt.password)
+			}
+			if dsn := c.Connections["reporting"].DSN; dsn != tt.dsn {
+				t.Errorf("\ngot dsn %q, wanted %q", dsn, tt.dsn)
+			}
+		})
+	}
+}
+
+func TestMaskDSN(t *testing.T) {
+	var tests = []struct {
+		name string
+		dsn  string
+		want string
+	}{
+		{"URL", "postgres://u:secret@h:5432/db?sslmode=disable", "postgres://u:********@h:5432/db?sslmode=disable"},
+		{"URL Without Path", "sqlserver://u:secret@h:1433?database=db", "sqlserver://u:********@h:1433?database=db"},
+		{"MySQL", "u:sec@ret@tcp(h:3306)/db?parseTime=true", "u:********@tcp(h:3306)/db?parseTime=true"},
+		{"Oracle", "u/secret@h:1521/svc", "u/********@h:1521/svc"},
+		{"No Password", "postgres://u@h/db", "postgres://u@h/db"},
+		{"No User", "postgres://h:5432/db", "postgres://h:5432/db"},
+		{"File", "file:app.db?mode=ro", "file:app.db?mode=ro"},
+		{"Key Value", "host=db user=u password=secret dbname=app", "host=db user=u password=******** dbname=app"},
+		{"Key Value Quoted", `host=db password = 'sec ret\'s' user=u`, "host=db password = ******** user=u"},
+		{"Key Value At End", "host=db user=u@x PASSWORD=secret", "host=db user=u@x PASSWORD=********"},
+		{"Key Value Without Password", "host=db user=u dbname=a/b@c", "host=db user=u dbname=a/b@c"},
+		{"SQL Server", "server=db;user id=u;password=sec ret;database=app", "server=db;user id=u;password=********;database=app"},
+		{"SQL Server Pwd Braced", "Server=db; User ID=u; Pwd={a;b}}c};Database=app", "Server=db; User ID=u; Pwd=********;Database=app"},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskDSN(tt.dsn); got != tt.want {
+				t.Errorf("\ngot %q, wanted %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithSecretsFrom(t *testing.T) {
+	stored := DBConfig{Type: "postgres", Host: "h", Username: "u", Password: "secret", DatabaseName: "db"}
+	storedDSN := DBConfig{Type: "mysql", DSN: "u:secret@tcp(h:3306)/db"}
+	var tests = []struct {
+		name   string
+		sent   DBConfig
+		stored DBConfig
+		want   DBConfig
+	}{
+		{"Mask Kept",
+			DBConfig{Type: "postgresql", Host: "h", Username: "u", Password: PasswordMask, DatabaseName: "db"}, stored,
+			DBConfig{Type: "postgresql", Host: "h", Username: "u", Password: "secret", DatabaseName: "db"}},
+		{"New Password",
+			DBConfig{Type: "postgres", Host: "h", Username: "u", Password: "other", DatabaseName: "db"}, stored,
+			DBConfig{Type: "postgres", Host: "h", Username: "u", Password: "other", DatabaseName: "db"}},
+		{"Other Host",
+			DBConfig{Type: "postgres", Host: "evil", Username: "u", Password: PasswordMask, DatabaseName: "db"}, stored,
+			DBConfig{Type: "postgres", Host: "evil", Username: "u", Password: PasswordMask, DatabaseName: "db"}},
+		{"Masked DSN",
+			DBConfig{Type: "mysql", DSN: "u:********@tcp(h:3306)/db"}, storedDSN,
+			storedDSN},
+		{"Other DSN",
+			DBConfig{Type: "mysql", DSN: "u:********@tcp(evil:3306)/db"}, storedDSN,
+			DBConfig{Type: "mysql", DSN: "u:********@tcp(evil:3306)/db"}},
+	}
+
+	for _, tt := range tests {
+		// Use t.Run to run each case as a subtest with a descriptive name
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sent.WithSecretsFrom(tt.stored); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\ngot %+v, wanted %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalJSONRedacts(t *testing.T) {
+	b, err := json.Marshal(DBConfig{Type: "mysql", Password: "secret", DSN: "u:secret@tcp(h:3306)/db", PasswordFile: "pw.txt"})
+	if err != nil {
+		t.Fatalf("\ngot unexpected error: \"%v\"", err)
+	}
+	if strings.Contains(string(b), "secret") || strings.Contains(string(b), "pw.txt") {
+		t.Errorf("\ngot %s, wanted no secrets", b)
+	}
+	if !strings.Contains(string(b), `"has_password":true`) {
+		t.Errorf("\ngot %s, wanted has_password true", b)
+	}
+}
@@ -9,11 +9,17 @@ const mermaidClassDefs =
     + `classDef tabsiz_7 fill:#769A62\n`
     + `classDef tabsiz_8 fill:#60804E\n`
     + `classDef tabsiz_9 fill:#4B663B\n`
-    + `classDef tabsiz_10 fill:#CB4040\n`;
+    + `classDef tabsiz_10 fill:#CB4040\n`
+    + `classDef view fill:#E8E8E8,stroke-dasharray:5 5\n`;
 
 // globals, used for filtering
 var allTables = [];
 var allFks = [];
+var allViews = [];
+var allTypes = [];
+var allSequences = [];
+var allTriggers = [];
+var allRoutines = [];
 var panZoomInstance = null;
 
 window.addEventListener('resize', function () {
@@ -26,9 +32,25 @@ window.addEventListener('resize', function () {
 const connectBtn = document.getElementById('connectBtn');
 const disconnectBtn = document.getElementById('disconnectBtn');
 const connectInfo = document.getElementById('connectInfo');
+const connSelect = document.getElementById('connSelect');
+const deleteConnBtn = document.getElementById('deleteConnBtn');
+const importInput = document.getElementById('importInput');
+const importDialect = document.getElementById('importDialect');
+const importBtn = document.getElementById('importBtn');
+const importInfo = document.getElementById('importInfo');
+const exportFormat = document.getElementById('exportFormat');
+const exportLink = document.getElementById('exportLink');
+const historyRow = document.getElementById('historyRow');
+const snapshotSelect = document.getElementById('snapshotSelect');
 const info = document.getElementById('info');
 const searchInput = document.getElementById('search');
 const schemaSelect = document.getElementById('schemaFilter');
+const colorBySelect = document.getElementById('colorBy');
+const legendLabel = document.getElementById('legendLabel');
+const objectKindSelect = document.getElementById('objectKind');
+const objectSearchInput = document.getElementById('objectSearch');
+const objectList = document.getElementById('objectList');
+const warningBanner = document.getElementById('warningBanner');
 const popup = document.getElementById('popup');
 const filterText = document.getElementById('filterText');
 const detailsDialog = document.getElementById('detailsDialog');
@@ -67,6 +89,7 @@ async function getConnect() {
     const res = await fetch('/api/getConnect');
     if (res.ok) {
         const body = await res.json();
+        document.getElementById('conn_name').value = body?.name || '';
         if (body?.config.type) {
             document.getElementById('dbType').value = body?.config.type || 'postgres';
             document.getElementById('dsn').value = body?.config.dsn || '';
@@ -76,6 +99,9 @@ async function getConnect() {
             document.getElementById('password').value = body?.config.password || '';
             document.getElementById('database_name').value = body?.config.database_name || '';
         }
+        for (const key of filterKeys) {
+            document.getElementById(key).value = (body?.config.filter?.[key] || []).join(', ');
+        }
     }
 }
 
@@ -95,7 +121,8 @@ async function postConnect(payload) {
             return null;
         }
         const body = await res.json();
-        connectInfo.innerText = 'Connected. Tables: ' + (body.schema.tables?.length || 0);
+        connectInfo.innerText = 'Connected. Tables: ' + (body.schema.tables?.length || 0)
+            + (body.schema.warnings?.length ? ', warnings: ' + body.schema.warnings.length : '');
         return body.schema;
     } catch (err) {
         connectInfo.innerText = 'Connection error: ' + err.message;
@@ -105,24 +132,157 @@ async function postConnect(payload) {
     }
 }
 
+// keys of the extraction filter, also the ids of their inputs
+const filterKeys = ['include_schemas', 'exclude_schemas', 'include_tables', 'exclude_tables'];
+
+function buildFilterFromForm() {
+    const filter = {};
+    for (const key of filterKeys) {
+        const rules = document.getElementById(key).value.split(',').map(r => r.trim()).filter(Boolean);
+        if (rules.length) filter[key] = rules;
+    }
+    return filter;
+}
+
 function buildPayloadFromForm() {
+    const name = document.getElementById('conn_name').value.trim();
     const type = document.getElementById('dbType').value;
     const dsn = document.getElementById('dsn').value.trim();
+    const filter = buildFilterFromForm();
     if (dsn) {
-        return { type, dsn };
+        return { name, type, dsn, filter };
     }
     const host = document.getElementById('host').value;
     const port = parseInt(document.getElementById('port').value) || undefined;
     const username = document.getElementById('username').value;
     const password = document.getElementById('password').value;
     const database_name = document.getElementById('database_name').value;
-    return { type, host, port, username, password, database_name };
+    return { name, type, host, port, username, password, database_name, filter };
+}
+
+// fills the connection picker with the named connections of the session
+async function loadConnections() {
+    const res = await fetch('/api/connections');
+    if (!res.ok) return;
+    const body = await res.json();
+    connSelect.innerHTML = '';
+    for (const c of body.connections || []) {
+        const opt = document.createElement('option');
+        opt.value = c.name;
+        opt.textContent = c.name + ' (' + c.type + (c.database_name ? ' ' + c.database_name : '') + ')' + (c.shared ? ' - shared' : '');
+        opt.selected = c.selected;
+        connSelect.appendChild(opt);
+    }
+    deleteConnBtn.disabled = !(body.connections || []).some(c => c.selected && !c.shared);
 }
 
+connSelect.addEventListener('change', async () => {
+    const res = await fetch('/api/connections/' + encodeURIComponent(connSelect.value) + '/select', { method: 'POST' });
+    if (!res.ok) {
+        connectInfo.innerText = 'Select failed: ' + await res.text();
+        return;
+    }
+    load();
+});
+
+deleteConnBtn.addEventListener('click', async () => {
+    const res = await fetch('/api/connections/' + encodeURIComponent(connSelect.value), { method: 'DELETE' });
+    if (!res.ok) {
+        connectInfo.innerText = 'Delete failed: ' + await res.text();
+        return;
+    }
+    load();
+});
+
 connectBtn.addEventListener('click', async () => {
     const payload = buildPayloadFromForm();
     const schema = await postConnect(payload);
-    if (schema) renderSchema(schema);
+    if (schema) {
+        renderSchema(schema);
+        loadConnections();
+        loadSnapshots();
+    }
+});
+
+// uploads a schema file; the schema is shown but not kept by the server
+importBtn.addEventListener('click', async () => {
+    const file = importInput.files[0];
+    if (!file) {
+        importInfo.innerText = 'Choose a file first';
+        return;
+    }
+    importInfo.innerText = 'Importing...';
+    importBtn.disabled = true;
+    try {
+        const format = file.name.toLowerCase().endsWith('.dbml') ? 'dbml' : 'ddl';
+        const params = new URLSearchParams({ format, dialect: importDialect.value });
+        const res = await fetch('/api/import?' + params, { method: 'POST', body: file });
+        if (!res.ok) {
+            importInfo.innerText = 'Import failed: ' + await res.text();
+            return;
+        }
+        const body = await res.json();
+        importInfo.innerText = `Imported ${file.name}. Tables: ` + (body.schema.tables?.length || 0)
+            + (body.schema.warnings?.length ? ', warnings: ' + body.schema.warnings.length : '');
+        renderSchema(body.schema);
+    } catch (err) {
+        importInfo.innerText = 'Import error: ' + err.message;
+    } finally {
+        importBtn.disabled = false;
+    }
+});
+
+// downloads the schema of the selected connection, or the version picked in
+// the history, diagrams with the active filters
+exportLink.addEventListener('click', () => {
+    const params = new URLSearchParams({
+        format: exportFormat.value,
+        schema: schemaSelect.value,
+        search: searchInput.value.trim(),
+        color_by: colorBySelect.value,
+    });
+    if (snapshotSelect.value) params.set('snapshot', snapshotSelect.value);
+    exportLink.href = '/api/export?' + params;
+});
+
+// fills the history picker with the snapshots of the selected connection;
+// it stays hidden for connections without a history
+async function loadSnapshots() {
+    const res = await fetch('/api/snapshots');
+    if (!res.ok) {
+        historyRow.hidden = true;
+        snapshotSelect.innerHTML = '';
+        return;
+    }
+    const body = await res.json();
+    snapshotSelect.innerHTML = '<option value="">Current schema</option>';
+    for (const snap of body.snapshots || []) {
+        const opt = document.createElement('option');
+        opt.value = snap.id;
+        opt.textContent = new Date(snap.taken).toLocaleString() + ' (' + snap.hash.slice(0, 8) + ')';
+        snapshotSelect.appendChild(opt);
+    }
+    historyRow.hidden = false;
+}
+
+// renders the picked version of the schema, or the current one
+snapshotSelect.addEventListener('change', async () => {
+    if (!snapshotSelect.value) {
+        load();
+        return;
+    }
+    info.innerText = 'Loading...';
+    try {
+        const res = await fetch('/api/snapshots/' + encodeURIComponent(snapshotSelect.value));
+        if (!res.ok) {
+            info.innerText = 'Load error: ' + await res.text();
+            return;
+        }
+        renderSchema(await res.json());
+        info.innerText += ' - version of ' + snapshotSelect.selectedOptions[0].textContent;
+    } catch (err) {
+        info.innerText = 'Load error: ' + err.message;
+    }
 });
 
 disconnectBtn.addEventListener('click', () => {
@@ -159,7 +319,15 @@ async function applyFiltersAndRender() {
     //alert(`Filtered FKs count: ${filteredFks.length}`); // for debugging
     //alert(JSON.stringify(filteredFks, null, 2)); // for debugging
 
-    const [mermaidCode, entityDetails] = jsonToMermaidERD(filteredTables, filteredFks);
+    const filteredViews = allViews.filter(v => {
+        const schema = v.schema?.toLowerCase() || '';
+        const viewnam = ((schema ? schema + '.' : '') + v.name).toLowerCase();
+        const matchesSchema = !schemaSel || schema === schemaSel;
+        const matchesQuery = !q || viewnam.includes(q);
+        return matchesSchema && matchesQuery;
+    });
+
+    const [mermaidCode, entityDetails] = jsonToMermaidERD(filteredTables, filteredFks, filteredViews);
     //await navigator.clipboard.writeText( mermaidCode );  // copy mermaid code to clipboard
     //alert( mermaidCode );  // for debugging
     //alert( entityDetails );  // for debugging
@@ -170,18 +338,191 @@ async function applyFiltersAndRender() {
 
 searchInput.addEventListener('input', applyFiltersAndRender);
 schemaSelect.addEventListener('change', applyFiltersAndRender);
+colorBySelect.addEventListener('change', () => {
+    legendLabel.innerText = colorBySelect.value === 'rows' ? 'Table Row Count' : 'Table Size in 8k pages';
+    applyFiltersAndRender();
+});
+
+function qualifiedName(obj) {
+    return (obj.schema ? obj.schema + '.' : '') + obj.name;
+}
+
+// catalog text such as comments and expressions may contain markup, so
+// escape it before it goes into the details HTML
+function escapeHtml(text) {
+    return String(text ?? '').replace(/[&<>"']/g, c => ({ '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;' })[c]);
+}
+
+// the name shown in the object list and the tables the object touches
+function objectSummary(kind, obj) {
+    if (kind === 'sequences') {
+        const owner = obj.owned_by ? [qualifiedName({ schema: obj.owned_by.schema, name: obj.owned_by.table }) + '.' + obj.owned_by.column] : [];
+        return { label: qualifiedName(obj), tables: owner };
+    }
+    if (kind === 'triggers') {
+        return { label: `${qualifiedName(obj)} on ${qualifiedName(obj.table)}`, tables: [qualifiedName(obj.table)] };
+    }
+    return { label: `${qualifiedName(obj)} (${obj.kind.toLowerCase()})`, tables: (obj.references || []).map(qualifiedName) };
+}
+
+function renderObjectList() {
+    const kind = objectKindSelect.value;
+    const objects = kind === 'sequences' ? allSequences : (kind === 'triggers' ? allTriggers : allRoutines);
+    const q = objectSearchInput.value.trim().toLowerCase();
+
+    objectList.innerHTML = '';
+    objects.forEach(obj => {
+        const summary = objectSummary(kind, obj);
+        if (q && !summary.label.toLowerCase().includes(q) && !summary.tables.some(t => t.toLowerCase().includes(q))) {
+            return;
+        }
+        const li = document.createElement('li');
+        li.textContent = summary.label;
+        li.addEventListener('click', () => handleEntityClick(qualifiedName(obj), getDetailsForObject(kind, obj)));
+        objectList.appendChild(li);
+    });
+    if (!objectList.children.length) {
+        objectList.innerHTML = '<li class="muted">none</li>';
+    }
+}
+
+objectKindSelect.addEventListener('change', renderObjectList);
+objectSearchInput.addEventListener('input', renderObjectList);
+
+function getDetailsForObject(kind, obj) {
+    let details = '';
+    if (kind === 'sequences') {
+        details += `<p><span class="detailLabel">Type:</span> Sequence${obj.data_type ? ' of ' + escapeHtml(obj.data_type) : ''}</p>`;
+        if (obj.start != null) details += `<p><span class="detailLabel">Start:</span> ${obj.start}</p>`;
+        if (obj.increment != null) details += `<p><span class="detailLabel">Increment:</span> ${obj.increment}</p>`;
+        if (obj.cycle) details += `<p><span class="detailLabel">Cycle:</span> Yes</p>`;
+        if (obj.owned_by) {
+            details += `<p><span class="detailLabel">Owned by:</span> ${escapeHtml(objectSummary(kind, obj).tables[0])}</p>`;
+        }
+        return details;
+    }
+
+    if (kind === 'triggers') {
+        details += `<p><span class="detailLabel">Type:</span> Trigger on ${escapeHtml(qualifiedName(obj.table))}</p>`;
+        details += `<p><span class="detailLabel">Fires:</span> ${escapeHtml([obj.timing, (obj.events || []).join(' OR '), obj.level ? 'for each ' + obj.level.toLowerCase() : ''].filter(x => x).join(' '))}</p>`;
+        if (obj.disabled) details += `<p><span class="detailLabel">Disabled:</span> Yes</p>`;
+    } else {
+        details += `<p><span class="detailLabel">Type:</span> ${escapeHtml(obj.kind.charAt(0) + obj.kind.slice(1).toLowerCase())}${obj.language ? ' (' + escapeHtml(obj.language) + ')' : ''}</p>`;
+        const pre = document.createElement('pre');
+        pre.textContent = `${obj.name}(${obj.arguments || ''})${obj.return_type ? ' returns ' + obj.return_type : ''}`;
+        details += `<p><span class="detailLabel">Signature:</span></p>${pre.outerHTML}`;
+        if (obj.references?.length) {
+            details += `<p><span class="detailLabel">Uses:</span> ${escapeHtml(obj.references.map(qualifiedName).join(', '))}</p>`;
+        }
+    }
+    if (obj.definition) {
+        const pre = document.createElement('pre');
+        pre.textContent = obj.definition;
+        details += `<p><span class="detailLabel">Definition:</span></p>${pre.outerHTML}`;
+    }
+    return details;
+}
+
+function getDetailsForView(viewName) {
+    const view = allViews.find(v => ((v.schema ? v.schema + '.' : '') + v.name) === viewName);
+    if (!view) return 'No details found for view: ' + escapeHtml(viewName);
+
+    let details = `<p><span class="detailLabel">Type:</span> ${view.materialized ? 'Materialized view' : (view.indexed ? 'Indexed view' : 'View')}</p>`;
+
+    if (view.comment) {
+        details += `<p><span class="detailLabel">Comment:</span> ${escapeHtml(view.comment)}</p>`;
+    }
+
+    let columns = '';
+    view.columns?.forEach(col => {
+        columns += `<tr><td>${escapeHtml(col.name)}</td><td>${escapeHtml(col.type)}</td><td>${col.nullable ? 'Yes' : 'No'}</td><td>${escapeHtml(col.comment)}</td></tr>`
+    })
+    if (columns) {
+        details += `<table><caption>Columns:</caption><thead><tr><th>Column Name</th><th>Type</th><th>Nullable</th><th>Comment</th></tr></thead><tbody>${columns}</tbody></table>`
+    }
+
+    if (view.depends_on?.length) {
+        const dependsOn = view.depends_on.map(d => (d.schema ? d.schema + '.' : '') + d.name).join(', ');
+        details += `<p><span class="detailLabel">Depends on:</span> ${escapeHtml(dependsOn)}</p>`;
+    }
+
+    if (view.definition) {
+        const pre = document.createElement('pre');
+        pre.textContent = view.definition;
+        details += `<p><span class="detailLabel">Definition:</span></p>${pre.outerHTML}`;
+    }
+
+    return details;
+}
 
 function getDetailsForTable(tableName) {
     const table = allTables.find(t => ((t.schema ? t.schema + '.' : '') + t.name) === tableName);
-    if (!table) return 'No details found for table: ' + tableName;
+    if (!table) {
+        if (allViews.some(v => ((v.schema ? v.schema + '.' : '') + v.name) === tableName)) {
+            return getDetailsForView(tableName);
+        }
+        return 'No details found for table: ' + escapeHtml(tableName);
+    }
 
     let details = '';
     if (table.size8kPages) {
         details += `<p><span class="detailLabel">Size:</span> approx. ${(table.size8kPages / 128).toFixed(2)} MB (${table.size8kPages.toLocaleString()} x 8k pages)</p>`;
     }
 
+    if (table.rows) {
+        details += `<p><span class="detailLabel">Rows:</span> approx. ${table.rows.toLocaleString()}</p>`;
+    }
+
     if (table.comment) {
-        details += `<p><span class="detailLabel">Comment:</span> ${table.comment}</p>`;
+        details += `<p><span class="detailLabel">Comment:</span> ${escapeHtml(table.comment)}</p>`;
+    }
+
+    if (table.partitioning) {
+        const p = table.partitioning;
+        details += `<p><span class="detailLabel">Partitioned by:</span> ${escapeHtml(p.strategy)}${p.columns?.length ? ' (' + escapeHtml(p.columns.join(', ')) + ')' : ''}, ${p.partitions?.length || 0} partitions</p>`;
+    }
+
+    let columns = '';
+    table.columns?.forEach(col => {
+        let attributes = [];
+        if (col.pk) attributes.push('PK');
+        if (col.identity) attributes.push('identity');
+        if (col.generated) attributes.push(`generated: ${col.generated}`);
+        if (col.default) attributes.push(`default: ${col.default}`);
+        const userType = allTypes.find(ut => ut.name === col.type && (!ut.schema || !table.schema || ut.schema === table.schema))
+            || allTypes.find(ut => ut.name === `${table.name}.${col.name}` && ut.schema === table.schema);
+        if (userType?.labels) attributes.push(`${userType.kind} values: ${userType.labels.join(', ')}`);
+        if (userType?.base_type) attributes.push(`${userType.kind} of ${userType.base_type}${userType.check ? ' ' + userType.check : ''}`);
+        columns += `<tr><td>${escapeHtml(col.name)}</td><td>${escapeHtml(col.type)}</td><td>${col.nullable ? 'Yes' : 'No'}</td><td>${escapeHtml(attributes.join(', '))}</td><td>${escapeHtml(col.comment)}</td></tr>`
+    })
+    if (columns) {
+        details += `<table><caption>Columns:</caption><thead><tr><th>Column Name</th><th>Type</th><th>Nullable</th><th>Attributes</th><th>Comment</th></tr></thead><tbody>${columns}</tbody></table>`
+    }
+
+    let indexes = '';
+    table.indexes?.forEach(idx => {
+        const columns = idx.columns.join(', ') + (idx.include?.length ? ` (include: ${idx.include.join(', ')})` : '');
+        indexes += `<tr><td>${escapeHtml(idx.name)}</td><td>${escapeHtml(columns)}</td><td>${idx.primary ? 'PK' : (idx.unique ? 'Unique' : '')}</td><td>${escapeHtml(idx.method)}</td><td>${escapeHtml(idx.predicate)}</td></tr>`
+    })
+    if (indexes) {
+        details += `<table><caption>Indexes:</caption><thead><tr><th>Index Name</th><th>Columns</th><th>Type</th><th>Method</th><th>Predicate</th></tr></thead><tbody>${indexes}</tbody></table>`
+    }
+
+    let constraints = '';
+    table.constraints?.forEach(c => {
+        constraints += `<tr><td>${escapeHtml(c.name)}</td><td>${escapeHtml(c.type)}</td><td>${escapeHtml(c.columns?.join(', '))}</td><td>${escapeHtml(c.expression)}</td></tr>`
+    })
+    if (constraints) {
+        details += `<table><caption>Constraints:</caption><thead><tr><th>Constraint Name</th><th>Type</th><th>Columns</th><th>Expression</th></tr></thead><tbody>${constraints}</tbody></table>`
+    }
+
+    let partitions = '';
+    table.partitioning?.partitions?.forEach(p => {
+        const name = p.name ? qualifiedName(p) : `#${p.number}`;
+        partitions += `<tr><td>${escapeHtml(name)}</td><td>${escapeHtml(p.bound)}</td><td>${(p.rows || 0).toLocaleString()}</td><td>${(p.size8kPages || 0).toLocaleString()}</td></tr>`
+    })
+    if (partitions) {
+        details += `<table><caption>Partitions:</caption><thead><tr><th>Partition</th><th>Bound</th><th>Rows</th><th>Size in 8k pages</th></tr></thead><tbody>${partitions}</tbody></table>`
     }
 
     let outboundForeignKeys = '';
@@ -189,41 +530,63 @@ function getDetailsForTable(tableName) {
     allFks.forEach(fk => {
         const fromTab = (fk.from_schema ? fk.from_schema + '.' : '') + fk.from_table;
         const toTab = (fk.to_schema ? fk.to_schema + '.' : '') + fk.to_table;
+        const actions = `<td>${escapeHtml(fk.on_delete)}</td><td>${escapeHtml(fk.on_update)}</td><td>${escapeHtml(foreignKeyOptions(fk))}</td>`;
         if (fromTab === tableName) {
-            outboundForeignKeys += `<tr><td>${fk.constraint || 'FK'}</td><td>${fk.from_column}</td><td>${toTab}</td><td>${fk.to_column}</td></tr>`
+            outboundForeignKeys += `<tr><td>${escapeHtml(fk.constraint || 'FK')}</td><td>${escapeHtml(fk.from_column)}</td><td>${escapeHtml(toTab)}</td><td>${escapeHtml(fk.to_column)}</td>${actions}</tr>`
         } else if (toTab === tableName) {
-            inboundForeignKeys += `<tr><td>${fromTab}</td><td>${fk.constraint}</td><td>${fk.from_column}</td><td>${fk.to_column}</td></tr>`
+            inboundForeignKeys += `<tr><td>${escapeHtml(fromTab)}</td><td>${escapeHtml(fk.constraint)}</td><td>${escapeHtml(fk.from_column)}</td><td>${escapeHtml(fk.to_column)}</td>${actions}</tr>`
         }
     })
     if (outboundForeignKeys) {
-        details += `<table><caption>Foreign Keys:</caption><thead><tr><th>Constraint Name</th><th>Columns</th><th>Target Table</th><th>Target Columns</th></tr></thead><tbody>${outboundForeignKeys}</tbody></table>`
+        details += `<table><caption>Foreign Keys:</caption><thead><tr><th>Constraint Name</th><th>Columns</th><th>Target Table</th><th>Target Columns</th><th>On Delete</th><th>On Update</th><th>Options</th></tr></thead><tbody>${outboundForeignKeys}</tbody></table>`
     }
     if (inboundForeignKeys) {
-        details += `<table><caption>Table is referenced by:</caption><thead><tr><th>Referencing Table</th><th>Referencing Constraint</th><th>Referencing Columns</th><th>Columns</th></tr></thead><tbody>${inboundForeignKeys}</tbody>`
+        details += `<table><caption>Table is referenced by:</caption><thead><tr><th>Referencing Table</th><th>Referencing Constraint</th><th>Referencing Columns</th><th>Columns</th><th>On Delete</th><th>On Update</th><th>Options</th></tr></thead><tbody>${inboundForeignKeys}</tbody>`
+    }
+
+    let triggers = '';
+    allTriggers.filter(tr => qualifiedName(tr.table) === tableName).forEach(tr => {
+        triggers += `<tr><td>${escapeHtml(tr.name)}</td><td>${escapeHtml(tr.timing)}</td><td>${escapeHtml((tr.events || []).join(', '))}</td><td>${escapeHtml(tr.level)}</td><td>${tr.disabled ? 'No' : 'Yes'}</td></tr>`
+    })
+    if (triggers) {
+        details += `<table><caption>Triggers:</caption><thead><tr><th>Trigger Name</th><th>Timing</th><th>Events</th><th>Level</th><th>Enabled</th></tr></thead><tbody>${triggers}</tbody></table>`
     }
 
     return details;
 }
 
+function foreignKeyOptions(fk) {
+    let options = [];
+    if (fk.match_type && fk.match_type !== 'SIMPLE') options.push(`match ${fk.match_type.toLowerCase()}`);
+    if (fk.deferrable) options.push(fk.initially_deferred ? 'deferrable, initially deferred' : 'deferrable');
+    if (fk.disabled) options.push('disabled');
+    if (fk.not_validated) options.push('not validated');
+    return options.join(', ');
+}
+
 function cleanColumnType(columnType) {
     // replace whitespaces with unicode en space (U+2002)
     // replace all non alphanumeric characters (except spaces, already handled) with empty string
     return columnType.replace(/\s+/g, '\u{2002}').replace(/[^a-zA-Z0-9\s]/g, '').trim();
 }
 
-function jsonToMermaidERD(tables, fks) {
+function jsonToMermaidERD(tables, fks, views) {
     let mermaidSyntax = 'erDiagram\ndirection BT\n\n';
     let entityDetails = {};
 
     // 1. Add Tables and Columns
     tables?.forEach(table => {
         const tabnam = (table.schema ? table.schema + '.' : '') + table.name
-        const tabsiz = table.size8kPages ? Math.min(Math.trunc(Math.log10(table.size8kPages)), 9) + 1 : 1;
+        // color class by order of magnitude of the size in pages or the row count
+        const magnitude = colorBySelect.value === 'rows' ? table.rows : table.size8kPages;
+        const tabsiz = magnitude ? Math.min(Math.trunc(Math.log10(magnitude)), 9) + 1 : 1;
         mermaidSyntax += `  "${tabnam}":::tabsiz_${tabsiz} {\n`;
         table.columns.forEach(column => {
             // Add a key indicator if specified in JSON
             const keyIndicator = column.pk ? 'PK' : '';
-            mermaidSyntax += `    ${cleanColumnType(column.type)} ${column.name} ${keyIndicator}\n`;
+            // mermaid attribute comments are double quoted and cannot contain double quotes
+            const comment = column.comment ? ` "${column.comment.replace(/"/g, "'").replace(/\s+/g, ' ')}"` : '';
+            mermaidSyntax += `    ${cleanColumnType(column.type)} ${column.name} ${keyIndicator}${comment}\n`;
         });
         mermaidSyntax += `  }\n`;
         entityDetails[`${tabnam}`] = getDetailsForTable(tabnam);
@@ -244,7 +607,26 @@ function jsonToMermaidERD(tables, fks) {
         }
     });
 
-    // 3. Add classDefinitions for table sizes
+    // 3. Add Views and their dependencies on the tables and views shown,
+    // others were filtered out
+    const shown = new Set([...(tables || []), ...(views || [])].map(qualifiedName));
+    views?.forEach(view => {
+        const viewnam = (view.schema ? view.schema + '.' : '') + view.name
+        mermaidSyntax += `  "${viewnam}":::view {\n`;
+        view.columns?.forEach(column => {
+            mermaidSyntax += `    ${cleanColumnType(column.type || 'any')} ${column.name}\n`;
+        });
+        mermaidSyntax += `  }\n`;
+        entityDetails[`${viewnam}`] = getDetailsForView(viewnam);
+        view.depends_on?.forEach(dep => {
+            const depnam = (dep.schema ? dep.schema + '.' : '') + dep.name
+            if (shown.has(depnam)) {
+                mermaidSyntax += `  "${viewnam}" }o..o{ "${depnam}" : "uses"\n`;
+            }
+        });
+    });
+
+    // 4. Add classDefinitions for table sizes and views
     mermaidSyntax += mermaidClassDefs;
 
     return [mermaidSyntax, entityDetails];
@@ -289,16 +671,42 @@ const insertSvgEvents = function (svgCode, element, svgId, entityDetails) {
     }
 };
 
+// extraction steps that failed, e.g. for missing permissions
+function renderWarnings(warnings) {
+    if (!warnings?.length) {
+        warningBanner.hidden = true;
+        warningBanner.innerHTML = '';
+        return;
+    }
+    const list = document.createElement('ul');
+    warnings.forEach(w => {
+        const li = document.createElement('li');
+        li.textContent = `${w.phase}${w.object ? ' of ' + w.object : ''}: ${w.message}`;
+        list.appendChild(li);
+    });
+    warningBanner.innerHTML = `<b>The schema may be incomplete, ${warnings.length} extraction step(s) failed:</b>`;
+    warningBanner.appendChild(list);
+    warningBanner.hidden = false;
+}
+
 async function renderSchema(s) {
     document.getElementById('mermaidContainer').innerHTML = 'Rendering...';
 
     allTables = s?.tables || [];
     allFks = s?.foreign_keys || [];
-    info.innerText = `Tables: ${allTables.length}, FKs: ${allFks.length}`;
+    allViews = s?.views || [];
+    allTypes = s?.types || [];
+    allSequences = s?.sequences || [];
+    allTriggers = s?.triggers || [];
+    allRoutines = s?.routines || [];
+    renderWarnings(s?.warnings);
+    renderObjectList();
+    info.innerText = `Tables: ${allTables.length}, Views: ${allViews.length}, FKs: ${allFks.length}`;
 
     // build schema selector
     const schemaSet = new Set();
     allTables.forEach(t => { schemaSet.add((t.schema || '').toString() || ''); });
+    allViews.forEach(v => { schemaSet.add((v.schema || '').toString() || ''); });
 
     // clear and repopulate
     schemaSelect.innerHTML = '<option value="">All schemas</option>';
@@ -337,6 +745,7 @@ async function load() {
     info.innerText = 'Loading...';
     showLegend();
     getConnect();
+    loadConnections();
     try {
         const res = await fetch('/api/schema');
         if (!res.ok) {
@@ -350,6 +759,8 @@ async function load() {
             return;
         }
         renderSchema(s);
+        // the extraction may have added a snapshot
+        loadSnapshots();
     } catch (err) {
         info.innerText = 'Load error: ' + err.message;
     }